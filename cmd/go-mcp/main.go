@@ -2,29 +2,100 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
 
 	mcp "github.com/ktr0731/go-mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/acl"
 	"github.com/takak2166/scrapbox-mcp/internal/config"
 	mcpServer "github.com/takak2166/scrapbox-mcp/internal/go-mcp"
-	scrapbox "github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
 	"golang.org/x/exp/jsonrpc2"
 )
 
 func main() {
-	// Load configuration from environment variables
-	cfg, err := config.LoadConfig()
+	// "config check" validates the configuration without starting the server.
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := config.Command(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Load configuration from the config file, environment variables and flags
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	// This implementation only serves stdio, so refuse the settings that
+	// need HTTP rather than ignore them
+	if cfg.Transport == config.TransportHTTP || cfg.MultiTenant() {
+		log.Fatalf("MCP_TRANSPORT=http and multi-tenant mode are not supported by the go-mcp implementation; use the official SDK or mcp-go implementation")
+	}
+
+	// Log to stderr, never recording cookies or queries, and send the records
+	// of each request to its client
+	logHandler := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	slog.SetDefault(slog.New(logHandler))
+
+	// Hide the pages denied by the access rules from every client
+	policy, err := acl.FromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure page access rules: %v", err)
+	}
+	// Redact secrets and personal data from everything sent to clients
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		log.Fatalf("Failed to configure redaction: %v", err)
+	}
+	set := projects.FromConfig(cfg, scrapbox.WithPageFilter(policy))
+	if err := set.DetectAccess(context.Background()); projects.IsCredentialError(err) {
+		log.Fatalf("Failed to access projects: %v", err)
+	} else if err != nil {
+		log.Printf("Warning: failed to validate credentials: %v", err)
+	}
+	for _, p := range set.List() {
+		log.Printf("Project %s: %s", p.Name, p.Access())
+	}
+	client := set.Primary()
+	registry := tools.NewRegistry(cfg.Tools, cfg.ProjectTools)
+	toolHandler := mcpServer.NewToolHandler(set, registry)
+	resourceHandler := mcpServer.NewResourceHandler(set)
+	promptHandler := mcpServer.NewPromptHandler(client)
+	completionHandler := mcpServer.NewCompletionHandler(client)
+	completionHandler.SetTTL(cfg.CompletionTTL)
+	handler := mcpServer.NewHandler(promptHandler, resourceHandler, toolHandler, completionHandler)
+	// The subscription binder notifies clients when the enabled tools change.
+	handler.Capabilities.Tools.ListChanged = true
 
-	client := scrapbox.NewClient(cfg.ProjectName, cfg.ScrapboxSID)
-	toolHandler := mcpServer.NewToolHandler(client)
-	handler := mcpServer.NewHandler(toolHandler)
+	// Apply changes to the config file without restarting
+	reloader := config.NewReloader(cfg, os.Args[1:])
+	reloader.OnReload(func(cfg *config.Config) {
+		logHandler.SetLevel(cfg.LogLevel)
+		set.Reconfigure(cfg)
+		if err := policy.Set(acl.RulesFromConfig(cfg)); err != nil {
+			log.Printf("Failed to update page access rules: %v", err)
+		}
+		if err := redactor.Set(cfg.Redaction); err != nil {
+			log.Printf("Failed to update redaction rules: %v", err)
+		}
+		registry.SetPolicy(cfg.Tools, cfg.ProjectTools)
+		completionHandler.SetTTL(cfg.CompletionTTL)
+	})
+	go reloader.Run(context.Background(), config.DefaultReloadInterval)
 
 	// Start the MCP server with stdio transport
 	ctx, listener, binder := mcp.NewStdioTransport(context.Background(), handler, nil)
-	srv, err := jsonrpc2.Serve(ctx, listener, binder)
+	watchOpts := watch.Options{Interval: cfg.WatchInterval, MaxBackoff: cfg.WatchMaxBackoff}
+	srv, err := jsonrpc2.Serve(ctx, listener, mcpServer.NewSubscriptionBinder(binder, set, watchOpts, registry, redactor))
 	if err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
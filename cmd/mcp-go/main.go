@@ -3,25 +3,131 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/takak2166/scrapbox-mcp/internal/acl"
+	"github.com/takak2166/scrapbox-mcp/internal/auth"
 	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/httpserver"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
 	mcpServer "github.com/takak2166/scrapbox-mcp/internal/mcp-go"
-	scrapbox "github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/tenant"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
 )
 
 func main() {
-	// Load configuration from environment variables
-	cfg, err := config.LoadConfig()
+	// "config check" validates the configuration without starting the server.
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := config.Command(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Load configuration from the config file, environment variables and flags
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	client := scrapbox.NewClient(cfg.ProjectName, cfg.ScrapboxSID)
+	// Log to stderr, never recording cookies or queries, and send the records
+	// of each request to its client
+	logHandler := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	slog.SetDefault(slog.New(logHandler))
+
+	// Hide the pages denied by the access rules from every client
+	policy, err := acl.FromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure page access rules: %v", err)
+	}
+	// Redact secrets and personal data from everything sent to clients
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		log.Fatalf("Failed to configure redaction: %v", err)
+	}
+	set := projects.FromConfig(cfg, scrapbox.WithPageFilter(policy))
+
+	tenants, err := tenant.FromConfig(cfg, scrapbox.WithPageFilter(policy))
+	if err != nil {
+		log.Fatalf("Failed to configure multi-tenant mode: %v", err)
+	}
+	// In multi-tenant mode the credentials are only known per session.
+	if tenants == nil {
+		if err := set.DetectAccess(context.Background()); projects.IsCredentialError(err) {
+			log.Fatalf("Failed to access projects: %v", err)
+		} else if err != nil {
+			log.Printf("Warning: failed to validate credentials: %v", err)
+		}
+		for _, p := range set.List() {
+			log.Printf("Project %s: %s", p.Name, p.Access())
+		}
+	}
 
 	// Create MCP server
-	mcpServer := mcpServer.NewServer(client)
+	registry := tools.NewRegistry(cfg.Tools, cfg.ProjectTools)
+	mcpServer := mcpServer.NewServer(set, watch.Options{Interval: cfg.WatchInterval, MaxBackoff: cfg.WatchMaxBackoff}, tenants, registry, redactor)
+
+	// Apply changes to the config file without restarting
+	reloader := config.NewReloader(cfg, os.Args[1:])
+	reloader.OnReload(func(cfg *config.Config) {
+		logHandler.SetLevel(cfg.LogLevel)
+		set.Reconfigure(cfg)
+		if err := policy.Set(acl.RulesFromConfig(cfg)); err != nil {
+			log.Printf("Failed to update page access rules: %v", err)
+		}
+		if err := redactor.Set(cfg.Redaction); err != nil {
+			log.Printf("Failed to update redaction rules: %v", err)
+		}
+		registry.SetPolicy(cfg.Tools, cfg.ProjectTools)
+	})
+
+	if cfg.Transport == config.TransportHTTP {
+		authenticator, err := auth.FromConfig(cfg)
+		if err != nil {
+			log.Fatalf("Failed to configure authentication: %v", err)
+		}
+		if authenticator == nil {
+			log.Printf("Warning: HTTP mode without authentication; set MCP_AUTH_TOKENS or MCP_AUTH_JWKS_FILE")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		sse := server.NewSSEServer(mcpServer)
+		httpServer := &httpserver.Server{
+			Addr: fmt.Sprintf(":%d", cfg.Port),
+			Handlers: map[string]http.Handler{
+				"/mcp":     server.NewStreamableHTTPServer(mcpServer),
+				"/sse":     sse,
+				"/message": sse,
+			},
+			MaxBodyBytes: cfg.MaxBodyBytes,
+			Auth:         authenticator,
+			Tenants:      tenants,
+		}
+		reloader.OnReload(func(cfg *config.Config) {
+			httpServer.SetMaxBodyBytes(cfg.MaxBodyBytes)
+		})
+		go reloader.Run(ctx, config.DefaultReloadInterval)
+		log.Printf("Serving MCP over HTTP on %s", httpServer.Addr)
+		if err := httpServer.ListenAndServe(ctx); err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+		return
+	}
+
+	go reloader.Run(context.Background(), config.DefaultReloadInterval)
 
 	// Start the MCP server with stdio transport
 	if err := server.ServeStdio(mcpServer); err != nil {
@@ -1,34 +1,112 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
 
 	mcp "github.com/metoro-io/mcp-golang"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
+	"github.com/takak2166/scrapbox-mcp/internal/acl"
 	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
 	mcpServer "github.com/takak2166/scrapbox-mcp/internal/mcp-golang"
-	scrapbox "github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
 )
 
 func main() {
 	done := make(chan struct{})
 
-	// Load configuration from environment variables
-	cfg, err := config.LoadConfig()
+	// "config check" validates the configuration without starting the server.
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := config.Command(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Load configuration from the config file, environment variables and flags
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	// This implementation only serves stdio, so refuse the settings that
+	// need HTTP rather than ignore them
+	if cfg.Transport == config.TransportHTTP || cfg.MultiTenant() {
+		log.Fatalf("MCP_TRANSPORT=http and multi-tenant mode are not supported by the mcp-golang implementation; use the official SDK or mcp-go implementation")
+	}
 
-	client := scrapbox.NewClient(cfg.ProjectName, cfg.ScrapboxSID)
+	// Log to stderr, never recording cookies or queries; mcp-golang cannot
+	// send log messages to the client
+	logHandler := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	slog.SetDefault(slog.New(logHandler))
+
+	// Hide the pages denied by the access rules from every client
+	policy, err := acl.FromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure page access rules: %v", err)
+	}
+	// Redact secrets and personal data from everything sent to clients
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		log.Fatalf("Failed to configure redaction: %v", err)
+	}
+	set := projects.FromConfig(cfg, scrapbox.WithPageFilter(policy))
+	client := set.Primary()
+	if err := set.DetectAccess(context.Background()); projects.IsCredentialError(err) {
+		log.Fatalf("Failed to access projects: %v", err)
+	} else if err != nil {
+		log.Printf("Warning: failed to validate credentials: %v", err)
+	}
+	for _, p := range set.List() {
+		log.Printf("Project %s: %s", p.Name, p.Access())
+	}
+	// mcp-golang cannot send log notifications, so expired cookies are only
+	// reported in tool errors and here.
+	set.OnExpired(func(err error) {
+		log.Printf("Warning: %v", err)
+	})
 
 	// Create MCP server with stdio transport
 	server := mcp.NewServer(stdio.NewStdioServerTransport())
 
 	// Register tools
-	if err := mcpServer.RegisterTools(server, client); err != nil {
+	registry := tools.NewRegistry(cfg.Tools, cfg.ProjectTools)
+	if err := mcpServer.RegisterTools(server, set, registry, redactor); err != nil {
 		log.Fatalf("Failed to register tools: %v", err)
 	}
 
+	// Register prompts
+	if err := mcpServer.RegisterPrompts(server, client, redactor); err != nil {
+		log.Fatalf("Failed to register prompts: %v", err)
+	}
+
+	// Register resources; the server stays usable with tools only if this fails
+	if err := mcpServer.RegisterResources(context.Background(), server, client, redactor); err != nil {
+		log.Printf("Failed to register resources: %v", err)
+	}
+
+	// Apply changes to the config file without restarting
+	reloader := config.NewReloader(cfg, os.Args[1:])
+	reloader.OnReload(func(cfg *config.Config) {
+		logHandler.SetLevel(cfg.LogLevel)
+		set.Reconfigure(cfg)
+		if err := policy.Set(acl.RulesFromConfig(cfg)); err != nil {
+			log.Printf("Failed to update page access rules: %v", err)
+		}
+		if err := redactor.Set(cfg.Redaction); err != nil {
+			log.Printf("Failed to update redaction rules: %v", err)
+		}
+		registry.SetPolicy(cfg.Tools, cfg.ProjectTools)
+	})
+	go reloader.Run(context.Background(), config.DefaultReloadInterval)
+
 	// Start the MCP server
 	if err := server.Serve(); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 
 	"github.com/ktr0731/go-mcp/codegen"
+	"github.com/takak2166/scrapbox-mcp/internal/prompts"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
 )
 
 func main() {
@@ -21,31 +23,47 @@ func main() {
 
 	def := &codegen.ServerDefinition{
 		Capabilities: codegen.ServerCapabilities{
-			Tools:   &codegen.ToolCapability{},
-			Logging: &codegen.LoggingCapability{},
+			Prompts:     &codegen.PromptCapability{},
+			Tools:       &codegen.ToolCapability{},
+			Resources:   &codegen.ResourceCapability{Subscribe: true, ListChanged: true},
+			Completions: &codegen.CompletionsCapability{},
+			Logging:     &codegen.LoggingCapability{},
 		},
 		Implementation: codegen.Implementation{
 			Name:    "Scrapbox MCP Server",
 			Version: "1.0.0",
 		},
+		Prompts: promptDefinitions(),
+		ResourceTemplates: []codegen.ResourceTemplate{
+			{
+				URITemplate: resources.URITemplate,
+				Name:        "page",
+				Description: "A page of the Scrapbox project as Markdown. Append ?format=json for the page JSON.",
+				MimeType:    resources.MIMEMarkdown,
+			},
+		},
 		Tools: []codegen.Tool{
 			{
 				Name:        "get_page",
 				Description: "Get a Scrapbox page by title",
 				InputSchema: struct {
-					PageTitle string `json:"page_title" jsonschema:"description=Page title to retrieve,required"`
+					PageTitle string  `json:"page_title" jsonschema:"description=Page title to retrieve,required"`
+					Project   *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
 				}{},
 			},
 			{
 				Name:        "list_pages",
 				Description: "Get a list of pages in the project (max 1000 pages)",
-				InputSchema: struct{}{},
+				InputSchema: struct {
+					Project *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+				}{},
 			},
 			{
 				Name:        "search_pages",
 				Description: "Full-text search across all pages in the project (max 100 pages)",
 				InputSchema: struct {
-					Query string `json:"query" jsonschema:"description=Search query,required"`
+					Query   string  `json:"query" jsonschema:"description=Search query,required"`
+					Project *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
 				}{},
 			},
 			{
@@ -54,8 +72,66 @@ func main() {
 				InputSchema: struct {
 					PageTitle string  `json:"page_title" jsonschema:"description=Page title,required"`
 					BodyText  *string `json:"body_text" jsonschema:"description=Body text for the new page"`
+					Project   *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+				}{},
+			},
+			{
+				Name:        "get_page_history",
+				Description: "List the saved snapshots and commits of a page, newest first",
+				InputSchema: struct {
+					PageTitle string  `json:"page_title" jsonschema:"description=Page title,required"`
+					Project   *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+				}{},
+			},
+			{
+				Name:        "get_page_at",
+				Description: "Get the content of a page as it was at a point in time",
+				InputSchema: struct {
+					PageTitle string  `json:"page_title" jsonschema:"description=Page title,required"`
+					Time      string  `json:"time" jsonschema:"description=Point in time as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds,required"`
+					Project   *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+				}{},
+			},
+			{
+				Name:        "diff_page_versions",
+				Description: "Show a unified diff between two versions of a page",
+				InputSchema: struct {
+					PageTitle string  `json:"page_title" jsonschema:"description=Page title,required"`
+					From      string  `json:"from" jsonschema:"description=Time of the older version,required"`
+					To        *string `json:"to,omitempty" jsonschema:"description=Time of the newer version (defaults to the current page)"`
+					Project   *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+				}{},
+			},
+			{
+				Name:        "blame_page",
+				Description: "Show who last edited each line of a page, grouped by author and editing session",
+				InputSchema: struct {
+					PageTitle string  `json:"page_title" jsonschema:"description=Page title,required"`
+					Since     *string `json:"since,omitempty" jsonschema:"description=Only include lines changed at or after this time (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)"`
+					Project   *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
 				}{},
 			},
+			{
+				Name:        "recent_changes",
+				Description: "List pages updated since a time with the lines each author added or modified",
+				InputSchema: struct {
+					Since    string  `json:"since" jsonschema:"description=Start of the period (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds),required"`
+					MaxChars *int    `json:"max_chars,omitempty" jsonschema:"description=Maximum total characters of line text to return"`
+					Project  *string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+				}{},
+			},
+			{
+				Name:        "search_all",
+				Description: "Full-text search across every project at once, with the project of each page and the projects whose search failed",
+				InputSchema: struct {
+					Query string `json:"query" jsonschema:"description=Search query,required"`
+				}{},
+			},
+			{
+				Name:        "list_projects",
+				Description: "List the Scrapbox projects served, which the project argument of the other tools selects",
+				InputSchema: struct{}{},
+			},
 		},
 	}
 
@@ -63,3 +139,20 @@ func main() {
 		log.Fatalf("Failed to generate code: %v", err)
 	}
 }
+
+// promptDefinitions converts the prompts shared by every implementation.
+func promptDefinitions() []codegen.Prompt {
+	var defs []codegen.Prompt
+	for _, p := range prompts.Prompts {
+		def := codegen.Prompt{Name: p.Name, Description: p.Description}
+		for _, a := range p.Arguments {
+			def.Arguments = append(def.Arguments, codegen.PromptArgument{
+				Name:        a.Name,
+				Description: a.Description,
+				Required:    a.Required,
+			})
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/takak2166/scrapbox-mcp/internal/export"
+)
+
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "", "output directory (required)")
+	storeDir := fs.String("store", "", "page store directory (default: user cache directory)")
+	noSync := fs.Bool("no-sync", false, "export the page store as is, without syncing it first")
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("-out is required")
+	}
+
+	store, err := openStore(ctx, cfg, *storeDir, *noSync)
+	if err != nil {
+		return err
+	}
+	exp := &export.Exporter{Store: store, Dir: *out}
+	res, err := exp.Export()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported to %s: %d written, %d removed, %d unchanged\n", *out, len(res.Written), len(res.Removed), res.Unchanged)
+	return nil
+}
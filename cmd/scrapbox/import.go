@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/takak2166/scrapbox-mcp/internal/importer"
+)
+
+func runImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dir := fs.String("dir", "", "directory of Markdown files to import (required)")
+	out := fs.String("out", "", "write the import JSON to this file instead of stdout")
+	preview := fs.Bool("preview", false, "report conversion warnings per file without writing the import JSON")
+	fs.Parse(args)
+	if *dir == "" {
+		return errors.New("-dir is required")
+	}
+
+	res, err := importer.ImportDir(*dir)
+	if err != nil {
+		return err
+	}
+
+	if *preview {
+		warnings := 0
+		for _, f := range res.Files {
+			fmt.Printf("%s -> %q\n", f.Path, f.Title)
+			for _, w := range f.Warnings {
+				fmt.Printf("  warning: %s\n", w)
+			}
+			warnings += len(f.Warnings)
+		}
+		fmt.Printf("%d files, %d pages, %d warnings\n", len(res.Files), len(res.Data.Pages), warnings)
+		return nil
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res.Data); err != nil {
+		return fmt.Errorf("failed to write import JSON: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "converted %d files into %d pages\n", len(res.Files), len(res.Data.Pages))
+	return nil
+}
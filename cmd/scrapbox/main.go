@@ -0,0 +1,117 @@
+// Package main implements the scrapbox command-line tool, which works on a
+// local copy of a Scrapbox project outside of any MCP client.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/takak2166/scrapbox-mcp/internal/acl"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/pagestore"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// command is a subcommand of the scrapbox tool.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "export", summary: "Export the project to a Markdown directory / Obsidian vault", run: runExport},
+	{name: "import", summary: "Convert a directory of Markdown files to Scrapbox import JSON", run: runImport},
+	{name: "mirror", summary: "Commit page changes to a local git repository", run: runMirror},
+	{name: "site", summary: "Generate a static HTML site for offline reading", run: runSite},
+	{name: "config", summary: "Validate the configuration (config check)", run: runConfig},
+	{name: "policy", summary: "Explain whether the page access rules deny a page", run: runPolicy},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(context.Background(), os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "scrapbox %s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: scrapbox <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+// runConfig runs the config subcommand shared with the MCP servers.
+func runConfig(_ context.Context, args []string) error {
+	return config.Command(args, os.Stdout)
+}
+
+// defaultStoreDir returns the page store location used when -store is not given.
+func defaultStoreDir(project string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "scrapbox-mcp", project)
+}
+
+// loadConfig parses args with fs, the flags of a subcommand, to which it
+// adds the configuration flags of the MCP servers, such as -config and
+// -profile, and loads the configuration as the servers do.
+func loadConfig(fs *flag.FlagSet, args []string) (*config.Config, error) {
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// openStore opens the project's page store, syncing it with Scrapbox first
+// unless noSync is set. Pages denied by the page access rules are never
+// synced, and are deleted from the store if they were synced before the
+// rules changed.
+func openStore(ctx context.Context, cfg *config.Config, dir string, noSync bool) (*pagestore.Store, error) {
+	if dir == "" {
+		dir = defaultStoreDir(cfg.ProjectName)
+	}
+	store, err := pagestore.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := acl.FromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !noSync {
+		client := scrapbox.NewClient(cfg.ProjectName, cfg.ScrapboxSID, scrapbox.WithPageFilter(policy))
+		res, err := store.Sync(ctx, client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync page store: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "synced %s: %d updated, %d deleted\n", store.Dir(), len(res.Updated), len(res.Deleted))
+	}
+	denied, err := store.Prune(func(p *scrapbox.Page) bool { return policy.Denies(p.Title, pageLinks(p)) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply the page access rules to the page store: %w", err)
+	}
+	if len(denied) > 0 {
+		fmt.Fprintf(os.Stderr, "deleted %d pages denied by the page access rules from %s\n", len(denied), store.Dir())
+	}
+	return store, nil
+}
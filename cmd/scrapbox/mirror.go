@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/takak2166/scrapbox-mcp/internal/mirror"
+)
+
+func runMirror(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("mirror", flag.ExitOnError)
+	repo := fs.String("repo", "", "git working tree to keep in sync (required)")
+	storeDir := fs.String("store", "", "page store directory (default: user cache directory)")
+	noSync := fs.Bool("no-sync", false, "mirror the page store as is, without syncing it first")
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if *repo == "" {
+		return errors.New("-repo is required")
+	}
+
+	store, err := openStore(ctx, cfg, *storeDir, *noSync)
+	if err != nil {
+		return err
+	}
+	m := &mirror.Mirror{Store: store, Dir: *repo}
+	res, err := m.Sync(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "mirrored to %s: %d commits\n", *repo, res.Commits)
+	return nil
+}
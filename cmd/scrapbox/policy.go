@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/takak2166/scrapbox-mcp/internal/acl"
+	scrapboxerrors "github.com/takak2166/scrapbox-mcp/internal/errors"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox/notation"
+)
+
+// runPolicy runs "policy explain <title>", which prints whether the page
+// access rules hide the page titled title from MCP clients, and which rules
+// do.
+func runPolicy(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "explain" {
+		return errors.New("usage: scrapbox policy explain [-offline] <title>")
+	}
+	fs := flag.NewFlagSet("policy explain", flag.ExitOnError)
+	offline := fs.Bool("offline", false, "check the title only, without fetching the links of the page")
+	cfg, err := loadConfig(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: scrapbox policy explain [-offline] <title>")
+	}
+	title := fs.Arg(0)
+
+	policy, err := acl.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Tag rules depend on the links of the page, which are fetched without
+	// the rules applied.
+	var links []string
+	if !*offline && policy.NeedsLinks() {
+		client := scrapbox.NewClient(cfg.ProjectName, cfg.ScrapboxSID)
+		page, err := client.GetPage(ctx, title)
+		var se *scrapboxerrors.ScrapboxError
+		switch {
+		case errors.As(err, &se) && se.Code == http.StatusNotFound:
+			fmt.Fprintf(os.Stderr, "%s does not exist in %s; checking its title only\n", title, cfg.ProjectName)
+		case err != nil:
+			return fmt.Errorf("failed to get the links of %s: %w", title, err)
+		default:
+			title, links = page.Title, pageLinks(page)
+		}
+	}
+
+	reasons := policy.Explain(title, links)
+	if len(reasons) == 0 {
+		fmt.Printf("%s is allowed: no rule denies it\n", title)
+		return nil
+	}
+	fmt.Printf("%s is denied:\n", title)
+	for _, r := range reasons {
+		fmt.Printf("  %s\n", r)
+	}
+	return nil
+}
+
+// pageLinks returns the titles page links to, parsing its lines if it was
+// stored without them.
+func pageLinks(page *scrapbox.Page) []string {
+	if page.Links != nil {
+		return page.Links
+	}
+	lines := make([]string, len(page.Lines))
+	for i, l := range page.Lines {
+		lines[i] = l.Text
+	}
+	return notation.Titles(notation.Parse(lines))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/takak2166/scrapbox-mcp/internal/site"
+)
+
+func runSite(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("site", flag.ExitOnError)
+	out := fs.String("out", "", "output directory (required)")
+	storeDir := fs.String("store", "", "page store directory (default: user cache directory)")
+	noSync := fs.Bool("no-sync", false, "render the page store as is, without syncing it first")
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("-out is required")
+	}
+
+	store, err := openStore(ctx, cfg, *storeDir, *noSync)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	s := &site.Site{Store: store, Dir: *out, Project: cfg.ProjectName}
+	if err := s.Generate(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "generated site for %d pages in %s\n", len(store.Entries()), *out)
+	return nil
+}
@@ -7,6 +7,7 @@ toolchain go1.23.8
 require (
 	github.com/google/go-cmp v0.7.0
 	github.com/modelcontextprotocol/go-sdk v0.0.0-20250627194314-8a3f272dbbcf
+	github.com/pelletier/go-toml/v2 v2.4.3
 )
 
 require (
@@ -40,8 +41,8 @@ require (
 	golang.org/x/exp/jsonrpc2 v0.0.0-20250408133849-7e4ce0ab07d0
 	golang.org/x/mod v0.25.0 // indirect
 	golang.org/x/sync v0.15.0 // indirect
-	golang.org/x/text v0.24.0 // indirect
+	golang.org/x/text v0.24.0
 	golang.org/x/tools v0.34.0 // indirect
 	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
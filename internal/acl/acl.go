@@ -0,0 +1,144 @@
+// Package acl keeps the access rules hiding pages of a project from MCP
+// clients, such as HR or incident pages that must not be fed to agents. A
+// page is denied if its title is listed, matches the title pattern or links
+// to one of the listed tags, as #tag or [tag], which Scrapbox treats alike.
+//
+// A Policy is the scrapbox.PageFilter of every client of a server, so the
+// rules apply to every read path: pages are left out of lists, search
+// results, title lists and backlinks, and reading them fails.
+package acl
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox/notation"
+)
+
+// Rules are the deny rules of a Policy.
+type Rules struct {
+	// Titles are the titles of denied pages, compared the way Scrapbox
+	// compares titles.
+	Titles []string
+	// Pattern, unless empty, is a regular expression matching the titles of
+	// denied pages.
+	Pattern string
+	// Tags deny the pages linking to them.
+	Tags []string
+}
+
+// RulesFromConfig returns the rules configured by cfg.
+func RulesFromConfig(cfg *config.Config) Rules {
+	return Rules{Titles: cfg.DenyTitles, Pattern: cfg.DenyTitlePattern, Tags: cfg.DenyTags}
+}
+
+// Reason is a rule denying a page: the setting configuring it and the
+// title, pattern or tag it matched.
+type Reason struct {
+	Setting string
+	Match   string
+}
+
+// String describes the reason, e.g. `deny_tags includes "hr"`.
+func (r Reason) String() string {
+	if r.Setting == "deny_title_pattern" {
+		return fmt.Sprintf("%s %q matches the title", r.Setting, r.Match)
+	}
+	return fmt.Sprintf("%s includes %q", r.Setting, r.Match)
+}
+
+// Policy denies the pages matching its rules. Its rules can be replaced
+// while it is in use; each replacement is a new version, so that clients
+// drop the results they cached under the previous rules.
+type Policy struct {
+	mu      sync.RWMutex
+	version uint64
+	titles  map[string]string
+	pattern *regexp.Regexp
+	tags    map[string]string
+}
+
+// New creates a Policy with rules.
+func New(rules Rules) (*Policy, error) {
+	p := &Policy{}
+	if err := p.Set(rules); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// FromConfig creates the Policy with the rules configured by cfg.
+func FromConfig(cfg *config.Config) (*Policy, error) {
+	return New(RulesFromConfig(cfg))
+}
+
+// Set replaces the rules of the Policy, unless the pattern is invalid.
+func (p *Policy) Set(rules Rules) error {
+	var pattern *regexp.Regexp
+	if rules.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(rules.Pattern); err != nil {
+			return fmt.Errorf("invalid title pattern: %w", err)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.titles, p.pattern, p.tags = normalize(rules.Titles), pattern, normalize(rules.Tags)
+	p.version++
+	return nil
+}
+
+// Version returns the version of the rules, which changes with every Set.
+func (p *Policy) Version() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.version
+}
+
+// normalize maps the normalized form of each title to the title.
+func normalize(titles []string) map[string]string {
+	m := make(map[string]string, len(titles))
+	for _, t := range titles {
+		m[notation.NormalizeTitle(t)] = t
+	}
+	return m
+}
+
+// NeedsLinks reports whether the Policy has tag rules, which need the links
+// of pages.
+func (p *Policy) NeedsLinks() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.tags) > 0
+}
+
+// Denies reports whether the page titled title, linking to links, is
+// denied.
+func (p *Policy) Denies(title string, links []string) bool {
+	return len(p.Explain(title, links)) > 0
+}
+
+// Explain returns the rules denying the page titled title, linking to links,
+// or nil if it is allowed.
+func (p *Policy) Explain(title string, links []string) []Reason {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var reasons []Reason
+	if t, ok := p.titles[notation.NormalizeTitle(title)]; ok {
+		reasons = append(reasons, Reason{Setting: "deny_titles", Match: t})
+	}
+	if p.pattern != nil && p.pattern.MatchString(title) {
+		reasons = append(reasons, Reason{Setting: "deny_title_pattern", Match: p.pattern.String()})
+	}
+	var tags []string
+	for _, l := range links {
+		if t, ok := p.tags[notation.NormalizeTitle(l)]; ok && !slices.Contains(tags, t) {
+			tags = append(tags, t)
+			reasons = append(reasons, Reason{Setting: "deny_tags", Match: t})
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,82 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPolicy_Explain(t *testing.T) {
+	policy, err := New(Rules{
+		Titles:  []string{"HR handbook"},
+		Pattern: "^incident/",
+		Tags:    []string{"confidential", "HR"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := map[string]struct {
+		title string
+		links []string
+		want  []Reason
+	}{
+		"ok: allowed": {
+			title: "Weekly notes",
+			links: []string{"meeting", "incident/2024-05-01"},
+		},
+		"ok: title compared as Scrapbox does": {
+			title: "hr_Handbook",
+			want:  []Reason{{Setting: "deny_titles", Match: "HR handbook"}},
+		},
+		"ok: title pattern": {
+			title: "incident/2024-05-01",
+			want:  []Reason{{Setting: "deny_title_pattern", Match: "^incident/"}},
+		},
+		"ok: every matching rule": {
+			title: "incident/db outage",
+			links: []string{"hr", "Confidential", "HR", "postmortem"},
+			want: []Reason{
+				{Setting: "deny_title_pattern", Match: "^incident/"},
+				{Setting: "deny_tags", Match: "HR"},
+				{Setting: "deny_tags", Match: "confidential"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := policy.Explain(tc.title, tc.links)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Explain() mismatch (-want +got):\n%s", diff)
+			}
+			if denied := policy.Denies(tc.title, tc.links); denied != (len(tc.want) > 0) {
+				t.Errorf("Denies() = %v, want %v", denied, len(tc.want) > 0)
+			}
+		})
+	}
+}
+
+func TestPolicy_Set(t *testing.T) {
+	policy, err := New(Rules{Titles: []string{"HR"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := policy.Set(Rules{Pattern: "incident/("}); err == nil {
+		t.Error("Set() with an invalid pattern error = nil, want error")
+	}
+	if !policy.Denies("HR", nil) {
+		t.Error("Denies() after a rejected Set() = false, want the previous rules")
+	}
+	version := policy.Version()
+	if policy.NeedsLinks() {
+		t.Error("NeedsLinks() without tags = true, want false")
+	}
+	if err := policy.Set(Rules{Tags: []string{"hr"}}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if policy.Denies("HR", nil) || !policy.Denies("Salaries", []string{"HR"}) || !policy.NeedsLinks() {
+		t.Error("Set() did not replace the rules")
+	}
+	if policy.Version() == version {
+		t.Error("Version() unchanged by Set()")
+	}
+}
@@ -0,0 +1,301 @@
+// Package auth authenticates the HTTP mode of the MCP servers.
+//
+// Requests carry an access token in the Authorization header as a bearer
+// token. A token is either one of the static API tokens of the configuration
+// or a JWT issued by an OAuth 2.1 authorization server, verified against a
+// local JWKS file as an MCP resource server: the signature, expiry and
+// audience are checked, and the issuer if one is configured.
+//
+// Every MCP request needs the read scope; calling a write tool also needs the
+// write scope. Rejected requests get the WWW-Authenticate challenges of
+// RFC 6750, pointing clients to the protected resource metadata of RFC 9728
+// served at MetadataPath.
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+)
+
+const (
+	// ScopeRead allows every MCP request except calls to write tools.
+	ScopeRead = "scrapbox:read"
+	// ScopeWrite allows calls to write tools.
+	ScopeWrite = "scrapbox:write"
+
+	// MetadataPath is the path of the protected resource metadata.
+	MetadataPath = "/.well-known/oauth-protected-resource"
+)
+
+// ToolScope returns the scope needed to call the tool called name: write
+// tools need ScopeWrite.
+func ToolScope(name string) string {
+	if tools.Write(name) {
+		return ScopeWrite
+	}
+	return ScopeRead
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Subject is the sub claim of a JWT, or "token:<n>" for the n-th static
+	// token of the configuration.
+	Subject string
+	Scopes  []string
+}
+
+// HasScope reports whether the identity was granted scope.
+func (id *Identity) HasScope(scope string) bool {
+	return slices.Contains(id.Scopes, scope)
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity carried by ctx, if any.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// Options configures an Authenticator.
+type Options struct {
+	// Tokens are static API tokens granting both scopes.
+	Tokens []string
+	// ReadOnlyTokens are static API tokens granting ScopeRead only.
+	ReadOnlyTokens []string
+
+	// JWKS verifies JWT access tokens; nil disables them.
+	JWKS *JWKS
+	// Audience is the aud claim required in JWTs, normally the URL of the
+	// MCP endpoint. It is also the resource of the metadata.
+	Audience string
+	// Issuer is the iss claim required in JWTs if not empty. It is
+	// advertised as the authorization server in the metadata.
+	Issuer string
+}
+
+// ErrInvalidToken is returned by Authenticate for tokens that are neither a
+// static token nor a valid JWT.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Authenticator checks the access tokens of HTTP requests.
+type Authenticator struct {
+	opts   Options
+	tokens []staticToken
+	now    func() time.Time
+}
+
+type staticToken struct {
+	token  []byte
+	scopes []string
+}
+
+// New creates an Authenticator. At least one token or a JWKS is required,
+// and an audience is required with a JWKS.
+func New(opts Options) (*Authenticator, error) {
+	a := &Authenticator{opts: opts, now: time.Now}
+	for _, t := range opts.Tokens {
+		a.tokens = append(a.tokens, staticToken{token: []byte(t), scopes: []string{ScopeRead, ScopeWrite}})
+	}
+	for _, t := range opts.ReadOnlyTokens {
+		a.tokens = append(a.tokens, staticToken{token: []byte(t), scopes: []string{ScopeRead}})
+	}
+	if len(a.tokens) == 0 && opts.JWKS == nil {
+		return nil, errors.New("no tokens or JWKS configured")
+	}
+	if opts.JWKS != nil && opts.Audience == "" {
+		return nil, errors.New("an audience is required to verify JWTs")
+	}
+	return a, nil
+}
+
+// FromConfig creates the Authenticator configured by cfg. It returns nil if
+// cfg configures no authentication.
+func FromConfig(cfg *config.Config) (*Authenticator, error) {
+	if !cfg.AuthEnabled() {
+		return nil, nil
+	}
+	opts := Options{
+		Tokens:         cfg.AuthTokens,
+		ReadOnlyTokens: cfg.AuthReadOnlyTokens,
+		Audience:       cfg.AuthAudience,
+		Issuer:         cfg.AuthIssuer,
+	}
+	if cfg.AuthJWKSFile != "" {
+		jwks, err := LoadJWKS(cfg.AuthJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.JWKS = jwks
+	}
+	return New(opts)
+}
+
+// Authenticate returns the identity of token.
+func (a *Authenticator) Authenticate(token string) (*Identity, error) {
+	var found *Identity
+	for i, t := range a.tokens {
+		// Compare with every token so that the time taken does not tell
+		// which one matched.
+		if subtle.ConstantTimeCompare([]byte(token), t.token) == 1 && found == nil {
+			found = &Identity{Subject: fmt.Sprintf("token:%d", i+1), Scopes: t.scopes}
+		}
+	}
+	if found != nil {
+		return found, nil
+	}
+	if a.opts.JWKS == nil {
+		return nil, ErrInvalidToken
+	}
+	c, err := verifyJWT(token, a.opts.JWKS, a.opts.Audience, a.opts.Issuer, a.now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	id := &Identity{Subject: c.Subject, Scopes: c.Scp}
+	if c.Scope != "" {
+		id.Scopes = append(id.Scopes, strings.Fields(c.Scope)...)
+	}
+	return id, nil
+}
+
+// Middleware rejects requests without a valid access token or with too few
+// scopes for the MCP messages they carry, and adds the identity to the
+// context of the others.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			a.challenge(w, r, http.StatusUnauthorized, "", "", "")
+			return
+		}
+		id, err := a.Authenticate(token)
+		if err != nil {
+			a.challenge(w, r, http.StatusUnauthorized, "invalid_token", "The access token is invalid or expired", "")
+			return
+		}
+
+		scopes := []string{ScopeRead}
+		if r.Method == http.MethodPost && r.Body != nil {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "failed to read body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			scopes = requiredScopes(body)
+		}
+		for _, s := range scopes {
+			if !id.HasScope(s) {
+				a.challenge(w, r, http.StatusForbidden, "insufficient_scope", "The request needs the "+s+" scope", s)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// bearerToken returns the token of the Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// requiredScopes returns the scopes needed by the JSON-RPC message or batch
+// in body. Bodies that are not JSON-RPC are left for the transport to reject.
+func requiredScopes(body []byte) []string {
+	type message struct {
+		Method string `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+	var batch []message
+	if err := json.Unmarshal(body, &batch); err != nil {
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return []string{ScopeRead}
+		}
+		batch = []message{msg}
+	}
+	scopes := []string{ScopeRead}
+	for _, m := range batch {
+		if m.Method == "tools/call" && ToolScope(m.Params.Name) == ScopeWrite && !slices.Contains(scopes, ScopeWrite) {
+			scopes = append(scopes, ScopeWrite)
+		}
+	}
+	return scopes
+}
+
+// challenge rejects a request with a WWW-Authenticate challenge. code and
+// scope are omitted when empty.
+func (a *Authenticator) challenge(w http.ResponseWriter, r *http.Request, status int, code, description, scope string) {
+	params := []string{fmt.Sprintf("resource_metadata=%q", metadataURL(r))}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code), fmt.Sprintf("error_description=%q", description))
+	}
+	if scope != "" {
+		params = append(params, fmt.Sprintf("scope=%q", scope))
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if code == "" {
+		code, description = "unauthorized", "An access token is required"
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+// metadataURL returns the URL of the protected resource metadata of the
+// server r was sent to.
+func metadataURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + MetadataPath
+}
+
+// MetadataHandler serves the protected resource metadata of RFC 9728.
+func (a *Authenticator) MetadataHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metadata := struct {
+			Resource               string   `json:"resource"`
+			AuthorizationServers   []string `json:"authorization_servers,omitempty"`
+			ScopesSupported        []string `json:"scopes_supported"`
+			BearerMethodsSupported []string `json:"bearer_methods_supported"`
+		}{
+			Resource:               a.opts.Audience,
+			ScopesSupported:        []string{ScopeRead, ScopeWrite},
+			BearerMethodsSupported: []string{"header"},
+		}
+		if metadata.Resource == "" {
+			metadata.Resource = strings.TrimSuffix(metadataURL(r), MetadataPath)
+		}
+		if a.opts.Issuer != "" {
+			metadata.AuthorizationServers = []string{a.opts.Issuer}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(metadata)
+	})
+}
@@ -0,0 +1,385 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+const testAudience = "https://mcp.example.com/mcp"
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// signJWT signs claims with key as a compact JWS.
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	hash := signingAlgs[alg]
+	h := hash.New()
+	h.Write([]byte(input))
+	digest := h.Sum(nil)
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+	}
+	return input + "." + b64(sig)
+}
+
+func testJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) *JWKS {
+	t.Helper()
+	size := (ecKey.Curve.Params().BitSize + 7) / 8
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, size))), "y": b64(ecKey.Y.FillBytes(make([]byte, size)))},
+		{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+	}}
+	data, _ := json.Marshal(set)
+	jwks, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatalf("ParseJWKS() error = %v", err)
+	}
+	return jwks
+}
+
+func TestParseJWKS(t *testing.T) {
+	tests := map[string]struct {
+		data    string
+		want    int
+		wantErr bool
+	}{
+		"ok: skips other keys": {
+			data: `{"keys":[{"kty":"RSA","n":"AQAB","e":"AQAB"},{"kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"},{"kty":"oct","k":"AA"}]}`,
+			want: 1,
+		},
+		"err: no usable keys": {
+			data:    `{"keys":[{"kty":"oct","k":"AA"}]}`,
+			wantErr: true,
+		},
+		"err: point not on the curve": {
+			data:    `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+			wantErr: true,
+		},
+		"err: unsupported curve": {
+			data:    `{"keys":[{"kty":"EC","crv":"secp256k1","x":"AQ","y":"AQ"}]}`,
+			wantErr: true,
+		},
+		"err: not JSON": {
+			data:    `keys`,
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseJWKS([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("ParseJWKS() error = nil, wantErr true")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJWKS() error = %v", err)
+			}
+			if len(got.keys) != tt.want {
+				t.Errorf("len(keys) = %d, want %d", len(got.keys), tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(Options{
+		Tokens:         []string{"full-token"},
+		ReadOnlyTokens: []string{"read-token"},
+		JWKS:           testJWKS(t, rsaKey, ecKey),
+		Audience:       testAudience,
+		Issuer:         "https://auth.example.com",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	a.now = func() time.Time { return testNow }
+
+	claims := func(override map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":   "alice",
+			"iss":   "https://auth.example.com",
+			"aud":   testAudience,
+			"exp":   testNow.Add(time.Hour).Unix(),
+			"scope": "scrapbox:read scrapbox:write",
+		}
+		for k, v := range override {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	tests := map[string]struct {
+		token   string
+		want    *Identity
+		wantErr bool
+	}{
+		"ok: static token": {
+			token: "full-token",
+			want:  &Identity{Subject: "token:1", Scopes: []string{ScopeRead, ScopeWrite}},
+		},
+		"ok: read-only static token": {
+			token: "read-token",
+			want:  &Identity{Subject: "token:2", Scopes: []string{ScopeRead}},
+		},
+		"ok: RS256 JWT": {
+			token: signJWT(t, "RS256", "rsa1", rsaKey, claims(nil)),
+			want:  &Identity{Subject: "alice", Scopes: []string{ScopeRead, ScopeWrite}},
+		},
+		"ok: ES256 JWT with scp and audience list": {
+			token: signJWT(t, "ES256", "ec1", ecKey, claims(map[string]any{
+				"scope": nil,
+				"scp":   []string{ScopeRead},
+				"aud":   []string{"other", testAudience},
+			})),
+			want: &Identity{Subject: "alice", Scopes: []string{ScopeRead}},
+		},
+		"ok: JWT without kid": {
+			token: signJWT(t, "RS256", "", rsaKey, claims(nil)),
+			want:  &Identity{Subject: "alice", Scopes: []string{ScopeRead, ScopeWrite}},
+		},
+		"ok: expired within clock skew": {
+			token: signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]any{"exp": testNow.Add(-30 * time.Second).Unix()})),
+			want:  &Identity{Subject: "alice", Scopes: []string{ScopeRead, ScopeWrite}},
+		},
+		"err: unknown static token": {
+			token:   "wrong-token",
+			wantErr: true,
+		},
+		"err: expired JWT": {
+			token:   signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]any{"exp": testNow.Add(-time.Hour).Unix()})),
+			wantErr: true,
+		},
+		"err: JWT without expiry": {
+			token:   signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]any{"exp": nil})),
+			wantErr: true,
+		},
+		"err: JWT not valid yet": {
+			token:   signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]any{"nbf": testNow.Add(time.Hour).Unix()})),
+			wantErr: true,
+		},
+		"err: wrong audience": {
+			token:   signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]any{"aud": "https://other.example.com"})),
+			wantErr: true,
+		},
+		"err: wrong issuer": {
+			token:   signJWT(t, "RS256", "rsa1", rsaKey, claims(map[string]any{"iss": "https://evil.example.com"})),
+			wantErr: true,
+		},
+		"err: signed by another key": {
+			token:   signJWT(t, "RS256", "rsa1", otherKey, claims(nil)),
+			wantErr: true,
+		},
+		"err: kid of another key": {
+			token:   signJWT(t, "RS256", "ec1", rsaKey, claims(nil)),
+			wantErr: true,
+		},
+		"err: algorithm does not match the key": {
+			token:   signJWT(t, "ES256", "rsa1", rsaKey, claims(nil)),
+			wantErr: true,
+		},
+		"err: unsupported algorithm": {
+			token:   b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{"sub":"alice"}`)) + ".",
+			wantErr: true,
+		},
+		"err: malformed": {
+			token:   "a.b",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := a.Authenticate(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Authenticate() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if got.Subject != tt.want.Subject || strings.Join(got.Scopes, " ") != strings.Join(tt.want.Scopes, " ") {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	a, err := New(Options{Tokens: []string{"full-token"}, ReadOnlyTokens: []string{"read-token"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("FromContext() found no identity")
+		}
+		_, _ = w.Write([]byte(id.Subject))
+	})
+	h := a.Middleware(next)
+
+	const metadata = `resource_metadata="http://mcp.example.com/.well-known/oauth-protected-resource"`
+	tests := map[string]struct {
+		method        string
+		authorization string
+		body          string
+		wantStatus    int
+		wantChallenge string
+		wantBody      string
+	}{
+		"ok: read tool with read-only token": {
+			method:        http.MethodPost,
+			authorization: "Bearer read-token",
+			body:          `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_page"}}`,
+			wantStatus:    http.StatusOK,
+			wantBody:      "token:2",
+		},
+		"ok: write tool with full token": {
+			method:        http.MethodPost,
+			authorization: "bearer full-token",
+			body:          `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"create_page_url"}}`,
+			wantStatus:    http.StatusOK,
+			wantBody:      "token:1",
+		},
+		"ok: event stream": {
+			method:        http.MethodGet,
+			authorization: "Bearer read-token",
+			wantStatus:    http.StatusOK,
+			wantBody:      "token:2",
+		},
+		"err: no token": {
+			method:        http.MethodPost,
+			body:          `{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: "Bearer " + metadata,
+		},
+		"err: other scheme": {
+			method:        http.MethodGet,
+			authorization: "Basic dXNlcjpwYXNz",
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: "Bearer " + metadata,
+		},
+		"err: invalid token": {
+			method:        http.MethodGet,
+			authorization: "Bearer wrong-token",
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: "Bearer " + metadata + `, error="invalid_token", error_description="The access token is invalid or expired"`,
+		},
+		"err: write tool with read-only token": {
+			method:        http.MethodPost,
+			authorization: "Bearer read-token",
+			body:          `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"create_page_url"}}`,
+			wantStatus:    http.StatusForbidden,
+			wantChallenge: "Bearer " + metadata + `, error="insufficient_scope", error_description="The request needs the scrapbox:write scope", scope="scrapbox:write"`,
+		},
+		"err: write tool in a batch with read-only token": {
+			method:        http.MethodPost,
+			authorization: "Bearer read-token",
+			body:          `[{"jsonrpc":"2.0","id":1,"method":"tools/list"},{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"create_page_url"}}]`,
+			wantStatus:    http.StatusForbidden,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://mcp.example.com/mcp", strings.NewReader(tt.body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); tt.wantChallenge != "" && got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %s, want %s", got, tt.wantChallenge)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_MetadataHandler(t *testing.T) {
+	tests := map[string]struct {
+		opts Options
+		want string
+	}{
+		"static tokens": {
+			opts: Options{Tokens: []string{"token"}},
+			want: `{"resource":"http://mcp.example.com","scopes_supported":["scrapbox:read","scrapbox:write"],"bearer_methods_supported":["header"]}`,
+		},
+		"authorization server": {
+			opts: Options{Tokens: []string{"token"}, Audience: testAudience, Issuer: "https://auth.example.com"},
+			want: `{"resource":"https://mcp.example.com/mcp","authorization_servers":["https://auth.example.com"],"scopes_supported":["scrapbox:read","scrapbox:write"],"bearer_methods_supported":["header"]}`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, err := New(tt.opts)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			rec := httptest.NewRecorder()
+			a.MetadataHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://mcp.example.com"+MetadataPath, nil))
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
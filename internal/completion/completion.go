@@ -0,0 +1,258 @@
+// Package completion answers MCP completion/complete requests for page
+// titles.
+//
+// Candidates come from the title list of the project, which is cached for
+// TTL, or until the access rules hiding pages change, and ranked by page
+// views and links. A value matches a title when it is
+// a prefix of it, occurs in it, or has its characters appear in order in it,
+// in decreasing order of relevance. Matching ignores case, treats underscores
+// as spaces like Scrapbox does, and folds full-width forms and katakana so
+// that Japanese titles can be typed either way.
+package completion
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// Reference types of completion/complete requests.
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+
+	// PageTitleArgument is the name of the tool and prompt arguments that
+	// take a page title.
+	PageTitleArgument = "page_title"
+
+	// DefaultTTL is how long the title list is cached when New is given a
+	// non-positive TTL.
+	DefaultTTL = 5 * time.Minute
+	// MaxValues is the largest number of values in a result, as allowed by
+	// the MCP specification.
+	MaxValues = 100
+)
+
+// Source is the subset of scrapbox.Client used to list page titles.
+type Source interface {
+	ProjectName() string
+	ListAllTitles(ctx context.Context) ([]scrapbox.PageTitle, error)
+	ListAllPages(ctx context.Context) ([]scrapbox.Page, error)
+	// FilterVersion returns the version of the rules hiding pages from
+	// the lists, which invalidates the cached list when it changes.
+	FilterVersion() uint64
+}
+
+// Result is the completion of an argument.
+type Result struct {
+	Values  []string
+	Total   int
+	HasMore bool
+}
+
+// match kinds in decreasing order of relevance.
+const (
+	matchExact = iota
+	matchPrefix
+	matchSubstring
+	matchFuzzy
+	noMatch
+)
+
+// candidate is a cached page title.
+type candidate struct {
+	title  string
+	key    string
+	views  int
+	linked int
+}
+
+// Completer completes page titles from a cached title list.
+type Completer struct {
+	src Source
+	now func() time.Time
+
+	mu      sync.Mutex
+	ttl     time.Duration
+	titles  []candidate
+	loaded  time.Time
+	version uint64
+}
+
+// New creates a Completer caching the title list of src for ttl.
+func New(src Source, ttl time.Duration) *Completer {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Completer{src: src, ttl: ttl, now: time.Now}
+}
+
+// SetTTL changes how long the title list is cached, DefaultTTL if ttl is not
+// positive. A cached list expires according to the new TTL.
+func (c *Completer) SetTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	c.mu.Lock()
+	c.ttl = ttl
+	c.mu.Unlock()
+}
+
+// Complete completes value for the argument argName of the prompt or
+// resource template named by refType and refName. Any argument named
+// page_title is completed whatever the reference is, since tools cannot be
+// referenced by completion/complete; so is the title variable of the page
+// resource template. Other arguments get no values.
+func (c *Completer) Complete(ctx context.Context, refType, refName, argName, value string) (*Result, error) {
+	isTemplate := refType == RefResource && refName == resources.URITemplate
+	switch {
+	case argName == PageTitleArgument || (isTemplate && argName == "title"):
+		titles, err := c.titleList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return rank(titles, value), nil
+	case isTemplate && argName == "project":
+		return rank([]candidate{{title: c.src.ProjectName(), key: normalize(c.src.ProjectName())}}, value), nil
+	default:
+		return &Result{Values: []string{}}, nil
+	}
+}
+
+// titleList returns the cached title list, loading it again once it is
+// older than the TTL or the access rules changed. A list older than the TTL
+// is kept when loading fails, but not one listed under other rules.
+func (c *Completer) titleList(ctx context.Context) ([]candidate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	version := c.src.FilterVersion()
+	if version != c.version {
+		c.titles = nil
+	}
+	if c.titles != nil && c.now().Sub(c.loaded) < c.ttl {
+		return c.titles, nil
+	}
+	titles, err := c.load(ctx)
+	if err != nil {
+		if c.titles != nil {
+			return c.titles, nil
+		}
+		return nil, err
+	}
+	c.titles, c.loaded, c.version = titles, c.now(), version
+	return c.titles, nil
+}
+
+// load fetches the title list and the view and link counts of its pages.
+func (c *Completer) load(ctx context.Context) ([]candidate, error) {
+	titles, err := c.src.ListAllTitles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list titles: %w", err)
+	}
+	pages, err := c.src.ListAllPages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pages: %w", err)
+	}
+	stats := make(map[string]scrapbox.Page, len(pages))
+	for _, p := range pages {
+		stats[p.Title] = p
+	}
+	candidates := make([]candidate, 0, len(titles))
+	for _, t := range titles {
+		p := stats[t.Title]
+		candidates = append(candidates, candidate{title: t.Title, key: normalize(t.Title), views: p.Views, linked: p.Linked})
+	}
+	return candidates, nil
+}
+
+// rank returns the titles matching value, best matches first.
+func rank(titles []candidate, value string) *Result {
+	query := normalize(value)
+	type ranked struct {
+		candidate
+		kind int
+	}
+	var matches []ranked
+	for _, t := range titles {
+		if kind := matchKind(t.key, query); kind != noMatch {
+			matches = append(matches, ranked{t, kind})
+		}
+	}
+	slices.SortFunc(matches, func(a, b ranked) int {
+		return cmp.Or(
+			cmp.Compare(a.kind, b.kind),
+			cmp.Compare(b.views, a.views),
+			cmp.Compare(b.linked, a.linked),
+			cmp.Compare(len(a.title), len(b.title)),
+			strings.Compare(a.title, b.title),
+		)
+	})
+
+	result := &Result{Values: []string{}, Total: len(matches), HasMore: len(matches) > MaxValues}
+	for _, m := range matches[:min(len(matches), MaxValues)] {
+		result.Values = append(result.Values, m.title)
+	}
+	return result
+}
+
+// matchKind reports how the normalized query matches the normalized key.
+func matchKind(key, query string) int {
+	switch {
+	case key == query:
+		return matchExact
+	case strings.HasPrefix(key, query):
+		return matchPrefix
+	case strings.Contains(key, query):
+		return matchSubstring
+	case isSubsequence(key, query):
+		return matchFuzzy
+	default:
+		return noMatch
+	}
+}
+
+// isSubsequence reports whether the runes of query appear in key in order.
+func isSubsequence(key, query string) bool {
+	q := []rune(query)
+	for _, r := range key {
+		if len(q) == 0 {
+			break
+		}
+		if r == q[0] {
+			q = q[1:]
+		}
+	}
+	return len(q) == 0
+}
+
+// normalize folds s for matching: full-width and other compatibility forms
+// are replaced by their NFKC equivalents, letters are lowercased, katakana
+// become hiragana, and runs of underscores and spaces become a single space.
+func normalize(s string) string {
+	var b strings.Builder
+	inSpace := false
+	for _, r := range strings.ToLower(norm.NFKC.String(s)) {
+		if r == '_' || unicode.IsSpace(r) {
+			if !inSpace {
+				b.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		if r >= 'ァ' && r <= 'ヶ' {
+			r -= 'ァ' - 'ぁ'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
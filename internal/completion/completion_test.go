@@ -0,0 +1,235 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/acl"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+type fakeSource struct {
+	pages  []scrapbox.Page
+	policy *acl.Policy
+	loads  int
+	err    error
+}
+
+func (f *fakeSource) ProjectName() string { return "proj" }
+
+func (f *fakeSource) FilterVersion() uint64 {
+	if f.policy == nil {
+		return 0
+	}
+	return f.policy.Version()
+}
+
+func (f *fakeSource) ListAllTitles(ctx context.Context) ([]scrapbox.PageTitle, error) {
+	f.loads++
+	if f.err != nil {
+		return nil, f.err
+	}
+	var titles []scrapbox.PageTitle
+	for _, p := range f.pages {
+		if f.policy == nil || !f.policy.Denies(p.Title, nil) {
+			titles = append(titles, scrapbox.PageTitle{ID: p.ID, Title: p.Title})
+		}
+	}
+	return titles, nil
+}
+
+func (f *fakeSource) ListAllPages(ctx context.Context) ([]scrapbox.Page, error) {
+	return f.pages, nil
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"lowercases":                 {in: "Go Lang", want: "go lang"},
+		"underscore is a space":      {in: "foo_bar", want: "foo bar"},
+		"collapses spaces":           {in: "foo _  bar", want: "foo bar"},
+		"full-width forms":           {in: "ＡＢＣ　１２３", want: "abc 123"},
+		"katakana becomes hiragana":  {in: "スクラップボックス", want: "すくらっぷぼっくす"},
+		"half-width katakana":        {in: "ｽｸﾗｯﾌﾟ", want: "すくらっぷ"},
+		"kanji is kept":              {in: "議事録", want: "議事録"},
+		"trailing underscore stays":  {in: "foo_", want: "foo "},
+		"hiragana is kept unchanged": {in: "めも", want: "めも"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := normalize(tt.in); got != tt.want {
+				t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleter_Complete(t *testing.T) {
+	src := &fakeSource{pages: []scrapbox.Page{
+		{Title: "Design doc", Views: 10, Linked: 1},
+		{Title: "design_review", Views: 50},
+		{Title: "Old design", Views: 100},
+		{Title: "Deploy guide", Views: 5},
+		{Title: "Design", Views: 1},
+		{Title: "スクラップボックス", Views: 3},
+		{Title: "議事録 2024-01-01", Views: 2, Linked: 5},
+		{Title: "議事録 2024-01-08", Views: 2, Linked: 7},
+	}}
+	c := New(src, time.Minute)
+
+	tests := map[string]struct {
+		refType string
+		refName string
+		arg     string
+		value   string
+		want    []string
+	}{
+		"exact match before prefixes by views": {
+			refType: RefPrompt, refName: "summarize_page", arg: PageTitleArgument, value: "design",
+			want: []string{"Design", "design_review", "Design doc", "Old design"},
+		},
+		"underscore matches space": {
+			refType: RefPrompt, refName: "summarize_page", arg: PageTitleArgument, value: "design_d",
+			want: []string{"Design doc"},
+		},
+		"space matches underscore": {
+			refType: RefPrompt, refName: "summarize_page", arg: PageTitleArgument, value: "design r",
+			want: []string{"design_review"},
+		},
+		"fuzzy match": {
+			refType: RefPrompt, refName: "summarize_page", arg: PageTitleArgument, value: "dply",
+			want: []string{"Deploy guide"},
+		},
+		"hiragana matches katakana": {
+			refType: RefPrompt, refName: "summarize_page", arg: PageTitleArgument, value: "すくら",
+			want: []string{"スクラップボックス"},
+		},
+		"ties ranked by linked": {
+			refType: RefPrompt, refName: "summarize_page", arg: PageTitleArgument, value: "議事録",
+			want: []string{"議事録 2024-01-08", "議事録 2024-01-01"},
+		},
+		"resource template title": {
+			refType: RefResource, refName: resources.URITemplate, arg: "title", value: "deploy",
+			want: []string{"Deploy guide"},
+		},
+		"resource template project": {
+			refType: RefResource, refName: resources.URITemplate, arg: "project", value: "pr",
+			want: []string{"proj"},
+		},
+		"page_title of a tool": {
+			refType: "ref/tool", refName: "get_page_history", arg: PageTitleArgument, value: "old",
+			want: []string{"Old design"},
+		},
+		"other argument": {
+			refType: RefPrompt, refName: "answer_from_project", arg: "question", value: "design",
+			want: []string{},
+		},
+		"no match": {
+			refType: RefPrompt, refName: "summarize_page", arg: PageTitleArgument, value: "zzz",
+			want: []string{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := c.Complete(context.Background(), tt.refType, tt.refName, tt.arg, tt.value)
+			if err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got.Values); diff != "" {
+				t.Errorf("Complete() values mismatch (-want +got):\n%s", diff)
+			}
+			if got.Total != len(tt.want) || got.HasMore {
+				t.Errorf("Complete() total = %d hasMore = %v, want %d false", got.Total, got.HasMore, len(tt.want))
+			}
+		})
+	}
+	if src.loads != 1 {
+		t.Errorf("title list loaded %d times, want 1", src.loads)
+	}
+}
+
+func TestCompleter_CompleteLimit(t *testing.T) {
+	src := &fakeSource{}
+	for i := range MaxValues + 20 {
+		src.pages = append(src.pages, scrapbox.Page{Title: fmt.Sprintf("Page %03d", i), Views: i})
+	}
+	got, err := New(src, time.Minute).Complete(context.Background(), RefPrompt, "summarize_page", PageTitleArgument, "")
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if len(got.Values) != MaxValues || got.Total != MaxValues+20 || !got.HasMore {
+		t.Errorf("Complete() = %d values total %d hasMore %v, want %d %d true", len(got.Values), got.Total, got.HasMore, MaxValues, MaxValues+20)
+	}
+	if got.Values[0] != fmt.Sprintf("Page %03d", MaxValues+19) {
+		t.Errorf("Complete() first value = %q, want the most viewed page", got.Values[0])
+	}
+}
+
+func TestCompleter_Cache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	src := &fakeSource{pages: []scrapbox.Page{{Title: "Design"}}}
+	c := New(src, time.Minute)
+	c.now = func() time.Time { return now }
+	complete := func() (*Result, error) {
+		return c.Complete(context.Background(), RefPrompt, "summarize_page", PageTitleArgument, "des")
+	}
+
+	if _, err := complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	now = now.Add(30 * time.Second)
+	if _, err := complete(); err != nil || src.loads != 1 {
+		t.Fatalf("Complete() within TTL: error = %v loads = %d, want nil 1", err, src.loads)
+	}
+
+	now = now.Add(time.Minute)
+	src.err = errors.New("unavailable")
+	got, err := complete()
+	if err != nil {
+		t.Fatalf("Complete() with stale cache error = %v", err)
+	}
+	if src.loads != 2 || len(got.Values) != 1 {
+		t.Errorf("Complete() with stale cache: loads = %d values = %v, want 2 [Design]", src.loads, got.Values)
+	}
+
+	if _, err := New(src, time.Minute).Complete(context.Background(), RefPrompt, "summarize_page", PageTitleArgument, "des"); err == nil {
+		t.Error("Complete() without cache error = nil, want error")
+	}
+}
+
+func TestCompleter_RulesChange(t *testing.T) {
+	policy, err := acl.New(acl.Rules{Titles: []string{"Salaries"}})
+	if err != nil {
+		t.Fatalf("acl.New() error = %v", err)
+	}
+	src := &fakeSource{pages: []scrapbox.Page{{Title: "Salaries"}, {Title: "Sales"}}, policy: policy}
+	c := New(src, time.Hour)
+	complete := func() []string {
+		t.Helper()
+		got, err := c.Complete(context.Background(), RefPrompt, "summarize_page", PageTitleArgument, "sal")
+		if err != nil {
+			t.Fatalf("Complete() error = %v", err)
+		}
+		return got.Values
+	}
+
+	if diff := cmp.Diff([]string{"Sales"}, complete()); diff != "" {
+		t.Errorf("Complete() mismatch (-want +got):\n%s", diff)
+	}
+	if err := policy.Set(acl.Rules{Titles: []string{"Sales"}}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"Salaries"}, complete()); diff != "" {
+		t.Errorf("Complete() after the rules changed mismatch (-want +got):\n%s", diff)
+	}
+	if src.loads != 2 {
+		t.Errorf("loads = %d, want 2", src.loads)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUsage is returned by Command for unknown subcommands.
+var ErrUsage = errors.New("usage: config check [flags]")
+
+// Command runs the config subcommand of the servers with args: "check"
+// followed by the flags of Load runs Check.
+func Command(args []string, w io.Writer) error {
+	if len(args) == 0 || args[0] != "check" {
+		return ErrUsage
+	}
+	return Check(args[1:], w)
+}
+
+// Check loads the configuration as Load does with the flags in args, and
+// writes the config file used and every setting with its origin to w, with
+// secrets masked. Unlike the servers it does not access Scrapbox.
+func Check(args []string, w io.Writer) error {
+	cfg, src, err := load(nil, args)
+	if err != nil {
+		return err
+	}
+	switch {
+	case cfg.ConfigFile == "":
+		fmt.Fprintln(w, "Config file: none")
+	case cfg.Profile != "":
+		fmt.Fprintf(w, "Config file: %s (profile %s)\n", cfg.ConfigFile, cfg.Profile)
+	default:
+		fmt.Fprintf(w, "Config file: %s\n", cfg.ConfigFile)
+	}
+	for _, s := range settings {
+		v, where := src.lookup(s.env)
+		if v == "" {
+			continue
+		}
+		switch {
+		case s.secret:
+			v = "(hidden)"
+		case s.kind == kindPatterns:
+			v = strings.ReplaceAll(v, "\n", "; ")
+		}
+		fmt.Fprintf(w, "  %s = %s (from %s)\n", s.key, v, where)
+	}
+	for _, p := range cfg.Projects {
+		switch {
+		case !p.SIDSource.IsZero():
+			fmt.Fprintf(w, "  project %s: SID from %s\n", p.Name, p.SIDSource)
+		case p.SID != "":
+			fmt.Fprintf(w, "  project %s: SID set\n", p.Name)
+		default:
+			fmt.Fprintf(w, "  project %s: anonymous\n", p.Name)
+		}
+		for _, key := range projectToolSettings {
+			if v, where := src.lookup(settingsByKey[key].env + projectSuffix(p.Name)); v != "" {
+				fmt.Fprintf(w, "  project %s: %s = %s (from %s)\n", p.Name, key, v, where)
+			}
+		}
+	}
+	fmt.Fprintln(w, "Configuration is valid.")
+	return nil
+}
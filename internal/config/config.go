@@ -1,50 +1,452 @@
 package config
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
-	"github.com/takak2166/scrapbox-mcp/internal/errors"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+)
+
+// Transports selectable with MCP_TRANSPORT.
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
 )
 
 type Config struct {
+	// ConfigFile is the config file loaded, if any, and Profile the profile
+	// of it applied.
+	ConfigFile string
+	Profile    string
+	// ScrapboxSID is the session cookie of ProjectName, or empty to access it
+	// anonymously.
 	ScrapboxSID string
+	// SIDSource is where ScrapboxSID was read from, if not from SCRAPBOX_SID.
+	SIDSource   CredentialSource
 	ProjectName string
 	Port        int
+	// WatchInterval is the poll interval for resource subscriptions; zero
+	// selects the default.
+	WatchInterval time.Duration
+	// WatchMaxBackoff caps the poll delay after failed polls; zero selects
+	// the default.
+	WatchMaxBackoff time.Duration
+	// CompletionTTL is how long the title list used for completion is
+	// cached; zero selects the default.
+	CompletionTTL time.Duration
+	// DenyTitles, DenyTitlePattern and DenyTags are the rules hiding pages
+	// from clients: the titles of hidden pages, a regular expression
+	// matching them and the tags whose pages are hidden.
+	DenyTitles       []string
+	DenyTitlePattern string
+	DenyTags         []string
+	// Redaction selects the detectors redacting secrets and personal data
+	// from the outputs of the server.
+	Redaction redact.Rules
+	// LogLevel is the level of the records logged to stderr, and LogFormat
+	// logging.FormatText or logging.FormatJSON.
+	LogLevel  slog.Level
+	LogFormat string
+	// Tools selects the tools enabled, and ProjectTools those enabled in the
+	// additional projects configured with a policy of their own.
+	Tools        tools.Policy
+	ProjectTools map[string]tools.Policy
+	// Transport is TransportStdio or TransportHTTP. In HTTP mode the server
+	// listens on Port.
+	Transport string
+	// MaxBodyBytes limits HTTP request bodies; zero selects the default.
+	MaxBodyBytes int64
+	// AuthTokens are static bearer tokens allowed to call every tool in HTTP
+	// mode, and AuthReadOnlyTokens tokens allowed to call read tools only.
+	AuthTokens         []string
+	AuthReadOnlyTokens []string
+	// AuthJWKSFile is a JWKS file verifying OAuth access tokens issued for
+	// AuthAudience, by AuthIssuer if set.
+	AuthJWKSFile string
+	AuthAudience string
+	AuthIssuer   string
+	// TenantMapFile maps authenticated callers to their Scrapbox
+	// credentials in multi-tenant mode.
+	TenantMapFile string
+	// TenantSIDHeader and TenantProjectHeader name the headers carrying the
+	// Scrapbox SID and project of the caller, set by a trusted proxy in
+	// multi-tenant mode.
+	TenantSIDHeader     string
+	TenantProjectHeader string
+	// Projects are the projects served next to ProjectName, the primary
+	// project, each with its own SID, which may be empty like ScrapboxSID.
+	Projects []Project
+}
+
+// Project is an additional Scrapbox project and the SID used to access it.
+type Project struct {
+	Name      string
+	SID       string
+	SIDSource CredentialSource
+}
+
+// MultiTenant reports whether the Scrapbox credentials are resolved per
+// session instead of using ScrapboxSID.
+func (c *Config) MultiTenant() bool {
+	return c.TenantMapFile != "" || c.TenantSIDHeader != ""
 }
 
+// AuthEnabled reports whether HTTP mode requires access tokens.
+func (c *Config) AuthEnabled() bool {
+	return len(c.AuthTokens) > 0 || len(c.AuthReadOnlyTokens) > 0 || c.AuthJWKSFile != ""
+}
+
+// LoadConfig loads the configuration from the config file and the
+// environment, as Load does without command-line flags.
 func LoadConfig() (*Config, error) {
+	return Load(nil)
+}
+
+// Load loads the configuration from the command-line flags in args, the
+// environment, including a .env file in the current directory, and the
+// config file, in decreasing order of precedence. The config file is the one
+// named by -config or SCRAPBOX_MCP_CONFIG, or else
+// $XDG_CONFIG_HOME/scrapbox-mcp/config.yaml, .yml or .toml if it exists; the
+// profile named by -profile, SCRAPBOX_MCP_PROFILE or its "profile" key
+// overrides its top-level settings.
+//
+// Every problem found is reported at once by the returned *ValidationError.
+func Load(args []string) (*Config, error) {
+	cfg, _, err := load(nil, args)
+	return cfg, err
+}
+
+// LoadFlags is Load for commands with flags of their own: it adds the flags
+// of Load to fs and parses args with it, leaving the arguments after the
+// flags in fs.Args().
+func LoadFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg, _, err := load(fs, args)
+	return cfg, err
+}
+
+// load is Load, or LoadFlags if fs is not nil, also returning where the
+// settings were looked up.
+func load(fs *flag.FlagSet, args []string) (*Config, sources, error) {
 	// Load .env file if exists
-	err := godotenv.Load()
-	if err != nil {
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
 		log.Printf("Failed to load .env file: %v", err)
 	}
 
-	sid := os.Getenv("SCRAPBOX_SID")
-	if sid == "" {
-		return nil, &errors.ScrapboxError{Code: errors.ErrInvalidCredentials, Message: "SCRAPBOX_SID is not set", Err: nil}
+	commandFlags := fs != nil
+	if !commandFlags {
+		fs = flag.NewFlagSet("scrapbox-mcp", flag.ContinueOnError)
+	}
+	configPath := fs.String("config", "", "config file (default: $XDG_CONFIG_HOME/scrapbox-mcp/config.yaml)")
+	profile := fs.String("profile", "", "profile of the config file to use")
+	flagEnv := map[string]string{}
+	for _, s := range settings {
+		if !s.secret {
+			fs.String(flagName(s), "", s.usage)
+			flagEnv[flagName(s)] = s.env
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if !commandFlags && fs.NArg() > 0 {
+		return nil, nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	flags := layer{values: map[string]string{}, where: func(s setting) string { return "-" + flagName(s) }}
+	fs.Visit(func(f *flag.Flag) {
+		if env, ok := flagEnv[f.Name]; ok {
+			flags.values[env] = f.Value.String()
+		}
+	})
+
+	var p problems
+	src := sources{flags, envLayer}
+	path, err := findConfigFile(*configPath)
+	if err != nil {
+		p.add("config file: %v", err)
+	}
+	if *profile == "" {
+		*profile = os.Getenv("SCRAPBOX_MCP_PROFILE")
+	}
+	var file *configFile
+	if path != "" {
+		file = readConfigFile(path, *profile, &p)
+		src = append(src, file.layer())
+	} else if *profile != "" {
+		p.add("profile %s selected without a config file", *profile)
+	}
+
+	cfg := parse(src, &p)
+	if file != nil {
+		cfg.ConfigFile, cfg.Profile = file.path, file.profile
+		for _, name := range slices.Sorted(maps.Keys(file.toolProjects)) {
+			if !slices.ContainsFunc(cfg.Projects, func(pr Project) bool { return pr.Name == name }) {
+				p.add("%s configures %s, which is not listed in SCRAPBOX_PROJECTS", file.toolProjects[name], name)
+			}
+		}
 	}
+	if len(p) > 0 {
+		return nil, src, &ValidationError{Problems: p}
+	}
+	return cfg, src, nil
+}
+
+// parse parses and validates the settings looked up in src, adding every
+// problem found to p.
+func parse(src sources, p *problems) *Config {
+	// SCRAPBOX_SID is optional: public projects can be read anonymously, and
+	// in multi-tenant mode every session brings its own SID. It may also be
+	// read from a file, a command or a cookies.txt export.
+	sid, sidSource := loadSID(src, "SCRAPBOX_SID", "SCRAPBOX_COOKIES_FILE", p)
 
-	project := os.Getenv("SCRAPBOX_PROJECT")
+	project := src.get("SCRAPBOX_PROJECT")
 	if project == "" {
-		return nil, &errors.ScrapboxError{Code: errors.ErrInvalidCredentials, Message: "SCRAPBOX_PROJECT is not set", Err: nil}
+		p.add("SCRAPBOX_PROJECT is not set; set it, -project or project in the config file")
 	}
 
 	// Default port is 8080
 	port := 8080
-	if portStr := os.Getenv("PORT"); portStr != "" {
+	if v, where := src.lookup("PORT"); v != "" {
+		var err error
+		if port, err = strconv.Atoi(v); err != nil {
+			p.add("%s must be a valid number", where)
+		}
+	}
+
+	watchInterval := parseDuration(src, "SCRAPBOX_WATCH_INTERVAL", p)
+	watchMaxBackoff := parseDuration(src, "SCRAPBOX_WATCH_MAX_BACKOFF", p)
+	completionTTL := parseDuration(src, "SCRAPBOX_COMPLETION_TTL", p)
+
+	denyTitlePattern, where := src.lookup("SCRAPBOX_DENY_TITLE_PATTERN")
+	if _, err := regexp.Compile(denyTitlePattern); err != nil {
+		p.add("%s must be a valid regular expression: %v", where, err)
+	}
+
+	redaction := parseRedaction(src, p)
+
+	var logLevel slog.Level
+	if v, where := src.lookup("MCP_LOG_LEVEL"); v != "" {
+		switch strings.ToLower(v) {
+		case "debug", "info", "warn", "error":
+			_ = logLevel.UnmarshalText([]byte(v))
+		default:
+			p.add("%s must be debug, info, warn or error", where)
+		}
+	}
+	logFormat, where := src.lookup("MCP_LOG_FORMAT")
+	switch logFormat {
+	case "":
+		logFormat = logging.FormatText
+	case logging.FormatText, logging.FormatJSON:
+	default:
+		p.add("%s must be text or json", where)
+	}
+
+	toolPolicy, _ := parseToolPolicy(src, "", tools.Policy{}, p)
+
+	transport, where := src.lookup("MCP_TRANSPORT")
+	switch transport {
+	case "":
+		transport = TransportStdio
+	case TransportStdio, TransportHTTP:
+	default:
+		p.add("%s must be stdio or http", where)
+	}
+
+	var maxBodyBytes int64
+	if v, where := src.lookup("MCP_MAX_BODY_BYTES"); v != "" {
 		var err error
-		port, err = strconv.Atoi(portStr)
+		if maxBodyBytes, err = strconv.ParseInt(v, 10, 64); err != nil || maxBodyBytes < 0 {
+			p.add("%s must be a non-negative number", where)
+		}
+	}
+
+	// Additional projects use SCRAPBOX_SID_<NAME> or its _FILE and _COMMAND
+	// variants, or else the SID of the primary project. Names whose key
+	// would be one of those of the primary project always use the latter.
+	var projects []Project
+	var projectTools map[string]tools.Policy
+	seen := map[string]bool{project: true}
+	for _, name := range src.list("SCRAPBOX_PROJECTS") {
+		if seen[name] {
+			_, where := src.lookup("SCRAPBOX_PROJECTS")
+			p.add("%s lists %s twice", where, name)
+			continue
+		}
+		seen[name] = true
+		pr := Project{Name: name, SID: sid, SIDSource: sidSource}
+		if key := ProjectSIDKey(name); key != "SCRAPBOX_SID_FILE" && key != "SCRAPBOX_SID_COMMAND" {
+			if projectSID, projectSource := loadSID(sources{envLayer}, key, "", p); projectSID != "" {
+				pr.SID, pr.SIDSource = projectSID, projectSource
+			}
+		}
+		projects = append(projects, pr)
+		if policy, ok := parseToolPolicy(src, projectSuffix(name), toolPolicy, p); ok {
+			if projectTools == nil {
+				projectTools = map[string]tools.Policy{}
+			}
+			projectTools[name] = policy
+		}
+	}
+
+	jwksFile := src.get("MCP_AUTH_JWKS_FILE")
+	audience := src.get("MCP_AUTH_AUDIENCE")
+	if jwksFile != "" && audience == "" {
+		p.add("MCP_AUTH_AUDIENCE must be set with MCP_AUTH_JWKS_FILE")
+	}
+
+	cfg := &Config{
+		ScrapboxSID:     sid,
+		SIDSource:       sidSource,
+		ProjectName:     project,
+		Port:            port,
+		WatchInterval:   watchInterval,
+		WatchMaxBackoff: watchMaxBackoff,
+		CompletionTTL:   completionTTL,
+
+		DenyTitles:       src.list("SCRAPBOX_DENY_TITLES"),
+		DenyTitlePattern: denyTitlePattern,
+		DenyTags:         src.list("SCRAPBOX_DENY_TAGS"),
+		Redaction:        redaction,
+		LogLevel:         logLevel,
+		LogFormat:        logFormat,
+
+		Tools:        toolPolicy,
+		ProjectTools: projectTools,
+		Transport:    transport,
+		MaxBodyBytes: maxBodyBytes,
+
+		AuthTokens:         src.list("MCP_AUTH_TOKENS"),
+		AuthReadOnlyTokens: src.list("MCP_AUTH_READ_ONLY_TOKENS"),
+		AuthJWKSFile:       jwksFile,
+		AuthAudience:       audience,
+		AuthIssuer:         src.get("MCP_AUTH_ISSUER"),
+
+		TenantMapFile:       src.get("MCP_TENANT_MAP_FILE"),
+		TenantSIDHeader:     src.get("MCP_TENANT_SID_HEADER"),
+		TenantProjectHeader: src.get("MCP_TENANT_PROJECT_HEADER"),
+
+		Projects: projects,
+	}
+	if cfg.MultiTenant() && cfg.Transport != TransportHTTP {
+		p.add("multi-tenant mode requires MCP_TRANSPORT=http")
+	}
+	if cfg.TenantMapFile != "" && !cfg.AuthEnabled() {
+		p.add("MCP_TENANT_MAP_FILE requires MCP_AUTH_TOKENS, MCP_AUTH_READ_ONLY_TOKENS or MCP_AUTH_JWKS_FILE")
+	}
+	return cfg
+}
+
+// ProjectSIDKey returns the environment variable holding the SID of an
+// additional project: SCRAPBOX_SID followed by the suffix of the project.
+func ProjectSIDKey(project string) string {
+	return "SCRAPBOX_SID" + projectSuffix(project)
+}
+
+// projectSuffix returns the suffix of the environment variables of an
+// additional project: "_" followed by the project name in upper case with
+// every character other than a letter or digit replaced by "_".
+func projectSuffix(project string) string {
+	return "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, project)
+}
+
+// parseToolPolicy parses the tool policy set by MCP_READ_ONLY,
+// MCP_ENABLED_TOOLS and MCP_DISABLED_TOOLS with suffix over policy, and
+// reports whether any of them is set.
+func parseToolPolicy(src sources, suffix string, policy tools.Policy, p *problems) (tools.Policy, bool) {
+	set := false
+	if v, where := src.lookup("MCP_READ_ONLY" + suffix); v != "" {
+		readOnly, err := strconv.ParseBool(v)
 		if err != nil {
-			return nil, &errors.ScrapboxError{Code: errors.ErrInvalidCredentials, Message: "PORT must be a valid number", Err: err}
+			p.add("%s must be true or false", where)
+		}
+		policy.ReadOnly, set = readOnly, true
+	}
+	if list := parseToolList(src, "MCP_ENABLED_TOOLS"+suffix, p); list != nil {
+		policy.Allow, set = list, true
+	}
+	if list := parseToolList(src, "MCP_DISABLED_TOOLS"+suffix, p); list != nil {
+		policy.Deny, set = list, true
+	}
+	return policy, set
+}
+
+// parseToolList parses a list of tool names.
+func parseToolList(src sources, key string, p *problems) []string {
+	list := src.list(key)
+	for _, name := range list {
+		if !tools.Known(name) {
+			_, where := src.lookup(key)
+			p.add("%s lists unknown tool %s; known tools are %s", where, name, strings.Join(tools.Names, ", "))
 		}
 	}
+	return list
+}
 
-	return &Config{
-		ScrapboxSID: sid,
-		ProjectName: project,
-		Port:        port,
-	}, nil
+// parseRedaction parses the redaction rules set by MCP_REDACT_DETECTORS,
+// where "none" disables the built-in detectors, and MCP_REDACT_PATTERNS.
+func parseRedaction(src sources, p *problems) redact.Rules {
+	var rules redact.Rules
+	if v, where := src.lookup("MCP_REDACT_DETECTORS"); v != "" {
+		rules.Detectors = []string{}
+		for _, name := range src.list("MCP_REDACT_DETECTORS") {
+			switch {
+			case name == "none":
+			case !redact.Known(name):
+				p.add("%s lists unknown detector %s; known detectors are %s", where, name, strings.Join(redact.Detectors, ", "))
+			default:
+				rules.Detectors = append(rules.Detectors, name)
+			}
+		}
+	}
+	v, where := src.lookup("MCP_REDACT_PATTERNS")
+	for _, line := range strings.Split(v, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, expr, ok := strings.Cut(line, "=")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			p.add("%s must list patterns as name=regexp, one per line", where)
+			continue
+		}
+		if _, err := regexp.Compile(expr); err != nil {
+			p.add("%s: pattern %s must be a valid regular expression: %v", where, name, err)
+			continue
+		}
+		rules.Patterns = append(rules.Patterns, redact.Pattern{Name: name, Regexp: expr})
+	}
+	return rules
+}
+
+// parseDuration parses an optional duration such as "30s".
+func parseDuration(src sources, key string, p *problems) time.Duration {
+	v, where := src.lookup(key)
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		p.add("%s must be a non-negative duration such as 30s", where)
+		return 0
+	}
+	return d
 }
@@ -1,13 +1,32 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
 )
 
 func TestLoadConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	sidFile := filepath.Join(t.TempDir(), "sid")
+	if err := os.WriteFile(sidFile, []byte("file_sid\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cookiesFile := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(cookiesFile, []byte("#HttpOnly_scrapbox.io\tFALSE\t/\tTRUE\t0\tconnect.sid\tcookie_sid\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
 	tests := map[string][]struct {
 		env     map[string]string
 		want    *Config
@@ -23,6 +42,8 @@ func TestLoadConfig(t *testing.T) {
 					ScrapboxSID: "test_sid",
 					ProjectName: "test_project",
 					Port:        8080,
+					Transport:   TransportStdio,
+					LogFormat:   logging.FormatText,
 				},
 				wantErr: false,
 			},
@@ -38,10 +59,289 @@ func TestLoadConfig(t *testing.T) {
 					ScrapboxSID: "test_sid",
 					ProjectName: "test_project",
 					Port:        3000,
+					Transport:   TransportStdio,
+					LogFormat:   logging.FormatText,
+				},
+				wantErr: false,
+			},
+		},
+		"ok: watch intervals": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":               "test_sid",
+					"SCRAPBOX_PROJECT":           "test_project",
+					"SCRAPBOX_WATCH_INTERVAL":    "10s",
+					"SCRAPBOX_WATCH_MAX_BACKOFF": "2m",
+				},
+				want: &Config{
+					ScrapboxSID:     "test_sid",
+					ProjectName:     "test_project",
+					Port:            8080,
+					WatchInterval:   10 * time.Second,
+					WatchMaxBackoff: 2 * time.Minute,
+					Transport:       TransportStdio,
+					LogFormat:       logging.FormatText,
+				},
+				wantErr: false,
+			},
+		},
+		"ok: http transport": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":       "test_sid",
+					"SCRAPBOX_PROJECT":   "test_project",
+					"MCP_TRANSPORT":      "http",
+					"MCP_MAX_BODY_BYTES": "4096",
+				},
+				want: &Config{
+					ScrapboxSID:  "test_sid",
+					ProjectName:  "test_project",
+					Port:         8080,
+					Transport:    TransportHTTP,
+					LogFormat:    logging.FormatText,
+					MaxBodyBytes: 4096,
+				},
+				wantErr: false,
+			},
+		},
+		"ok: auth": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":              "test_sid",
+					"SCRAPBOX_PROJECT":          "test_project",
+					"MCP_AUTH_TOKENS":           "token1, token2,",
+					"MCP_AUTH_READ_ONLY_TOKENS": "token3",
+					"MCP_AUTH_JWKS_FILE":        "/etc/jwks.json",
+					"MCP_AUTH_AUDIENCE":         "https://mcp.example.com/mcp",
+					"MCP_AUTH_ISSUER":           "https://auth.example.com",
+				},
+				want: &Config{
+					ScrapboxSID:        "test_sid",
+					ProjectName:        "test_project",
+					Port:               8080,
+					Transport:          TransportStdio,
+					LogFormat:          logging.FormatText,
+					AuthTokens:         []string{"token1", "token2"},
+					AuthReadOnlyTokens: []string{"token3"},
+					AuthJWKSFile:       "/etc/jwks.json",
+					AuthAudience:       "https://mcp.example.com/mcp",
+					AuthIssuer:         "https://auth.example.com",
 				},
 				wantErr: false,
 			},
 		},
+		"ok: multi-tenant without SCRAPBOX_SID": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_PROJECT":          "test_project",
+					"MCP_TRANSPORT":             "http",
+					"MCP_AUTH_TOKENS":           "token1",
+					"MCP_TENANT_MAP_FILE":       "/etc/tenants.json",
+					"MCP_TENANT_SID_HEADER":     "X-Scrapbox-Sid",
+					"MCP_TENANT_PROJECT_HEADER": "X-Scrapbox-Project",
+				},
+				want: &Config{
+					ProjectName:         "test_project",
+					Port:                8080,
+					Transport:           TransportHTTP,
+					LogFormat:           logging.FormatText,
+					AuthTokens:          []string{"token1"},
+					TenantMapFile:       "/etc/tenants.json",
+					TenantSIDHeader:     "X-Scrapbox-Sid",
+					TenantProjectHeader: "X-Scrapbox-Project",
+				},
+				wantErr: false,
+			},
+		},
+		"ok: anonymous without SCRAPBOX_SID": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_PROJECT":  "test_project",
+					"SCRAPBOX_PROJECTS": "team-wiki",
+				},
+				want: &Config{
+					ProjectName: "test_project",
+					Port:        8080,
+					Transport:   TransportStdio,
+					LogFormat:   logging.FormatText,
+					Projects:    []Project{{Name: "team-wiki"}},
+				},
+				wantErr: false,
+			},
+		},
+		"ok: additional projects": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":           "test_sid",
+					"SCRAPBOX_PROJECT":       "test_project",
+					"SCRAPBOX_PROJECTS":      "team-wiki, other.project",
+					"SCRAPBOX_SID_TEAM_WIKI": "team_sid",
+				},
+				want: &Config{
+					ScrapboxSID: "test_sid",
+					ProjectName: "test_project",
+					Port:        8080,
+					Transport:   TransportStdio,
+					LogFormat:   logging.FormatText,
+					Projects: []Project{
+						{Name: "team-wiki", SID: "team_sid"},
+						{Name: "other.project", SID: "test_sid"},
+					},
+				},
+				wantErr: false,
+			},
+		},
+		"ok: SID from a file": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID_FILE": sidFile,
+					"SCRAPBOX_PROJECT":  "test_project",
+					"SCRAPBOX_PROJECTS": "team-wiki",
+				},
+				want: &Config{
+					ScrapboxSID: "file_sid",
+					SIDSource:   CredentialSource{File: sidFile},
+					ProjectName: "test_project",
+					Port:        8080,
+					Transport:   TransportStdio,
+					LogFormat:   logging.FormatText,
+					Projects:    []Project{{Name: "team-wiki", SID: "file_sid", SIDSource: CredentialSource{File: sidFile}}},
+				},
+				wantErr: false,
+			},
+		},
+		"ok: SID from a command and a cookies.txt export": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID_COMMAND":           "echo command_sid",
+					"SCRAPBOX_PROJECT":               "test_project",
+					"SCRAPBOX_PROJECTS":              "team-wiki",
+					"SCRAPBOX_SID_TEAM_WIKI_COMMAND": "echo team_sid",
+				},
+				want: &Config{
+					ScrapboxSID: "command_sid",
+					SIDSource:   CredentialSource{Command: "echo command_sid"},
+					ProjectName: "test_project",
+					Port:        8080,
+					Transport:   TransportStdio,
+					LogFormat:   logging.FormatText,
+					Projects:    []Project{{Name: "team-wiki", SID: "team_sid", SIDSource: CredentialSource{Command: "echo team_sid"}}},
+				},
+				wantErr: false,
+			},
+			{
+				env: map[string]string{
+					"SCRAPBOX_COOKIES_FILE": cookiesFile,
+					"SCRAPBOX_PROJECT":      "test_project",
+				},
+				want: &Config{
+					ScrapboxSID: "cookie_sid",
+					SIDSource:   CredentialSource{CookiesFile: cookiesFile},
+					ProjectName: "test_project",
+					Port:        8080,
+					Transport:   TransportStdio,
+					LogFormat:   logging.FormatText,
+				},
+				wantErr: false,
+			},
+		},
+		"err: SCRAPBOX_SID with SCRAPBOX_SID_FILE": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":      "test_sid",
+					"SCRAPBOX_SID_FILE": sidFile,
+					"SCRAPBOX_PROJECT":  "test_project",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
+		"err: failing SCRAPBOX_SID_COMMAND": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID_COMMAND": "exit 1",
+					"SCRAPBOX_PROJECT":     "test_project",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
+		"err: duplicate project": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":      "test_sid",
+					"SCRAPBOX_PROJECT":  "test_project",
+					"SCRAPBOX_PROJECTS": "team-wiki,test_project",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
+		"err: multi-tenant over stdio": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_PROJECT":      "test_project",
+					"MCP_TENANT_SID_HEADER": "X-Scrapbox-Sid",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
+		"err: MCP_TENANT_MAP_FILE without authentication": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_PROJECT":    "test_project",
+					"MCP_TRANSPORT":       "http",
+					"MCP_TENANT_MAP_FILE": "/etc/tenants.json",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
+		"err: MCP_AUTH_JWKS_FILE without MCP_AUTH_AUDIENCE": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":       "test_sid",
+					"SCRAPBOX_PROJECT":   "test_project",
+					"MCP_AUTH_JWKS_FILE": "/etc/jwks.json",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
+		"err: unknown MCP_TRANSPORT": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":     "test_sid",
+					"SCRAPBOX_PROJECT": "test_project",
+					"MCP_TRANSPORT":    "websocket",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
+		"err: invalid MCP_MAX_BODY_BYTES": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":       "test_sid",
+					"SCRAPBOX_PROJECT":   "test_project",
+					"MCP_MAX_BODY_BYTES": "1MB",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
+		"err: invalid SCRAPBOX_WATCH_INTERVAL": {
+			{
+				env: map[string]string{
+					"SCRAPBOX_SID":            "test_sid",
+					"SCRAPBOX_PROJECT":        "test_project",
+					"SCRAPBOX_WATCH_INTERVAL": "often",
+				},
+				want:    nil,
+				wantErr: true,
+			},
+		},
 		"err: invalid PORT": {
 			{
 				env: map[string]string{
@@ -90,3 +390,304 @@ func TestLoadConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	yamlFile := write("scrapbox-mcp/config.yaml", `
+project: main
+port: 3000
+watch_interval: 10s
+profiles:
+  work:
+    project: company
+    projects: [team-wiki, notes]
+`)
+	tomlFile := write("config.toml", `
+project = "main"
+profile = "http"
+
+[profiles.http]
+transport = "http"
+max_body_bytes = 4096
+`)
+	toolsFile := write("tools.yaml", `
+project: main
+projects: [team-wiki, sandbox]
+read_only: true
+disabled_tools: [search_all]
+project_tools:
+  sandbox:
+    read_only: false
+  team-wiki:
+    enabled_tools: [get_page, list_pages]
+`)
+	invalidToolsFile := write("invalid-tools.yaml", `
+project: main
+read_only: "yes"
+enabled_tools: [get_page, edit_page]
+project_tools:
+  main:
+    read_only: false
+    disabled: [get_page]
+`)
+	aclFile := write("acl.yaml", `
+project: main
+deny_titles: [HR, Salaries]
+deny_title_pattern: ^incident/
+deny_tags: [confidential]
+`)
+	redactFile := write("redact.toml", `
+project = "main"
+redact_detectors = ["email", "jwt"]
+
+[redact_patterns]
+employee_id = 'EMP-\d{6}'
+`)
+	invalidFile := write("invalid.yaml", `
+project: main
+port: "3000"
+projekt: typo
+profiles:
+  work:
+    transport: carrier-pigeon
+`)
+
+	tests := map[string]struct {
+		args         []string
+		env          map[string]string
+		want         *Config
+		wantProblems []string
+	}{
+		"ok: XDG config file": {
+			want: &Config{
+				ConfigFile:    yamlFile,
+				ProjectName:   "main",
+				Port:          3000,
+				WatchInterval: 10 * time.Second,
+				Transport:     TransportStdio,
+				LogFormat:     logging.FormatText,
+			},
+		},
+		"ok: profile from flag": {
+			args: []string{"-profile", "work"},
+			want: &Config{
+				ConfigFile:    yamlFile,
+				Profile:       "work",
+				ProjectName:   "company",
+				Port:          3000,
+				WatchInterval: 10 * time.Second,
+				Transport:     TransportStdio,
+				LogFormat:     logging.FormatText,
+				Projects:      []Project{{Name: "team-wiki"}, {Name: "notes"}},
+			},
+		},
+		"ok: environment over file, flags over environment": {
+			args: []string{"-port", "5000"},
+			env:  map[string]string{"SCRAPBOX_PROJECT": "env_project", "PORT": "4000"},
+			want: &Config{
+				ConfigFile:    yamlFile,
+				ProjectName:   "env_project",
+				Port:          5000,
+				WatchInterval: 10 * time.Second,
+				Transport:     TransportStdio,
+				LogFormat:     logging.FormatText,
+			},
+		},
+		"ok: TOML file from SCRAPBOX_MCP_CONFIG with default profile": {
+			env: map[string]string{"SCRAPBOX_MCP_CONFIG": tomlFile},
+			want: &Config{
+				ConfigFile:   tomlFile,
+				Profile:      "http",
+				ProjectName:  "main",
+				Port:         8080,
+				Transport:    TransportHTTP,
+				LogFormat:    logging.FormatText,
+				MaxBodyBytes: 4096,
+			},
+		},
+		"ok: tool policies with project overrides": {
+			args: []string{"-config", toolsFile},
+			env:  map[string]string{"MCP_DISABLED_TOOLS_TEAM_WIKI": "list_pages"},
+			want: &Config{
+				ConfigFile:  toolsFile,
+				ProjectName: "main",
+				Port:        8080,
+				Transport:   TransportStdio,
+				LogFormat:   logging.FormatText,
+				Tools:       tools.Policy{ReadOnly: true, Deny: []string{"search_all"}},
+				ProjectTools: map[string]tools.Policy{
+					"sandbox":   {Deny: []string{"search_all"}},
+					"team-wiki": {ReadOnly: true, Allow: []string{"get_page", "list_pages"}, Deny: []string{"list_pages"}},
+				},
+				Projects: []Project{{Name: "team-wiki"}, {Name: "sandbox"}},
+			},
+		},
+		"err: invalid tool policies": {
+			args: []string{"-config", invalidToolsFile},
+			wantProblems: []string{
+				"unknown key project_tools.main.disabled in " + invalidToolsFile,
+				"read_only in " + invalidToolsFile + " must be true or false",
+				"enabled_tools in " + invalidToolsFile + " lists unknown tool edit_page; known tools are " + strings.Join(tools.Names, ", "),
+				"project_tools in " + invalidToolsFile + " configures main, which is not listed in SCRAPBOX_PROJECTS",
+			},
+		},
+		"ok: page access rules": {
+			args: []string{"-config", aclFile},
+			want: &Config{
+				ConfigFile:       aclFile,
+				ProjectName:      "main",
+				Port:             8080,
+				Transport:        TransportStdio,
+				LogFormat:        logging.FormatText,
+				DenyTitles:       []string{"HR", "Salaries"},
+				DenyTitlePattern: "^incident/",
+				DenyTags:         []string{"confidential"},
+			},
+		},
+		"err: invalid title pattern": {
+			args:         []string{"-deny-title-pattern", "incident/("},
+			wantProblems: []string{"-deny-title-pattern must be a valid regular expression: error parsing regexp: missing closing ): `incident/(`"},
+		},
+		"ok: redaction rules": {
+			args: []string{"-config", redactFile},
+			want: &Config{
+				ConfigFile:  redactFile,
+				ProjectName: "main",
+				Port:        8080,
+				Transport:   TransportStdio,
+				LogFormat:   logging.FormatText,
+				Redaction: redact.Rules{
+					Detectors: []string{"email", "jwt"},
+					Patterns:  []redact.Pattern{{Name: "employee_id", Regexp: `EMP-\d{6}`}},
+				},
+			},
+		},
+		"ok: redaction disabled": {
+			args: []string{"-redact-detectors", "none"},
+			want: &Config{
+				ConfigFile:    yamlFile,
+				ProjectName:   "main",
+				Port:          3000,
+				WatchInterval: 10 * time.Second,
+				Transport:     TransportStdio,
+				LogFormat:     logging.FormatText,
+				Redaction:     redact.Rules{Detectors: []string{}},
+			},
+		},
+		"err: invalid redaction rules": {
+			args: []string{"-project", "main", "-redact-detectors", "email,passport", "-redact-patterns", "id=EMP-(\nphone"},
+			wantProblems: []string{
+				"-redact-detectors lists unknown detector passport; known detectors are jwt, aws_key, api_key, email, phone",
+				"-redact-patterns: pattern id must be a valid regular expression: error parsing regexp: missing closing ): `EMP-(`",
+				"-redact-patterns must list patterns as name=regexp, one per line",
+			},
+		},
+		"ok: logging": {
+			args: []string{"-project", "main", "-log-level", "debug"},
+			env:  map[string]string{"MCP_LOG_FORMAT": "json"},
+			want: &Config{
+				ConfigFile:    yamlFile,
+				ProjectName:   "main",
+				Port:          3000,
+				WatchInterval: 10 * time.Second,
+				Transport:     TransportStdio,
+				LogLevel:      slog.LevelDebug,
+				LogFormat:     logging.FormatJSON,
+			},
+		},
+		"err: invalid logging": {
+			args: []string{"-project", "main", "-log-level", "verbose", "-log-format", "xml"},
+			wantProblems: []string{
+				"-log-level must be debug, info, warn or error",
+				"-log-format must be text or json",
+			},
+		},
+		"err: every problem reported": {
+			args: []string{"-config", invalidFile, "-profile", "work", "-watch-interval", "often"},
+			wantProblems: []string{
+				"port in " + invalidFile + " must be an integer",
+				"unknown key projekt in " + invalidFile,
+				"-watch-interval must be a non-negative duration such as 30s",
+				"transport in " + invalidFile + " (profile work) must be stdio or http",
+			},
+		},
+		"err: unknown profile": {
+			args:         []string{"-profile", "home"},
+			wantProblems: []string{"profile home is not defined in " + yamlFile},
+		},
+		"err: missing config file": {
+			args:         []string{"-config", filepath.Join(dir, "missing.yaml"), "-project", "main"},
+			wantProblems: []string{"config file: stat " + filepath.Join(dir, "missing.yaml") + ": no such file or directory"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, err := Load(tt.args)
+			if tt.wantProblems != nil {
+				var verr *ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("Load() error = %v, want a *ValidationError", err)
+				}
+				if diff := cmp.Diff(tt.wantProblems, verr.Problems); diff != "" {
+					t.Errorf("Load() problems mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("project: main\nprofiles:\n  work:\n    project: company\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "", "output directory")
+	got, err := LoadFlags(fs, []string{"-config", path, "-profile", "work", "-out", "vault", "-watch-interval", "1m", "title"})
+	if err != nil {
+		t.Fatalf("LoadFlags() error = %v", err)
+	}
+	want := &Config{
+		ConfigFile:    path,
+		Profile:       "work",
+		ProjectName:   "company",
+		Port:          8080,
+		WatchInterval: time.Minute,
+		Transport:     TransportStdio,
+		LogFormat:     logging.FormatText,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LoadFlags() mismatch (-want +got):\n%s", diff)
+	}
+	if *out != "vault" {
+		t.Errorf("-out = %q, want vault", *out)
+	}
+	if diff := cmp.Diff([]string{"title"}, fs.Args()); diff != "" {
+		t.Errorf("LoadFlags() arguments mismatch (-want +got):\n%s", diff)
+	}
+}
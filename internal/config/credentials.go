@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandTimeout limits how long the command of a CredentialSource may take.
+const commandTimeout = 30 * time.Second
+
+// CredentialSource is where a Scrapbox SID is read from instead of the
+// environment. At most one of its fields is set.
+type CredentialSource struct {
+	// File is a file holding the SID.
+	File string
+	// Command is a shell command printing the SID, such as the CLI of a
+	// password manager.
+	Command string
+	// CookiesFile is a Netscape cookies.txt export holding the connect.sid
+	// cookie of scrapbox.io.
+	CookiesFile string
+}
+
+// IsZero reports whether no source is set, in which case the SID comes from
+// the environment.
+func (s CredentialSource) IsZero() bool {
+	return s == CredentialSource{}
+}
+
+// String describes the source for messages asking to refresh the SID.
+func (s CredentialSource) String() string {
+	switch {
+	case s.File != "":
+		return "the file " + s.File
+	case s.Command != "":
+		return "the SID printed by " + s.Command
+	case s.CookiesFile != "":
+		return "the connect.sid cookie in " + s.CookiesFile
+	}
+	return "the environment"
+}
+
+// Read reads the SID from the source. It is called at startup and again
+// whenever Scrapbox rejects the SID, so that a rotated SID is picked up.
+func (s CredentialSource) Read(ctx context.Context) (string, error) {
+	switch {
+	case s.File != "":
+		b, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", err
+		}
+		if sid := strings.TrimSpace(string(b)); sid != "" {
+			return sid, nil
+		}
+		return "", fmt.Errorf("%s is empty", s.File)
+	case s.Command != "":
+		return runCommand(ctx, s.Command)
+	case s.CookiesFile != "":
+		return readCookiesFile(s.CookiesFile, time.Now())
+	}
+	return "", fmt.Errorf("no credential source")
+}
+
+// runCommand runs command with sh and returns its trimmed output.
+func runCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	if sid := strings.TrimSpace(stdout.String()); sid != "" {
+		return sid, nil
+	}
+	return "", fmt.Errorf("%q printed nothing", command)
+}
+
+// readCookiesFile returns the connect.sid cookie of scrapbox.io from a
+// Netscape cookies.txt file, skipping cookies expired at now. Each line has
+// the tab-separated fields domain, include subdomains, path, secure,
+// expiry, name and value; browsers prefix the domain of HttpOnly cookies,
+// such as connect.sid, with "#HttpOnly_".
+func readCookiesFile(path string, now time.Time) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var sid string
+	expired := false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(sc.Text()), "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 || strings.TrimPrefix(fields[0], ".") != "scrapbox.io" || fields[5] != "connect.sid" {
+			continue
+		}
+		if expiry, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expiry != 0 && expiry < now.Unix() {
+			expired = true
+			continue
+		}
+		sid = fields[6]
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	switch {
+	case sid != "":
+		return sid, nil
+	case expired:
+		return "", fmt.Errorf("the connect.sid cookie of scrapbox.io in %s has expired", path)
+	}
+	return "", fmt.Errorf("no connect.sid cookie of scrapbox.io in %s", path)
+}
+
+// loadSID reads the SID configured with key, key_FILE or key_COMMAND, or
+// with cookiesKey unless it is empty, and returns it along with its source.
+// They are looked up in the first layer of src setting any of them, which
+// may set only one.
+func loadSID(src sources, key, cookiesKey string, p *problems) (string, CredentialSource) {
+	keys := []string{key, key + "_FILE", key + "_COMMAND"}
+	if cookiesKey != "" {
+		keys = append(keys, cookiesKey)
+	}
+	for _, l := range src {
+		var set []string
+		var sid string
+		var source CredentialSource
+		for i, k := range keys {
+			v := l.get(k)
+			if v == "" {
+				continue
+			}
+			set = append(set, describe(l, k))
+			switch i {
+			case 0:
+				sid = v
+			case 1:
+				source = CredentialSource{File: v}
+			case 2:
+				source = CredentialSource{Command: v}
+			case 3:
+				source = CredentialSource{CookiesFile: v}
+			}
+		}
+		switch {
+		case len(set) == 0:
+			continue
+		case len(set) > 1:
+			p.add("only one of %s may be set", strings.Join(set, ", "))
+			return "", CredentialSource{}
+		case source.IsZero():
+			return sid, source
+		}
+		sid, err := source.Read(context.Background())
+		if err != nil {
+			p.add("failed to read the SID from %s: %v", set[0], err)
+			return "", CredentialSource{}
+		}
+		return sid, source
+	}
+	return "", CredentialSource{}
+}
+
+// describe describes where l sets the setting named by the environment
+// variable key.
+func describe(l layer, key string) string {
+	s, ok := settingsByEnv[key]
+	if !ok {
+		s = setting{env: key}
+	}
+	return l.where(s)
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadCookiesFile(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tests := map[string]struct {
+		content string
+		want    string
+		wantErr bool
+	}{
+		"ok: HttpOnly cookie": {
+			content: "# Netscape HTTP Cookie File\n#HttpOnly_scrapbox.io\tFALSE\t/\tTRUE\t1800000000\tconnect.sid\ts%3Aabc\n",
+			want:    "s%3Aabc",
+		},
+		"ok: other cookies and domains skipped": {
+			content: ".example.com\tTRUE\t/\tFALSE\t0\tconnect.sid\tother\r\n.scrapbox.io\tTRUE\t/\tTRUE\t0\t_ga\tga\r\n.scrapbox.io\tTRUE\t/\tTRUE\t0\tconnect.sid\ts%3Adef\r\n",
+			want:    "s%3Adef",
+		},
+		"err: expired cookie": {
+			content: "#HttpOnly_scrapbox.io\tFALSE\t/\tTRUE\t1600000000\tconnect.sid\ts%3Aabc\n",
+			wantErr: true,
+		},
+		"err: no cookie": {
+			content: "# Netscape HTTP Cookie File\n",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cookies.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readCookiesFile(path, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readCookiesFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readCookiesFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// kind is the type of the value of a setting.
+type kind int
+
+const (
+	kindString kind = iota
+	kindNumber
+	kindDuration
+	kindList
+	kindBool
+	// kindPatterns is a table of names to regular expressions in the file,
+	// and name=regexp lines elsewhere.
+	kindPatterns
+)
+
+// setting is a configuration setting, set by its environment variable, its
+// key in the config file or its command-line flag, which is the key with
+// "-" in place of "_". Secret settings have no flag, so that they do not
+// show up in the process list, and are masked by config check.
+type setting struct {
+	env    string
+	key    string
+	kind   kind
+	secret bool
+	usage  string
+}
+
+// settings are the settings in the order config check prints them.
+var settings = []setting{
+	{env: "SCRAPBOX_PROJECT", key: "project", usage: "primary Scrapbox project"},
+	{env: "SCRAPBOX_PROJECTS", key: "projects", kind: kindList, usage: "additional projects, comma-separated"},
+	{env: "SCRAPBOX_SID", key: "sid", secret: true},
+	{env: "SCRAPBOX_SID_FILE", key: "sid_file", usage: "file holding the Scrapbox SID"},
+	{env: "SCRAPBOX_SID_COMMAND", key: "sid_command", usage: "command printing the Scrapbox SID"},
+	{env: "SCRAPBOX_COOKIES_FILE", key: "cookies_file", usage: "Netscape cookies.txt holding the Scrapbox SID"},
+	{env: "SCRAPBOX_WATCH_INTERVAL", key: "watch_interval", kind: kindDuration, usage: "poll interval of resource subscriptions"},
+	{env: "SCRAPBOX_WATCH_MAX_BACKOFF", key: "watch_max_backoff", kind: kindDuration, usage: "maximum poll delay after failed polls"},
+	{env: "SCRAPBOX_COMPLETION_TTL", key: "completion_ttl", kind: kindDuration, usage: "how long page titles are cached for completion"},
+	{env: "SCRAPBOX_DENY_TITLES", key: "deny_titles", kind: kindList, usage: "titles of pages hidden from clients, comma-separated"},
+	{env: "SCRAPBOX_DENY_TITLE_PATTERN", key: "deny_title_pattern", usage: "regular expression matching the titles of pages hidden from clients"},
+	{env: "SCRAPBOX_DENY_TAGS", key: "deny_tags", kind: kindList, usage: "tags whose pages are hidden from clients, comma-separated"},
+	{env: "MCP_READ_ONLY", key: "read_only", kind: kindBool, usage: "disable the tools that create or modify pages"},
+	{env: "MCP_ENABLED_TOOLS", key: "enabled_tools", kind: kindList, usage: "tools to enable, comma-separated (default: all)"},
+	{env: "MCP_DISABLED_TOOLS", key: "disabled_tools", kind: kindList, usage: "tools to disable, comma-separated"},
+	{env: "MCP_REDACT_DETECTORS", key: "redact_detectors", kind: kindList, usage: "built-in detectors redacting outputs, comma-separated (default: all; none to disable)"},
+	{env: "MCP_REDACT_PATTERNS", key: "redact_patterns", kind: kindPatterns, usage: "additional patterns redacting outputs, one name=regexp per line"},
+	{env: "MCP_LOG_LEVEL", key: "log_level", usage: "debug, info, warn or error (default: info)"},
+	{env: "MCP_LOG_FORMAT", key: "log_format", usage: "text or json (default: text)"},
+	{env: "MCP_TRANSPORT", key: "transport", usage: "stdio or http"},
+	{env: "PORT", key: "port", kind: kindNumber, usage: "port to listen on in HTTP mode"},
+	{env: "MCP_MAX_BODY_BYTES", key: "max_body_bytes", kind: kindNumber, usage: "maximum HTTP request body size"},
+	{env: "MCP_AUTH_TOKENS", key: "auth_tokens", kind: kindList, secret: true},
+	{env: "MCP_AUTH_READ_ONLY_TOKENS", key: "auth_read_only_tokens", kind: kindList, secret: true},
+	{env: "MCP_AUTH_JWKS_FILE", key: "auth_jwks_file", usage: "JWKS file verifying OAuth access tokens"},
+	{env: "MCP_AUTH_AUDIENCE", key: "auth_audience", usage: "audience of OAuth access tokens"},
+	{env: "MCP_AUTH_ISSUER", key: "auth_issuer", usage: "issuer of OAuth access tokens"},
+	{env: "MCP_TENANT_MAP_FILE", key: "tenant_map_file", usage: "file mapping callers to Scrapbox credentials"},
+	{env: "MCP_TENANT_SID_HEADER", key: "tenant_sid_header", usage: "header carrying the Scrapbox SID of the caller"},
+	{env: "MCP_TENANT_PROJECT_HEADER", key: "tenant_project_header", usage: "header carrying the Scrapbox project of the caller"},
+}
+
+// settingsByKey indexes settings by their config file key.
+var settingsByKey = func() map[string]setting {
+	m := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		m[s.key] = s
+	}
+	return m
+}()
+
+// projectToolSettings are the settings that the project_tools table of the
+// config file overrides per project, as the environment variables named
+// after them with the suffix of projectSuffix do.
+var projectToolSettings = []string{"read_only", "enabled_tools", "disabled_tools"}
+
+// configFileNames are the names of the config file looked for in
+// $XDG_CONFIG_HOME/scrapbox-mcp, in order.
+var configFileNames = []string{"config.yaml", "config.yml", "config.toml"}
+
+// findConfigFile returns the config file named by path, SCRAPBOX_MCP_CONFIG
+// or else the first of configFileNames in the XDG config directory, or ""
+// if there is none. A file named by path or SCRAPBOX_MCP_CONFIG must exist.
+func findConfigFile(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv("SCRAPBOX_MCP_CONFIG")
+	}
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, "scrapbox-mcp", name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// configFile is a parsed config file: the settings of the selected profile
+// over the top-level ones, by environment variable name.
+type configFile struct {
+	path    string
+	profile string
+	values  map[string]string
+	// where describes where in the file each value is set.
+	where map[string]string
+	// profiles are the names of the profiles defined in the file.
+	profiles []string
+	// toolProjects describes where project_tools configures each project.
+	toolProjects map[string]string
+}
+
+// readConfigFile parses the YAML or TOML config file at path, TOML if its
+// name ends in .toml, and applies profile, or the profile named by the
+// "profile" key if empty. Every problem found, such as an unknown key, a
+// value of the wrong type or an unknown profile, is added to p.
+func readConfigFile(path, profile string, p *problems) *configFile {
+	f := &configFile{path: path, values: map[string]string{}, where: map[string]string{}, toolProjects: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		p.add("failed to read %s: %v", path, err)
+		return f
+	}
+	var raw map[string]any
+	if strings.HasSuffix(path, ".toml") {
+		if err := toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(&raw); err != nil {
+			p.add("failed to parse %s: %v", path, err)
+		}
+	} else if err := yaml.Unmarshal(data, &raw); err != nil {
+		p.add("failed to parse %s: %v", path, err)
+	}
+	if raw == nil {
+		return f
+	}
+
+	profiles := map[string]map[string]any{}
+	if v, ok := raw["profiles"]; ok {
+		m, ok := v.(map[string]any)
+		if !ok {
+			p.add("profiles in %s must be a table of profiles", path)
+		}
+		for name, v := range m {
+			ps, ok := v.(map[string]any)
+			if !ok {
+				p.add("profile %s in %s must be a table of settings", name, path)
+				continue
+			}
+			profiles[name] = ps
+			f.profiles = append(f.profiles, name)
+		}
+		sort.Strings(f.profiles)
+	}
+	if v, ok := raw["profile"]; ok && profile == "" {
+		if profile, ok = v.(string); !ok {
+			p.add("profile in %s must be a string", path)
+		}
+	}
+	delete(raw, "profiles")
+	delete(raw, "profile")
+
+	f.set(parseSettings(raw, path, p), path)
+	for _, name := range f.profiles {
+		where := fmt.Sprintf("%s (profile %s)", path, name)
+		fs := parseSettings(profiles[name], where, p)
+		if name == profile {
+			f.set(fs, where)
+		}
+	}
+	if profile != "" {
+		if _, ok := profiles[profile]; !ok {
+			p.add("profile %s is not defined in %s", profile, path)
+		}
+		f.profile = profile
+	}
+	return f
+}
+
+// set sets the settings of fs, found in where.
+func (f *configFile) set(fs *fileSettings, where string) {
+	for k, v := range fs.values {
+		f.values[k] = v
+		f.where[k] = fs.keys[k] + " in " + where
+	}
+	for _, name := range fs.projects {
+		f.toolProjects[name] = "project_tools in " + where
+	}
+}
+
+// fileSettings are the settings of a table of a config file.
+type fileSettings struct {
+	// values are the settings as they would be set in the environment,
+	// keyed by environment variable name, and keys their keys in the file.
+	values map[string]string
+	keys   map[string]string
+	// projects are the projects configured by project_tools.
+	projects []string
+}
+
+// parseSettings parses the settings of raw, the table of the config file
+// described by where, adding every problem found to p.
+func parseSettings(raw map[string]any, where string, p *problems) *fileSettings {
+	fs := &fileSettings{values: map[string]string{}, keys: map[string]string{}}
+	for _, k := range sortedKeys(raw) {
+		if k == "project_tools" {
+			fs.parseProjectTools(raw[k], where, p)
+			continue
+		}
+		s, ok := settingsByKey[k]
+		if !ok {
+			p.add("unknown key %s in %s", k, where)
+			continue
+		}
+		fs.parse(s, k, "", raw[k], where, p)
+	}
+	return fs
+}
+
+// parse parses the value v of setting s, set by key, for the environment
+// variable named by s with suffix.
+func (fs *fileSettings) parse(s setting, key, suffix string, v any, where string, p *problems) {
+	value, ok := formatValue(v, s.kind)
+	if !ok {
+		p.add("%s in %s must be %s", key, where, kindNames[s.kind])
+		return
+	}
+	fs.values[s.env+suffix] = value
+	fs.keys[s.env+suffix] = key
+}
+
+// parseProjectTools parses the project_tools table, which maps project names
+// to the settings of projectToolSettings.
+func (fs *fileSettings) parseProjectTools(v any, where string, p *problems) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		p.add("project_tools in %s must be a table of projects", where)
+		return
+	}
+	for _, name := range sortedKeys(m) {
+		ps, ok := m[name].(map[string]any)
+		if !ok {
+			p.add("project_tools.%s in %s must be a table of settings", name, where)
+			continue
+		}
+		fs.projects = append(fs.projects, name)
+		for _, k := range sortedKeys(ps) {
+			key := "project_tools." + name + "." + k
+			if !slices.Contains(projectToolSettings, k) {
+				p.add("unknown key %s in %s", key, where)
+				continue
+			}
+			fs.parse(settingsByKey[k], key, projectSuffix(name), ps[k], where, p)
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// kindNames describe the kinds in messages.
+var kindNames = map[kind]string{
+	kindString:   "a string",
+	kindNumber:   "an integer",
+	kindDuration: "a duration string such as \"30s\"",
+	kindList:     "a list of strings without commas",
+	kindBool:     "true or false",
+	kindPatterns: "a table of names to regular expressions",
+}
+
+// formatValue formats a value decoded from YAML or TOML as it would be set in
+// the environment, or reports false if it does not fit k.
+func formatValue(v any, k kind) (string, bool) {
+	switch k {
+	case kindBool:
+		b, ok := v.(bool)
+		return strconv.FormatBool(b), ok
+	case kindNumber:
+		switch n := v.(type) {
+		case int:
+			return strconv.Itoa(n), true
+		case int64:
+			return strconv.FormatInt(n, 10), true
+		case uint64:
+			return strconv.FormatUint(n, 10), true
+		}
+		return "", false
+	case kindList:
+		items, ok := v.([]any)
+		if !ok {
+			return "", false
+		}
+		list := make([]string, len(items))
+		for i, item := range items {
+			s, ok := formatValue(item, kindString)
+			if !ok || strings.Contains(s, ",") {
+				return "", false
+			}
+			list[i] = s
+		}
+		return strings.Join(list, ","), true
+	case kindPatterns:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		lines := make([]string, 0, len(m))
+		for _, name := range sortedKeys(m) {
+			s, ok := m[name].(string)
+			if !ok || strings.ContainsAny(name, "=\n") || strings.Contains(s, "\n") {
+				return "", false
+			}
+			lines = append(lines, name+"="+s)
+		}
+		return strings.Join(lines, "\n"), true
+	}
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case int, int64, uint64:
+		if k == kindString {
+			return fmt.Sprint(s), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval is how often a Reloader checks the config file for
+// changes.
+const DefaultReloadInterval = 2 * time.Second
+
+// ErrRestartRequired is returned by Reload for configurations changing
+// settings that only take effect when the server starts.
+var ErrRestartRequired = errors.New("restart required")
+
+// change is a setting compared by Reload. Live settings are applied to the
+// running server; the others need a restart.
+type change struct {
+	key   string
+	live  bool
+	value func(c *Config) any
+}
+
+var changes = []change{
+	{key: "project", value: func(c *Config) any { return c.ProjectName }},
+	{key: "projects", value: func(c *Config) any { return projectNames(c) }},
+	{key: "sid", live: true, value: func(c *Config) any { return []any{c.ScrapboxSID, c.SIDSource, c.Projects} }},
+	{key: "watch_interval", value: func(c *Config) any { return c.WatchInterval }},
+	{key: "watch_max_backoff", value: func(c *Config) any { return c.WatchMaxBackoff }},
+	{key: "completion_ttl", live: true, value: func(c *Config) any { return c.CompletionTTL }},
+	{key: "deny_titles", live: true, value: func(c *Config) any { return c.DenyTitles }},
+	{key: "deny_title_pattern", live: true, value: func(c *Config) any { return c.DenyTitlePattern }},
+	{key: "deny_tags", live: true, value: func(c *Config) any { return c.DenyTags }},
+	{key: "redact_detectors", live: true, value: func(c *Config) any { return c.Redaction.Detectors }},
+	{key: "redact_patterns", live: true, value: func(c *Config) any { return c.Redaction.Patterns }},
+	{key: "read_only", live: true, value: func(c *Config) any { return c.Tools.ReadOnly }},
+	{key: "enabled_tools", live: true, value: func(c *Config) any { return c.Tools.Allow }},
+	{key: "disabled_tools", live: true, value: func(c *Config) any { return c.Tools.Deny }},
+	{key: "project_tools", live: true, value: func(c *Config) any { return c.ProjectTools }},
+	{key: "log_level", live: true, value: func(c *Config) any { return c.LogLevel }},
+	{key: "log_format", value: func(c *Config) any { return c.LogFormat }},
+	{key: "transport", value: func(c *Config) any { return c.Transport }},
+	{key: "port", value: func(c *Config) any { return c.Port }},
+	{key: "max_body_bytes", live: true, value: func(c *Config) any { return c.MaxBodyBytes }},
+	{key: "auth", value: func(c *Config) any {
+		return []any{c.AuthTokens, c.AuthReadOnlyTokens, c.AuthJWKSFile, c.AuthAudience, c.AuthIssuer}
+	}},
+	{key: "tenant", value: func(c *Config) any { return []any{c.TenantMapFile, c.TenantSIDHeader, c.TenantProjectHeader} }},
+}
+
+// projectNames returns the names of the additional projects of c.
+func projectNames(c *Config) []string {
+	names := make([]string, len(c.Projects))
+	for i, p := range c.Projects {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Reloader reloads the configuration when the config file changes, so that
+// the settings that can change live, the credentials, limits, cache TTLs,
+// page access rules, redaction rules, log level and tool policies, do so
+// without restarting the server and dropping the sessions of its clients.
+type Reloader struct {
+	args []string
+
+	mu       sync.Mutex
+	current  *Config
+	modTime  time.Time
+	size     int64
+	onReload []func(*Config)
+}
+
+// NewReloader creates a Reloader of cfg, which was loaded by Load with args.
+func NewReloader(cfg *Config, args []string) *Reloader {
+	r := &Reloader{args: args, current: cfg}
+	r.modTime, r.size = stat(cfg.ConfigFile)
+	return r
+}
+
+// stat returns the modification time and size of the file at path.
+func stat(path string) (time.Time, int64) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, -1
+	}
+	return fi.ModTime(), fi.Size()
+}
+
+// OnReload registers f to be called with the new configuration after every
+// reload that changes it.
+func (r *Reloader) OnReload(f func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReload = append(r.onReload, f)
+}
+
+// Current returns the configuration in effect.
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Run checks the config file for changes every interval, or
+// DefaultReloadInterval if it is not positive, and reloads it until ctx is
+// done. The outcome of every reload is logged. Run returns at once if no
+// config file was loaded, since the environment and the flags cannot change.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	path := r.Current().ConfigFile
+	if path == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		modTime, size := stat(path)
+		r.mu.Lock()
+		unchanged := modTime.Equal(r.modTime) && size == r.size
+		r.modTime, r.size = modTime, size
+		r.mu.Unlock()
+		if unchanged {
+			continue
+		}
+		changed, err := r.Reload()
+		switch {
+		case err != nil:
+			slog.Default().Warn("rejected configuration changes, keeping the current configuration", "path", path, "error", err)
+		case len(changed) > 0:
+			slog.Default().Info("reloaded configuration", "path", path, "changed", strings.Join(changed, ", "))
+		}
+	}
+}
+
+// Reload loads the configuration again and returns the settings it changes.
+// A configuration that is invalid, or that changes settings needing a
+// restart, is rejected with an error listing them, and the current one stays
+// in effect. Otherwise the new configuration becomes current and, if it
+// changes anything, is passed to the functions registered with OnReload.
+func (r *Reloader) Reload() ([]string, error) {
+	cfg, err := Load(r.args)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	old := r.current
+	var live, restart []string
+	for _, c := range changes {
+		if reflect.DeepEqual(c.value(old), c.value(cfg)) {
+			continue
+		}
+		if c.live {
+			live = append(live, c.key)
+		} else {
+			restart = append(restart, c.key)
+		}
+	}
+	if len(restart) > 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w to change %s", ErrRestartRequired, strings.Join(restart, ", "))
+	}
+	r.current = cfg
+	fs := append([]func(*Config){}, r.onReload...)
+	r.mu.Unlock()
+
+	if len(live) > 0 {
+		for _, f := range fs {
+			f(cfg)
+		}
+	}
+	return live, nil
+}
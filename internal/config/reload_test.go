@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReloader_Reload(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	const initial = `
+project: main
+sid: old_sid
+enabled_tools: [get_page]
+`
+
+	tests := map[string]struct {
+		content     string
+		wantChanged []string
+		wantErr     bool
+		wantRestart bool
+	}{
+		"ok: live settings applied": {
+			content: `
+project: main
+sid: new_sid
+completion_ttl: 1m
+enabled_tools: [get_page, search_pages]
+max_body_bytes: 4096
+`,
+			wantChanged: []string{"sid", "completion_ttl", "enabled_tools", "max_body_bytes"},
+		},
+		"ok: nothing changed": {
+			content: initial,
+		},
+		"err: restart required": {
+			content: `
+project: other
+sid: new_sid
+port: 9000
+enabled_tools: [get_page]
+`,
+			wantErr:     true,
+			wantRestart: true,
+		},
+		"err: invalid configuration": {
+			content: `
+project: main
+port: "9000"
+`,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(initial), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			args := []string{"-config", path}
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			r := NewReloader(cfg, args)
+			var reloaded *Config
+			r.OnReload(func(cfg *Config) { reloaded = cfg })
+
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			changed, err := r.Reload()
+			if tt.wantErr {
+				if err == nil || errors.Is(err, ErrRestartRequired) != tt.wantRestart {
+					t.Fatalf("Reload() error = %v, want restart required: %v", err, tt.wantRestart)
+				}
+				if r.Current() != cfg || reloaded != nil {
+					t.Error("Reload() applied a rejected configuration")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reload() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantChanged, changed); diff != "" {
+				t.Errorf("Reload() mismatch (-want +got):\n%s", diff)
+			}
+			if len(tt.wantChanged) == 0 {
+				if reloaded != nil {
+					t.Error("OnReload functions called without changes")
+				}
+				return
+			}
+			if reloaded != r.Current() {
+				t.Error("OnReload functions not called with the current configuration")
+			}
+			if reloaded.ScrapboxSID != "new_sid" || reloaded.CompletionTTL != time.Minute {
+				t.Errorf("Current() = %+v, want the reloaded settings", reloaded)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// layer is one of the places settings are looked up: the command-line
+// flags, the environment or the config file.
+type layer struct {
+	// values are the settings by environment variable name, or nil to look
+	// them up in the environment.
+	values map[string]string
+	// where describes the origin of a setting in messages.
+	where func(s setting) string
+}
+
+// envLayer looks settings up in the environment.
+var envLayer = layer{where: func(s setting) string { return s.env }}
+
+// get returns the setting named by the environment variable key.
+func (l layer) get(key string) string {
+	if l.values == nil {
+		return os.Getenv(key)
+	}
+	return l.values[key]
+}
+
+// layer returns the layer of the settings of the file.
+func (f *configFile) layer() layer {
+	return layer{values: f.values, where: func(s setting) string { return f.where[s.env] }}
+}
+
+// sources are the layers settings are looked up in, in decreasing order of
+// precedence.
+type sources []layer
+
+// lookup returns the setting named by the environment variable key from the
+// first layer setting it, and describes where it was set.
+func (src sources) lookup(key string) (value, where string) {
+	for _, l := range src {
+		if v := l.get(key); v != "" {
+			return v, describe(l, key)
+		}
+	}
+	return "", key
+}
+
+// get returns the setting named by the environment variable key.
+func (src sources) get(key string) string {
+	v, _ := src.lookup(key)
+	return v
+}
+
+// list returns a comma-separated list setting, skipping empty items.
+func (src sources) list(key string) []string {
+	var list []string
+	for _, v := range strings.Split(src.get(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// settingsByEnv indexes settings by their environment variable.
+var settingsByEnv = func() map[string]setting {
+	m := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		m[s.env] = s
+	}
+	return m
+}()
+
+// flagName returns the command-line flag of s.
+func flagName(s setting) string {
+	return strings.ReplaceAll(s.key, "_", "-")
+}
+
+// problems collects the problems found in the configuration.
+type problems []string
+
+func (p *problems) add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// ValidationError is returned by Load for an invalid configuration. It lists
+// every problem found, so that they can all be fixed at once.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
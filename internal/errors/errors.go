@@ -17,6 +17,10 @@ func (e *ScrapboxError) Error() string {
 	return fmt.Sprintf("scrapbox error: %s (code: %d)", e.Message, e.Code)
 }
 
+func (e *ScrapboxError) Unwrap() error {
+	return e.Err
+}
+
 const (
 	ErrInvalidCredentials = 401
 	ErrNotFound           = 404
@@ -0,0 +1,190 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/pagestore"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+func lines(texts ...string) []scrapbox.Line {
+	ls := make([]scrapbox.Line, len(texts))
+	for i, t := range texts {
+		ls[i] = scrapbox.Line{Text: t}
+	}
+	return ls
+}
+
+func TestMarkdown(t *testing.T) {
+	page := &scrapbox.Page{
+		Title:   "Design: API",
+		Created: 1700000000,
+		Updated: 1700003600,
+		User:    &scrapbox.User{ID: "u1", Name: "alice", DisplayName: "Alice"},
+		Lines: lines(
+			"Design: API",
+			"See [Other Page] and [https://example.com site] #spec",
+			" [* important] `code`",
+			"  > nested quote",
+			"code:main.go",
+			" fmt.Println(`x`)",
+			"table:roles",
+			" name\trole",
+			" bob\ta|b",
+		),
+	}
+	resolve := func(title string) string { return SanitizeFileName(title) }
+
+	want := `---
+title: "Design: API"
+aliases:
+  - "Design: API"
+created: 2023-11-14T22:13:20Z
+updated: 2023-11-14T23:13:20Z
+author: "Alice"
+tags:
+  - "spec"
+---
+See [[Other Page]] and [site](https://example.com) #spec
+- **important** ` + "`code`" + `
+  - > nested quote
+` + "```go\nfmt.Println(`x`)\n```" + `
+**roles**
+
+| name | role |
+| --- | --- |
+| bob | a\|b |
+
+`
+	got := Markdown(page, SanitizeFileName(page.Title), resolve)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Markdown() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := map[string]struct {
+		title string
+		want  string
+	}{
+		"ok: plain title":       {title: "日本語 ページ", want: "日本語 ページ"},
+		"ok: reserved chars":    {title: `a/b\c:d?`, want: "a_b_c_d_"},
+		"ok: link syntax chars": {title: "[x]#y^z|w", want: "_x__y_z_w"},
+		"ok: leading dots":      {title: "..hidden.", want: "hidden"},
+		"ok: empty after trim":  {title: " . ", want: "untitled"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := SanitizeFileName(tc.title); got != tc.want {
+				t.Errorf("SanitizeFileName(%q) = %q, want %q", tc.title, got, tc.want)
+			}
+		})
+	}
+}
+
+type fakeSource struct {
+	pages []*scrapbox.Page
+}
+
+func (f *fakeSource) ListAllPages(ctx context.Context) ([]scrapbox.Page, error) {
+	var list []scrapbox.Page
+	for _, p := range f.pages {
+		list = append(list, scrapbox.Page{ID: p.ID, Title: p.Title, Updated: p.Updated})
+	}
+	return list, nil
+}
+
+func (f *fakeSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	for _, p := range f.pages {
+		if p.Title == title {
+			return p, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func TestExporter_Export(t *testing.T) {
+	store, err := pagestore.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	src := &fakeSource{pages: []*scrapbox.Page{
+		{ID: "1", Title: "A/B", Updated: 1, Lines: lines("A/B", "[c]")},
+		{ID: "2", Title: "c", Updated: 1, Lines: lines("c", "body")},
+		{ID: "3", Title: "A_B", Updated: 1, Lines: lines("A_B")},
+	}}
+	if _, err := store.Sync(context.Background(), src); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	out := t.TempDir()
+	exp := &Exporter{Store: store, Dir: out}
+	res, err := exp.Export()
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"A_B.md", "A_B (2).md", "c.md"}, res.Written); diff != "" {
+		t.Errorf("first Export() written mismatch (-want +got):\n%s", diff)
+	}
+
+	// Rename "c" and touch nothing else: only that page is rewritten.
+	src.pages[1] = &scrapbox.Page{ID: "2", Title: "d", Updated: 2, Lines: lines("d", "body")}
+	if _, err := store.Sync(context.Background(), src); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	res, err = exp.Export()
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	want := &Result{Written: []string{"d.md"}, Removed: []string{"c.md"}, Unchanged: 2}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("second Export() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(out, "c.md")); !os.IsNotExist(err) {
+		t.Errorf("c.md still exists after rename")
+	}
+}
+
+func TestExporter_ExportIgnoresForeignManifestEntries(t *testing.T) {
+	store, err := pagestore.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	parent := t.TempDir()
+	out := filepath.Join(parent, "out")
+	if err := os.MkdirAll(filepath.Join(out, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{
+		filepath.Join(parent, "outside.md"),
+		filepath.Join(out, "sub", "nested.md"),
+		filepath.Join(out, "notes.txt"),
+		filepath.Join(out, "stale.md"),
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := `{"1":{"file":"../outside.md"},"2":{"file":"sub/nested.md"},"3":{"file":"notes.txt"},"4":{"file":".."},"5":{"file":"stale.md"}}`
+	if err := os.WriteFile(filepath.Join(out, manifestFile), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := (&Exporter{Store: store, Dir: out}).Export()
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"stale.md"}, res.Removed); diff != "" {
+		t.Errorf("Export() removed mismatch (-want +got):\n%s", diff)
+	}
+	for _, f := range files[:3] {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("%s removed: %v", f, err)
+		}
+	}
+}
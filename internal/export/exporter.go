@@ -0,0 +1,197 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/takak2166/scrapbox-mcp/internal/pagestore"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox/notation"
+)
+
+// manifestFile records which page was exported to which file and at which
+// updated timestamp, so that later runs only rewrite changed pages.
+const manifestFile = ".scrapbox-export.json"
+
+// maxFileNameBytes keeps generated names well below common file system limits.
+const maxFileNameBytes = 200
+
+type manifestEntry struct {
+	File    string `json:"file"`
+	Updated int64  `json:"updated"`
+}
+
+// Result reports what an export changed.
+type Result struct {
+	Written   []string
+	Removed   []string
+	Unchanged int
+}
+
+// Exporter writes the pages of a store to a directory of Markdown files.
+type Exporter struct {
+	Store *pagestore.Store
+	Dir   string
+}
+
+// Export writes every page that changed since the previous export and
+// removes files of pages that were deleted or renamed.
+func (e *Exporter) Export() (*Result, error) {
+	if err := os.MkdirAll(e.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	manifest, err := e.readManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := e.Store.Entries()
+	names := assignFileNames(entries, manifest)
+	byTitle := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		byTitle[notation.NormalizeTitle(entry.Title)] = names[entry.ID]
+	}
+	resolve := func(title string) string {
+		if name, ok := byTitle[notation.NormalizeTitle(title)]; ok {
+			return name
+		}
+		return SanitizeFileName(title)
+	}
+
+	result := &Result{}
+	next := make(map[string]manifestEntry, len(entries))
+	for _, entry := range entries {
+		file := names[entry.ID] + ".md"
+		prev, ok := manifest[entry.ID]
+		if ok && prev.File == file && prev.Updated == entry.Updated {
+			if _, err := os.Stat(filepath.Join(e.Dir, file)); err == nil {
+				next[entry.ID] = prev
+				result.Unchanged++
+				continue
+			}
+		}
+		page, err := e.Store.Page(entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		md := Markdown(page, names[entry.ID], resolve)
+		if err := os.WriteFile(filepath.Join(e.Dir, file), []byte(md), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		next[entry.ID] = manifestEntry{File: file, Updated: entry.Updated}
+		result.Written = append(result.Written, file)
+	}
+
+	kept := map[string]bool{}
+	for _, m := range next {
+		kept[m.File] = true
+	}
+	for _, m := range manifest {
+		// The manifest may have been edited: only remove files this
+		// Exporter could have written, never ones outside Dir.
+		if kept[m.File] || !isExportFile(m.File) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(e.Dir, m.File)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove %s: %w", m.File, err)
+		}
+		result.Removed = append(result.Removed, m.File)
+	}
+
+	if err := e.writeManifest(next); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// isExportFile reports whether name is a Markdown file directly in the
+// export directory.
+func isExportFile(name string) bool {
+	return name == filepath.Base(name) && name != "." && name != ".." && strings.HasSuffix(name, ".md")
+}
+
+func (e *Exporter) readManifest() (map[string]manifestEntry, error) {
+	manifest := map[string]manifestEntry{}
+	b, err := os.ReadFile(filepath.Join(e.Dir, manifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return nil, fmt.Errorf("failed to read export manifest: %w", err)
+	}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode export manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func (e *Exporter) writeManifest(manifest map[string]manifestEntry) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal export manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(e.Dir, manifestFile), b, 0o644); err != nil {
+		return fmt.Errorf("failed to write export manifest: %w", err)
+	}
+	return nil
+}
+
+// assignFileNames picks a unique file name for every entry. Names from the
+// previous export are kept when the title still maps to them, so unrelated
+// pages never get renamed because a new page collides with them.
+func assignFileNames(entries []pagestore.Entry, manifest map[string]manifestEntry) map[string]string {
+	names := make(map[string]string, len(entries))
+	used := map[string]bool{}
+	for _, entry := range entries {
+		prev, ok := manifest[entry.ID]
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(prev.File, ".md")
+		base := SanitizeFileName(entry.Title)
+		if name == base || strings.HasPrefix(name, base+" (") {
+			names[entry.ID] = name
+			used[strings.ToLower(name)] = true
+		}
+	}
+	for _, entry := range entries {
+		if _, ok := names[entry.ID]; ok {
+			continue
+		}
+		base := SanitizeFileName(entry.Title)
+		name := base
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = base + " (" + strconv.Itoa(i) + ")"
+		}
+		names[entry.ID] = name
+		used[strings.ToLower(name)] = true
+	}
+	return names
+}
+
+// SanitizeFileName turns a page title into a file name that is valid on
+// common file systems and can be used as an Obsidian wikilink target.
+func SanitizeFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20:
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|#^[]`, r):
+			return '_'
+		}
+		return r
+	}, title)
+	name = strings.Trim(name, " .")
+	for len(name) > maxFileNameBytes {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" {
+		return "untitled"
+	}
+	return name
+}
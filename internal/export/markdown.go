@@ -0,0 +1,272 @@
+// Package export converts Scrapbox pages to Markdown files that can be opened
+// as an Obsidian vault.
+package export
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox/notation"
+)
+
+// LinkResolver maps a linked page title to the file name (without extension)
+// of the exported page.
+type LinkResolver func(title string) string
+
+// markdownEscaper escapes characters that would otherwise start Markdown
+// emphasis, links, HTML or code spans in plain text.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	"`", "\\`",
+)
+
+// Markdown renders page as a Markdown document with YAML front matter.
+// fileName is the name the page is exported under and is used to decide
+// whether the title needs to be kept as an alias.
+func Markdown(page *scrapbox.Page, fileName string, resolve LinkResolver) string {
+	var lines []string
+	for i, l := range page.Lines {
+		// The first line of a Scrapbox page is its title.
+		if i == 0 {
+			continue
+		}
+		lines = append(lines, l.Text)
+	}
+	blocks := notation.Parse(lines)
+
+	var b strings.Builder
+	writeFrontMatter(&b, page, fileName, notation.Tags(blocks))
+	for _, block := range blocks {
+		switch block := block.(type) {
+		case *notation.Line:
+			b.WriteString(listPrefix(block.Indent))
+			if block.Quote {
+				b.WriteString("> ")
+			}
+			text := renderNodes(block.Nodes, resolve)
+			if block.Indent == 0 && !block.Quote && strings.HasPrefix(text, "#") {
+				text = `\` + text
+			}
+			b.WriteString(text)
+			b.WriteString("\n")
+		case *notation.CodeBlock:
+			writeCodeBlock(&b, block)
+		case *notation.Table:
+			writeTable(&b, block)
+		}
+	}
+	return b.String()
+}
+
+func writeFrontMatter(b *strings.Builder, page *scrapbox.Page, fileName string, tags []string) {
+	b.WriteString("---\n")
+	fmt.Fprintf(b, "title: %s\n", strconv.Quote(page.Title))
+	if fileName != page.Title {
+		fmt.Fprintf(b, "aliases:\n  - %s\n", strconv.Quote(page.Title))
+	}
+	if page.Created != 0 {
+		fmt.Fprintf(b, "created: %s\n", formatTime(page.Created))
+	}
+	if page.Updated != 0 {
+		fmt.Fprintf(b, "updated: %s\n", formatTime(page.Updated))
+	}
+	if author := authorName(page); author != "" {
+		fmt.Fprintf(b, "author: %s\n", strconv.Quote(author))
+	}
+	if len(tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, t := range tags {
+			fmt.Fprintf(b, "  - %s\n", strconv.Quote(tagName(t)))
+		}
+	}
+	b.WriteString("---\n")
+}
+
+// authorName returns the display name of the page's creator, falling back to
+// the last editor and the user name.
+func authorName(page *scrapbox.Page) string {
+	for _, u := range []*scrapbox.User{page.User, page.LastUpdateUser} {
+		if u == nil {
+			continue
+		}
+		if u.DisplayName != "" {
+			return u.DisplayName
+		}
+		if u.Name != "" {
+			return u.Name
+		}
+	}
+	return ""
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+// listPrefix turns Scrapbox indentation into a nested Markdown bullet.
+func listPrefix(indent int) string {
+	if indent == 0 {
+		return ""
+	}
+	return strings.Repeat("  ", indent-1) + "- "
+}
+
+func renderNodes(nodes []notation.Node, resolve LinkResolver) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *notation.Text:
+			b.WriteString(markdownEscaper.Replace(n.Text))
+		case *notation.Link:
+			b.WriteString(wikiLink(n.Title, resolve))
+		case *notation.Icon:
+			b.WriteString(wikiLink(n.Title, resolve))
+		case *notation.Hashtag:
+			if tag := tagName(n.Tag); tag != "" && strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+				b.WriteString("#" + tag)
+			} else {
+				b.WriteString(wikiLink(n.Tag, resolve))
+			}
+		case *notation.ExternalLink:
+			if n.Label == "" {
+				b.WriteString("<" + n.URL + ">")
+			} else {
+				fmt.Fprintf(&b, "[%s](%s)", markdownEscaper.Replace(n.Label), n.URL)
+			}
+		case *notation.Image:
+			fmt.Fprintf(&b, "![](%s)", n.URL)
+		case *notation.Strong:
+			b.WriteString("**" + renderNodes(n.Nodes, resolve) + "**")
+		case *notation.Decoration:
+			b.WriteString(decorate(n.Marks, renderNodes(n.Nodes, resolve)))
+		case *notation.Code:
+			b.WriteString(codeSpan(n.Text))
+		case *notation.Formula:
+			b.WriteString("$" + n.Formula + "$")
+		}
+	}
+	return b.String()
+}
+
+// decorate applies the Markdown equivalents of Scrapbox decoration marks.
+func decorate(marks, text string) string {
+	if strings.Contains(marks, "_") {
+		text = "<u>" + text + "</u>"
+	}
+	if strings.Contains(marks, "-") {
+		text = "~~" + text + "~~"
+	}
+	if strings.Contains(marks, "/") {
+		text = "*" + text + "*"
+	}
+	if strings.Contains(marks, "*") {
+		text = "**" + text + "**"
+	}
+	return text
+}
+
+func wikiLink(title string, resolve LinkResolver) string {
+	target := resolve(title)
+	if target == title {
+		return "[[" + title + "]]"
+	}
+	return "[[" + target + "|" + strings.ReplaceAll(title, "|", "-") + "]]"
+}
+
+// codeSpan wraps s in enough backquotes that it cannot terminate early.
+func codeSpan(s string) string {
+	fence := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
+
+func writeCodeBlock(b *strings.Builder, block *notation.CodeBlock) {
+	fence := "```"
+	for _, l := range block.Lines {
+		if n := longestRun(l, '`'); n >= len(fence) {
+			fence = strings.Repeat("`", n+1)
+		}
+	}
+	b.WriteString(fence + codeLanguage(block.FileName) + "\n")
+	for _, l := range block.Lines {
+		b.WriteString(l + "\n")
+	}
+	b.WriteString(fence + "\n")
+}
+
+// codeLanguage derives a fence info string from a code block name, which is
+// either a file name ("main.go") or a bare language ("js").
+func codeLanguage(fileName string) string {
+	if ext := path.Ext(fileName); ext != "" {
+		return strings.TrimPrefix(ext, ".")
+	}
+	if strings.ContainsAny(fileName, " \t") {
+		return ""
+	}
+	return fileName
+}
+
+func writeTable(b *strings.Builder, table *notation.Table) {
+	if table.Name != "" {
+		fmt.Fprintf(b, "**%s**\n\n", markdownEscaper.Replace(table.Name))
+	}
+	if len(table.Rows) == 0 {
+		return
+	}
+	width := 0
+	for _, row := range table.Rows {
+		width = max(width, len(row))
+	}
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = strings.ReplaceAll(strings.TrimSpace(cells[i]), "|", `\|`)
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(table.Rows[0])
+	b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range table.Rows[1:] {
+		writeRow(row)
+	}
+	b.WriteString("\n")
+}
+
+// tagName converts a Scrapbox hashtag to a valid Obsidian tag, which may only
+// contain letters, digits, "_", "-" and "/".
+func tagName(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '/' {
+			return r
+		}
+		return '_'
+	}, tag)
+}
+
+func longestRun(s string, c byte) int {
+	longest, cur := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			cur++
+			longest = max(longest, cur)
+		} else {
+			cur = 0
+		}
+	}
+	return longest
+}
@@ -4,26 +4,50 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	mcp "github.com/ktr0731/go-mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/completion"
+	"github.com/takak2166/scrapbox-mcp/internal/history"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/prompts"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
 	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
 )
 
 // ToolHandler implements ServerToolHandler interface.
 type ToolHandler struct {
-	client *scrapbox.Client
+	projects *projects.Set
 }
 
-// NewToolHandler creates a new ToolHandler instance.
-func NewToolHandler(client *scrapbox.Client) *ToolHandler {
-	return &ToolHandler{
-		client: client,
+// NewToolHandler creates a new ToolHandler instance. The project argument of
+// every tool selects one of set, the primary project by default. Calls to
+// tools that registry disables in the project they are called on are
+// rejected.
+func NewToolHandler(set *projects.Set, registry *tools.Registry) ServerToolHandler {
+	return &checkedToolHandler{
+		next:     &ToolHandler{projects: set},
+		registry: registry,
+		projects: set,
+	}
+}
+
+// client returns the client of the project selected by a project argument.
+func (h *ToolHandler) client(project *string) (*scrapbox.Client, error) {
+	if project == nil {
+		return h.projects.Primary(), nil
 	}
+	return h.projects.Client(*project)
 }
 
 // HandleToolGetPage handles get_page tool requests.
 func (h *ToolHandler) HandleToolGetPage(ctx context.Context, req *ToolGetPageRequest) (*mcp.CallToolResult, error) {
-	page, err := h.client.GetPage(ctx, req.PageTitle)
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
+	page, err := client.GetPage(ctx, req.PageTitle)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get page: %w", err)
 	}
@@ -40,7 +64,11 @@ func (h *ToolHandler) HandleToolGetPage(ctx context.Context, req *ToolGetPageReq
 
 // HandleToolListPages handles list_pages tool requests.
 func (h *ToolHandler) HandleToolListPages(ctx context.Context, req *ToolListPagesRequest) (*mcp.CallToolResult, error) {
-	pages, err := h.client.ListPages(ctx)
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := client.ListPages(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to list pages: %w", err)
 	}
@@ -57,7 +85,11 @@ func (h *ToolHandler) HandleToolListPages(ctx context.Context, req *ToolListPage
 
 // HandleToolSearchPages handles search_pages tool requests.
 func (h *ToolHandler) HandleToolSearchPages(ctx context.Context, req *ToolSearchPagesRequest) (*mcp.CallToolResult, error) {
-	pages, err := h.client.SearchPages(ctx, req.Query)
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := client.SearchPages(ctx, req.Query)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to search pages: %w", err)
 	}
@@ -74,11 +106,15 @@ func (h *ToolHandler) HandleToolSearchPages(ctx context.Context, req *ToolSearch
 
 // HandleToolCreatePageUrl handles create_page_url tool requests.
 func (h *ToolHandler) HandleToolCreatePageUrl(ctx context.Context, req *ToolCreatePageUrlRequest) (*mcp.CallToolResult, error) {
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
 	bodyText := ""
 	if req.BodyText != nil {
 		bodyText = *req.BodyText
 	}
-	pageURL, err := h.client.CreatePageURL(ctx, req.PageTitle, bodyText)
+	pageURL, err := client.CreatePageURL(ctx, req.PageTitle, bodyText)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to generate page URL: %w", err)
 	}
@@ -88,3 +124,267 @@ func (h *ToolHandler) HandleToolCreatePageUrl(ctx context.Context, req *ToolCrea
 		},
 	}, nil
 }
+
+// HandleToolGetPageHistory handles get_page_history tool requests.
+func (h *ToolHandler) HandleToolGetPageHistory(ctx context.Context, req *ToolGetPageHistoryRequest) (*mcp.CallToolResult, error) {
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
+	hist, err := history.GetHistory(ctx, client, req.PageTitle)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get page history: %w", err)
+	}
+	return jsonResult(hist)
+}
+
+// HandleToolGetPageAt handles get_page_at tool requests.
+func (h *ToolHandler) HandleToolGetPageAt(ctx context.Context, req *ToolGetPageAtRequest) (*mcp.CallToolResult, error) {
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
+	at, err := history.ParseTime(req.Time)
+	if err != nil {
+		return nil, err
+	}
+	v, err := history.PageAt(ctx, client, req.PageTitle, at)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get page version: %w", err)
+	}
+	return jsonResult(v)
+}
+
+// HandleToolDiffPageVersions handles diff_page_versions tool requests.
+func (h *ToolHandler) HandleToolDiffPageVersions(ctx context.Context, req *ToolDiffPageVersionsRequest) (*mcp.CallToolResult, error) {
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
+	from, err := history.ParseTime(req.From)
+	if err != nil {
+		return nil, err
+	}
+	var to time.Time
+	if req.To != nil && *req.To != "" {
+		if to, err = history.ParseTime(*req.To); err != nil {
+			return nil, err
+		}
+	}
+	d, err := history.DiffVersions(ctx, client, req.PageTitle, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to diff page versions: %w", err)
+	}
+	return jsonResult(d)
+}
+
+// HandleToolBlamePage handles blame_page tool requests.
+func (h *ToolHandler) HandleToolBlamePage(ctx context.Context, req *ToolBlamePageRequest) (*mcp.CallToolResult, error) {
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
+	var since time.Time
+	if req.Since != nil && *req.Since != "" {
+		if since, err = history.ParseTime(*req.Since); err != nil {
+			return nil, err
+		}
+	}
+	b, err := history.Blame(ctx, client, req.PageTitle, since)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to blame page: %w", err)
+	}
+	return jsonResult(b)
+}
+
+// HandleToolRecentChanges handles recent_changes tool requests.
+func (h *ToolHandler) HandleToolRecentChanges(ctx context.Context, req *ToolRecentChangesRequest) (*mcp.CallToolResult, error) {
+	client, err := h.client(req.Project)
+	if err != nil {
+		return nil, err
+	}
+	since, err := history.ParseTime(req.Since)
+	if err != nil {
+		return nil, err
+	}
+	maxChars := 0
+	if req.MaxChars != nil {
+		maxChars = *req.MaxChars
+	}
+	a, err := history.RecentChanges(ctx, client, since, maxChars)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list recent changes: %w", err)
+	}
+	return jsonResult(a)
+}
+
+// HandleToolSearchAll handles search_all tool requests.
+func (h *ToolHandler) HandleToolSearchAll(ctx context.Context, req *ToolSearchAllRequest) (*mcp.CallToolResult, error) {
+	r, err := h.projects.SearchAll(ctx, req.Query, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to search projects: %w", err)
+	}
+	return jsonResult(r)
+}
+
+// HandleToolListProjects handles list_projects tool requests.
+func (h *ToolHandler) HandleToolListProjects(ctx context.Context, req *ToolListProjectsRequest) (*mcp.CallToolResult, error) {
+	return jsonResult(h.projects.List())
+}
+
+// jsonResult returns v marshalled as JSON text content.
+func jsonResult(v any) (*mcp.CallToolResult, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal result: %w", err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.CallToolContent{
+			mcp.TextContent{Text: string(b)},
+		},
+	}, nil
+}
+
+// ResourceHandler implements mcp.ServerResourceHandler for page resources.
+type ResourceHandler struct {
+	projects *projects.Set
+}
+
+// NewResourceHandler creates a new ResourceHandler instance. The pages of the
+// primary project are listed; those of every project in set can be read.
+func NewResourceHandler(set *projects.Set) *ResourceHandler {
+	return &ResourceHandler{
+		projects: set,
+	}
+}
+
+// HandleResourcesList lists the pinned and recently updated pages.
+// go-mcp has no lastModified annotation, so the time is only part of the
+// description.
+func (h *ResourceHandler) HandleResourcesList(ctx context.Context) (*mcp.ListResourcesResult, error) {
+	list, err := resources.List(ctx, h.projects.Primary(), resources.DefaultLimit)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list resources: %w", err)
+	}
+	result := &mcp.ListResourcesResult{Resources: []mcp.Resource{}}
+	for _, r := range list {
+		result.Resources = append(result.Resources, mcp.Resource{
+			URI:         r.URI,
+			Name:        r.Name,
+			Description: r.Description,
+			MimeType:    r.MIMEType,
+		})
+	}
+	return result, nil
+}
+
+// HandleResourcesRead reads a page resource.
+func (h *ResourceHandler) HandleResourcesRead(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	c, err := resources.Read(ctx, h.projects.ForURI(req.URI), req.URI)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read resource: %w", err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContent{
+			mcp.TextResourceContent{URI: c.URI, MimeType: c.MIMEType, Text: c.Text},
+		},
+	}, nil
+}
+
+// PromptHandler implements ServerPromptHandler interface.
+type PromptHandler struct {
+	client *scrapbox.Client
+}
+
+// NewPromptHandler creates a new PromptHandler instance.
+func NewPromptHandler(client *scrapbox.Client) *PromptHandler {
+	return &PromptHandler{
+		client: client,
+	}
+}
+
+// HandlePromptSummarizePage handles summarize_page prompt requests.
+func (h *PromptHandler) HandlePromptSummarizePage(ctx context.Context, req *PromptSummarizePageRequest) (*mcp.GetPromptResult, error) {
+	return h.get(ctx, prompts.SummarizePage, map[string]string{"page_title": req.PageTitle})
+}
+
+// HandlePromptWeeklyDigest handles weekly_digest prompt requests.
+func (h *PromptHandler) HandlePromptWeeklyDigest(ctx context.Context, req *PromptWeeklyDigestRequest) (*mcp.GetPromptResult, error) {
+	return h.get(ctx, prompts.WeeklyDigest, map[string]string{"since": req.Since})
+}
+
+// HandlePromptDraftMeetingNotes handles draft_meeting_notes prompt requests.
+func (h *PromptHandler) HandlePromptDraftMeetingNotes(ctx context.Context, req *PromptDraftMeetingNotesRequest) (*mcp.GetPromptResult, error) {
+	return h.get(ctx, prompts.DraftMeetingNotes, map[string]string{"topic": req.Topic})
+}
+
+// HandlePromptAnswerFromProject handles answer_from_project prompt requests.
+func (h *PromptHandler) HandlePromptAnswerFromProject(ctx context.Context, req *PromptAnswerFromProjectRequest) (*mcp.GetPromptResult, error) {
+	return h.get(ctx, prompts.AnswerFromProject, map[string]string{"question": req.Question})
+}
+
+func (h *PromptHandler) get(ctx context.Context, name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	r, err := prompts.Get(ctx, h.client, name, args)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get prompt: %w", err)
+	}
+	result := &mcp.GetPromptResult{Description: r.Description}
+	for _, m := range r.Messages {
+		var content mcp.PromptMessageContent = mcp.TextContent{Text: m.Text}
+		if m.Resource != nil {
+			content = embeddedResource{mcp.EmbeddedResource{
+				Resource: mcp.TextResourceContent{URI: m.Resource.URI, MimeType: m.Resource.MIMEType, Text: m.Resource.Text},
+			}}
+		}
+		result.Messages = append(result.Messages, mcp.PromptMessage{Role: mcp.RoleUser, Content: content})
+	}
+	return result, nil
+}
+
+// CompletionHandler implements mcp.ServerCompletionHandler for page titles.
+type CompletionHandler struct {
+	completer *completion.Completer
+}
+
+// NewCompletionHandler creates a new CompletionHandler instance.
+func NewCompletionHandler(client *scrapbox.Client) *CompletionHandler {
+	return &CompletionHandler{
+		completer: completion.New(client, completion.DefaultTTL),
+	}
+}
+
+// SetTTL changes how long page titles are cached.
+func (h *CompletionHandler) SetTTL(ttl time.Duration) {
+	h.completer.SetTTL(ttl)
+}
+
+// HandleComplete completes page titles. go-mcp drops the uri of resource
+// references, so they are taken to name the page resource template, the only
+// template offered.
+func (h *CompletionHandler) HandleComplete(ctx context.Context, req *mcp.CompleteRequestParams) (*mcp.CompleteResult, error) {
+	refName := req.Ref.Name
+	if req.Ref.Type == mcp.CompletionReferenceTypeResource {
+		refName = resources.URITemplate
+	}
+	r, err := h.completer.Complete(ctx, string(req.Ref.Type), refName, req.Argument.Name, req.Argument.Value)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to complete: %w", err)
+	}
+	return &mcp.CompleteResult{Values: r.Values, Total: r.Total, HasMore: r.HasMore}, nil
+}
+
+// embeddedResource adds the content type that go-mcp leaves out when
+// marshalling an embedded resource.
+type embeddedResource struct {
+	mcp.EmbeddedResource
+}
+
+func (e embeddedResource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type     string              `json:"type"`
+		Resource mcp.ResourceContent `json:"resource"`
+	}{
+		Type:     "resource",
+		Resource: e.Resource,
+	})
+}
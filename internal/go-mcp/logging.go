@@ -0,0 +1,56 @@
+package scrapbox
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+const methodSetLevel = "logging/setLevel"
+
+// go-mcp keeps the level selected with logging/setLevel in a global shared
+// by every connection, so the handler keeps one per connection instead and
+// sends the records logged while serving a request over its connection.
+
+// loggingHandler logs the Scrapbox requests made for each request with a
+// request ID of their own and sends the records logged while serving it to
+// the connection, once it selected a level.
+type loggingHandler struct {
+	next jsonrpc2.Handler
+	conn *jsonrpc2.Connection
+	// level is the level selected by the client, nil until it does.
+	level atomic.Pointer[slog.Level]
+}
+
+// Handle implements jsonrpc2.Handler.
+func (h *loggingHandler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+	if req.Method == methodSetLevel {
+		var params struct {
+			Level string `json:"level"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err == nil {
+			if level, ok := logging.ParseMCPLevel(params.Level); ok {
+				h.level.Store(&level)
+			}
+		}
+	}
+	ctx = scrapbox.ContextWithRequestID(ctx, scrapbox.NewRequestID())
+	return h.next.Handle(logging.NewContext(ctx, h), req)
+}
+
+// Log implements logging.Client.
+func (h *loggingHandler) Log(ctx context.Context, level slog.Level, data json.RawMessage) {
+	if min := h.level.Load(); min == nil || level < *min {
+		return
+	}
+	_ = h.conn.Notify(ctx, notificationMessage, map[string]any{
+		"level":  logging.MCPLevel(level),
+		"logger": logging.LoggerName,
+		"data":   data,
+	})
+}
@@ -13,6 +13,40 @@ import (
 
 // ServerPromptHandler is the interface for prompt handlers.
 type ServerPromptHandler interface {
+	HandlePromptSummarizePage(ctx context.Context, req *PromptSummarizePageRequest) (*mcp.GetPromptResult, error)
+	HandlePromptWeeklyDigest(ctx context.Context, req *PromptWeeklyDigestRequest) (*mcp.GetPromptResult, error)
+	HandlePromptDraftMeetingNotes(ctx context.Context, req *PromptDraftMeetingNotesRequest) (*mcp.GetPromptResult, error)
+	HandlePromptAnswerFromProject(ctx context.Context, req *PromptAnswerFromProjectRequest) (*mcp.GetPromptResult, error)
+}
+
+// PromptSummarizePageRequest contains input parameters for the summarize_page prompt.
+type PromptSummarizePageRequest struct {
+	PageTitle string `json:"page_title"`
+}
+
+// PromptWeeklyDigestRequest contains input parameters for the weekly_digest prompt.
+type PromptWeeklyDigestRequest struct {
+	Since string `json:"since"`
+}
+
+// PromptDraftMeetingNotesRequest contains input parameters for the draft_meeting_notes prompt.
+type PromptDraftMeetingNotesRequest struct {
+	Topic string `json:"topic"`
+}
+
+// PromptAnswerFromProjectRequest contains input parameters for the answer_from_project prompt.
+type PromptAnswerFromProjectRequest struct {
+	Question string `json:"question"`
+}
+
+// ResourceTemplateList contains all available ResourceTemplates.
+var ResourceTemplateList = []mcp.ResourceTemplate{
+	{
+		URITemplate: "scrapbox://{project}/{title}",
+		Name:        "page",
+		Description: "A page of the Scrapbox project as Markdown. Append ?format=json for the page JSON.",
+		MimeType:    "text/markdown",
+	},
 }
 
 // ServerToolHandler is the interface for tool handlers.
@@ -21,37 +55,143 @@ type ServerToolHandler interface {
 	HandleToolListPages(ctx context.Context, req *ToolListPagesRequest) (*mcp.CallToolResult, error)
 	HandleToolSearchPages(ctx context.Context, req *ToolSearchPagesRequest) (*mcp.CallToolResult, error)
 	HandleToolCreatePageUrl(ctx context.Context, req *ToolCreatePageUrlRequest) (*mcp.CallToolResult, error)
+	HandleToolGetPageHistory(ctx context.Context, req *ToolGetPageHistoryRequest) (*mcp.CallToolResult, error)
+	HandleToolGetPageAt(ctx context.Context, req *ToolGetPageAtRequest) (*mcp.CallToolResult, error)
+	HandleToolDiffPageVersions(ctx context.Context, req *ToolDiffPageVersionsRequest) (*mcp.CallToolResult, error)
+	HandleToolBlamePage(ctx context.Context, req *ToolBlamePageRequest) (*mcp.CallToolResult, error)
+	HandleToolRecentChanges(ctx context.Context, req *ToolRecentChangesRequest) (*mcp.CallToolResult, error)
+	HandleToolSearchAll(ctx context.Context, req *ToolSearchAllRequest) (*mcp.CallToolResult, error)
+	HandleToolListProjects(ctx context.Context, req *ToolListProjectsRequest) (*mcp.CallToolResult, error)
 }
 
 // ToolGetPageRequest contains input parameters for the get_page tool.
 type ToolGetPageRequest struct {
-	PageTitle string `json:"page_title"`
+	PageTitle string  `json:"page_title"`
+	Project   *string `json:"project,omitempty"`
 }
 
 // ToolListPagesRequest contains input parameters for the list_pages tool.
 type ToolListPagesRequest struct {
+	Project *string `json:"project,omitempty"`
 }
 
 // ToolSearchPagesRequest contains input parameters for the search_pages tool.
 type ToolSearchPagesRequest struct {
-	Query string `json:"query"`
+	Query   string  `json:"query"`
+	Project *string `json:"project,omitempty"`
 }
 
 // ToolCreatePageUrlRequest contains input parameters for the create_page_url tool.
 type ToolCreatePageUrlRequest struct {
 	PageTitle string  `json:"page_title"`
 	BodyText  *string `json:"body_text"`
+	Project   *string `json:"project,omitempty"`
+}
+
+// ToolGetPageHistoryRequest contains input parameters for the get_page_history tool.
+type ToolGetPageHistoryRequest struct {
+	PageTitle string  `json:"page_title"`
+	Project   *string `json:"project,omitempty"`
+}
+
+// ToolGetPageAtRequest contains input parameters for the get_page_at tool.
+type ToolGetPageAtRequest struct {
+	PageTitle string  `json:"page_title"`
+	Time      string  `json:"time"`
+	Project   *string `json:"project,omitempty"`
+}
+
+// ToolDiffPageVersionsRequest contains input parameters for the diff_page_versions tool.
+type ToolDiffPageVersionsRequest struct {
+	PageTitle string  `json:"page_title"`
+	From      string  `json:"from"`
+	To        *string `json:"to,omitempty"`
+	Project   *string `json:"project,omitempty"`
+}
+
+// ToolBlamePageRequest contains input parameters for the blame_page tool.
+type ToolBlamePageRequest struct {
+	PageTitle string  `json:"page_title"`
+	Since     *string `json:"since,omitempty"`
+	Project   *string `json:"project,omitempty"`
+}
+
+// ToolRecentChangesRequest contains input parameters for the recent_changes tool.
+type ToolRecentChangesRequest struct {
+	Since    string  `json:"since"`
+	MaxChars *int    `json:"max_chars,omitempty"`
+	Project  *string `json:"project,omitempty"`
+}
+
+// ToolSearchAllRequest contains input parameters for the search_all tool.
+type ToolSearchAllRequest struct {
+	Query string `json:"query"`
+}
+
+// ToolListProjectsRequest contains input parameters for the list_projects tool.
+type ToolListProjectsRequest struct {
 }
 
 // PromptList contains all available prompts.
-var PromptList = []protocol.Prompt{}
+var PromptList = []protocol.Prompt{
+	{
+		Name:        "summarize_page",
+		Description: "Summarize a Scrapbox page",
+		Arguments: []protocol.PromptArgument{
+			{
+				Name:        "page_title",
+				Description: "Title of the page to summarize",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "weekly_digest",
+		Description: "Write a digest of the pages changed in the project",
+		Arguments: []protocol.PromptArgument{
+			{
+				Name:        "since",
+				Description: "Start of the period as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds (defaults to 7 days ago)",
+			},
+		},
+	},
+	{
+		Name:        "draft_meeting_notes",
+		Description: "Draft a meeting notes page in Scrapbox notation",
+		Arguments: []protocol.PromptArgument{
+			{
+				Name:        "topic",
+				Description: "Topic of the meeting",
+				Required:    true,
+			},
+		},
+	},
+	{
+		Name:        "answer_from_project",
+		Description: "Answer a question using the pages of the project that match it",
+		Arguments: []protocol.PromptArgument{
+			{
+				Name:        "question",
+				Description: "Question to answer",
+				Required:    true,
+			},
+		},
+	},
+}
 
 // JSON Schema type definitions generated from inputSchema
 var (
-	ToolGetPageInputSchema       = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"page_title":{"type":"string","description":"Page title to retrieve"}},"additionalProperties":false,"type":"object","required":["page_title"]}`)
-	ToolListPagesInputSchema     = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{},"additionalProperties":false,"type":"object"}`)
-	ToolSearchPagesInputSchema   = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"query":{"type":"string","description":"Search query"}},"additionalProperties":false,"type":"object","required":["query"]}`)
-	ToolCreatePageUrlInputSchema = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"page_title":{"type":"string","description":"Page title"},"body_text":{"type":"string","description":"Body text for the new page"}},"additionalProperties":false,"type":"object","required":["page_title","body_text"]}`)
+	ToolGetPageInputSchema          = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"page_title":{"type":"string","description":"Page title to retrieve"},"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object","required":["page_title"]}`)
+	ToolListPagesInputSchema        = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object"}`)
+	ToolSearchPagesInputSchema      = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"query":{"type":"string","description":"Search query"},"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object","required":["query"]}`)
+	ToolCreatePageUrlInputSchema    = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"page_title":{"type":"string","description":"Page title"},"body_text":{"type":"string","description":"Body text for the new page"},"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object","required":["page_title","body_text"]}`)
+	ToolGetPageHistoryInputSchema   = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"page_title":{"type":"string","description":"Page title"},"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object","required":["page_title"]}`)
+	ToolGetPageAtInputSchema        = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"page_title":{"type":"string","description":"Page title"},"time":{"type":"string","description":"Point in time as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds"},"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object","required":["page_title","time"]}`)
+	ToolDiffPageVersionsInputSchema = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"page_title":{"type":"string","description":"Page title"},"from":{"type":"string","description":"Time of the older version"},"to":{"type":"string","description":"Time of the newer version (defaults to the current page)"},"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object","required":["page_title","from"]}`)
+	ToolBlamePageInputSchema        = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"page_title":{"type":"string","description":"Page title"},"since":{"type":"string","description":"Only include lines changed at or after this time (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)"},"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object","required":["page_title"]}`)
+	ToolRecentChangesInputSchema    = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"since":{"type":"string","description":"Start of the period (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)"},"max_chars":{"type":"integer","description":"Maximum total characters of line text to return"},"project":{"type":"string","description":"Project to use (defaults to the primary project)"}},"additionalProperties":false,"type":"object","required":["since"]}`)
+	ToolSearchAllInputSchema        = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"query":{"type":"string","description":"Search query"}},"additionalProperties":false,"type":"object","required":["query"]}`)
+	ToolListProjectsInputSchema     = json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{},"additionalProperties":false,"type":"object"}`)
 )
 
 // ToolList contains all available tools.
@@ -76,19 +216,98 @@ var ToolList = []protocol.Tool{
 		Description: "Generate a URL for creating a new page",
 		InputSchema: ToolCreatePageUrlInputSchema,
 	},
+	{
+		Name:        "get_page_history",
+		Description: "List the saved snapshots and commits of a page, newest first",
+		InputSchema: ToolGetPageHistoryInputSchema,
+	},
+	{
+		Name:        "get_page_at",
+		Description: "Get the content of a page as it was at a point in time",
+		InputSchema: ToolGetPageAtInputSchema,
+	},
+	{
+		Name:        "diff_page_versions",
+		Description: "Show a unified diff between two versions of a page",
+		InputSchema: ToolDiffPageVersionsInputSchema,
+	},
+	{
+		Name:        "blame_page",
+		Description: "Show who last edited each line of a page, grouped by author and editing session",
+		InputSchema: ToolBlamePageInputSchema,
+	},
+	{
+		Name:        "recent_changes",
+		Description: "List pages updated since a time with the lines each author added or modified",
+		InputSchema: ToolRecentChangesInputSchema,
+	},
+	{
+		Name:        "search_all",
+		Description: "Full-text search across every project at once, with the project of each page and the projects whose search failed",
+		InputSchema: ToolSearchAllInputSchema,
+	},
+	{
+		Name:        "list_projects",
+		Description: "List the Scrapbox projects served, which the project argument of the other tools selects",
+		InputSchema: ToolListProjectsInputSchema,
+	},
 }
 
 // NewHandler creates a new MCP handler.
-func NewHandler(toolHandler ServerToolHandler) *mcp.Handler {
+func NewHandler(promptHandler ServerPromptHandler, resourceHandler mcp.ServerResourceHandler, toolHandler ServerToolHandler, completionHandler mcp.ServerCompletionHandler) *mcp.Handler {
 	h := &mcp.Handler{}
 	h.Capabilities = protocol.ServerCapabilities{
-		Tools:   &protocol.ToolCapability{},
-		Logging: &protocol.LoggingCapability{},
+		Prompts: &protocol.PromptCapability{},
+		Resources: &protocol.ResourceCapability{
+			Subscribe:   true,
+			ListChanged: true,
+		},
+		Tools:       &protocol.ToolCapability{},
+		Completions: &protocol.CompletionsCapability{},
+		Logging:     &protocol.LoggingCapability{},
 	}
 	h.Implementation = protocol.Implementation{
 		Name:    "Scrapbox MCP Server",
 		Version: "1.0.0",
 	}
+	h.Prompts = PromptList
+	h.PromptHandler = protocol.ServerHandlerFunc[protocol.GetPromptRequestParams](func(ctx context.Context, method string, req protocol.GetPromptRequestParams) (any, error) {
+		switch method {
+		case "prompts/get":
+			switch req.Name {
+			case "summarize_page":
+				var in PromptSummarizePageRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				return promptHandler.HandlePromptSummarizePage(ctx, &in)
+			case "weekly_digest":
+				var in PromptWeeklyDigestRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				return promptHandler.HandlePromptWeeklyDigest(ctx, &in)
+			case "draft_meeting_notes":
+				var in PromptDraftMeetingNotesRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				return promptHandler.HandlePromptDraftMeetingNotes(ctx, &in)
+			case "answer_from_project":
+				var in PromptAnswerFromProjectRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				return promptHandler.HandlePromptAnswerFromProject(ctx, &in)
+			default:
+				return nil, fmt.Errorf("prompt not found: %s", req.Name)
+			}
+		default:
+			return nil, fmt.Errorf("method %s not found", method)
+		}
+	})
+	h.ResourceHandler = resourceHandler
+	h.ResourceTemplates = ResourceTemplateList
 	h.Tools = ToolList
 	h.ToolHandler = protocol.ServerHandlerFunc[protocol.CallToolRequestParams](func(ctx context.Context, method string, req protocol.CallToolRequestParams) (any, error) {
 		idx := slices.IndexFunc(ToolList, func(t protocol.Tool) bool {
@@ -140,6 +359,76 @@ func NewHandler(toolHandler ServerToolHandler) *mcp.Handler {
 					return nil, err
 				}
 				return toolHandler.HandleToolCreatePageUrl(ctx, &in)
+			case "get_page_history":
+				var in ToolGetPageHistoryRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
+				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
+					return nil, err
+				}
+				return toolHandler.HandleToolGetPageHistory(ctx, &in)
+			case "get_page_at":
+				var in ToolGetPageAtRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
+				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
+					return nil, err
+				}
+				return toolHandler.HandleToolGetPageAt(ctx, &in)
+			case "diff_page_versions":
+				var in ToolDiffPageVersionsRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
+				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
+					return nil, err
+				}
+				return toolHandler.HandleToolDiffPageVersions(ctx, &in)
+			case "blame_page":
+				var in ToolBlamePageRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
+				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
+					return nil, err
+				}
+				return toolHandler.HandleToolBlamePage(ctx, &in)
+			case "recent_changes":
+				var in ToolRecentChangesRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
+				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
+					return nil, err
+				}
+				return toolHandler.HandleToolRecentChanges(ctx, &in)
+			case "search_all":
+				var in ToolSearchAllRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
+				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
+					return nil, err
+				}
+				return toolHandler.HandleToolSearchAll(ctx, &in)
+			case "list_projects":
+				var in ToolListProjectsRequest
+				if err := json.Unmarshal(req.Arguments, &in); err != nil {
+					return nil, err
+				}
+				inputSchema, _ := ToolList[idx].InputSchema.(json.RawMessage)
+				if err := protocol.ValidateByJSONSchema(string(inputSchema), in); err != nil {
+					return nil, err
+				}
+				return toolHandler.HandleToolListProjects(ctx, &in)
 			default:
 				return nil, fmt.Errorf("tool not found: %s", req.Name)
 			}
@@ -147,5 +436,6 @@ func NewHandler(toolHandler ServerToolHandler) *mcp.Handler {
 			return nil, fmt.Errorf("method %s not found", method)
 		}
 	})
+	h.CompletionHandler = completionHandler
 	return h
 }
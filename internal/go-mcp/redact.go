@@ -0,0 +1,72 @@
+package scrapbox
+
+import (
+	"context"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+// redactHandler redacts the tool results, resources, resource lists and
+// prompts returned by the generated handler.
+type redactHandler struct {
+	next     jsonrpc2.Handler
+	redactor *redact.Redactor
+}
+
+// Handle implements jsonrpc2.Handler.
+func (h *redactHandler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+	result, err := h.next.Handle(ctx, req)
+	if err != nil || h.redactor == nil {
+		return result, err
+	}
+	switch r := result.(type) {
+	case *mcp.CallToolResult:
+		for i, c := range r.Content {
+			if c, ok := h.redactContent(c).(mcp.CallToolContent); ok {
+				r.Content[i] = c
+			}
+		}
+	case *mcp.ReadResourceResult:
+		for i, c := range r.Contents {
+			r.Contents[i] = h.redactResource(c)
+		}
+	case *mcp.ListResourcesResult:
+		for i := range r.Resources {
+			r.Resources[i].Description = h.redactor.Redact(r.Resources[i].Description)
+		}
+	case *mcp.GetPromptResult:
+		for i, m := range r.Messages {
+			if c, ok := h.redactContent(m.Content).(mcp.PromptMessageContent); ok {
+				r.Messages[i].Content = c
+			}
+		}
+	}
+	return result, nil
+}
+
+// redactContent returns the tool or prompt content c with its text redacted.
+func (h *redactHandler) redactContent(c any) any {
+	switch c := c.(type) {
+	case mcp.TextContent:
+		c.Text = h.redactor.Text(c.Text)
+		return c
+	case mcp.EmbeddedResource:
+		c.Resource = h.redactResource(c.Resource)
+		return c
+	case embeddedResource:
+		c.Resource = h.redactResource(c.Resource)
+		return c
+	}
+	return c
+}
+
+// redactResource returns the resource content c with its text redacted.
+func (h *redactHandler) redactResource(c mcp.ResourceContent) mcp.ResourceContent {
+	if t, ok := c.(mcp.TextResourceContent); ok {
+		t.Text = h.redactor.Text(t.Text)
+		return t
+	}
+	return c
+}
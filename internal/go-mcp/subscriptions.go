@@ -0,0 +1,122 @@
+package scrapbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+const (
+	methodSubscribe                 = "resources/subscribe"
+	methodUnsubscribe               = "resources/unsubscribe"
+	notificationResourceUpdated     = "notifications/resources/updated"
+	notificationResourceListChanged = "notifications/resources/list_changed"
+	notificationMessage             = "notifications/message"
+)
+
+// go-mcp records subscriptions but has no way to notify subscribers, so the
+// binder is wrapped: every connection gets a watch.Watcher that follows the
+// subscribe and unsubscribe requests and notifies through the connection.
+// The connection is also told when the cookie of a project expires, when
+// the enabled tools change and of the records logged while serving it.
+
+// SubscriptionBinder adds resource change notifications to the connections
+// bound by another binder.
+type SubscriptionBinder struct {
+	binder   jsonrpc2.Binder
+	projects *projects.Set
+	opts     watch.Options
+	registry *tools.Registry
+	redactor *redact.Redactor
+}
+
+// NewSubscriptionBinder wraps binder, typically the one returned by
+// mcp.NewStdioTransport. The pages of the primary project of set can be
+// subscribed to, and opts configures their polling. Only the tools enabled by
+// registry are served, and their results, resources and prompts are redacted
+// by redactor, unless nil.
+func NewSubscriptionBinder(binder jsonrpc2.Binder, set *projects.Set, opts watch.Options, registry *tools.Registry, redactor *redact.Redactor) *SubscriptionBinder {
+	return &SubscriptionBinder{
+		binder:   binder,
+		projects: set,
+		opts:     opts,
+		registry: registry,
+		redactor: redactor,
+	}
+}
+
+// Bind implements jsonrpc2.Binder.
+func (b *SubscriptionBinder) Bind(ctx context.Context, conn *jsonrpc2.Connection) (jsonrpc2.ConnectionOptions, error) {
+	opts, err := b.binder.Bind(ctx, conn)
+	if err != nil {
+		return opts, err
+	}
+	w := watch.New(b.projects.Primary(), &connNotifier{conn: conn}, b.opts)
+	var next jsonrpc2.Handler = &toolsHandler{next: &redactHandler{next: opts.Handler, redactor: b.redactor}, registry: b.registry}
+	next = &loggingHandler{next: next, conn: conn}
+	opts.Handler = &subscriptionHandler{next: next, watcher: w}
+	remove := b.projects.OnExpired(func(err error) {
+		_ = conn.Notify(context.Background(), notificationMessage, map[string]string{"level": "error", "logger": "scrapbox", "data": err.Error()})
+	})
+	removeTools := b.registry.OnChange(func() {
+		_ = conn.Notify(context.Background(), notificationToolListChanged, struct{}{})
+	})
+	go func() {
+		_ = conn.Wait()
+		remove()
+		removeTools()
+		w.Close()
+	}()
+	return opts, nil
+}
+
+// subscriptionHandler keeps the watcher in step with the subscriptions of a
+// connection before passing every request on.
+type subscriptionHandler struct {
+	next    jsonrpc2.Handler
+	watcher *watch.Watcher
+}
+
+// Handle implements jsonrpc2.Handler.
+func (h *subscriptionHandler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+	if req.Method == methodSubscribe || req.Method == methodUnsubscribe {
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+			return nil, jsonrpc2.ErrInvalidParams
+		}
+		if req.Method == methodUnsubscribe {
+			h.watcher.Unsubscribe(params.URI)
+		} else if err := h.watcher.Subscribe(ctx, params.URI); err != nil {
+			if errors.Is(err, resources.ErrNotFound) {
+				return nil, fmt.Errorf("Resource not found: %s", params.URI)
+			}
+			return nil, fmt.Errorf("Failed to subscribe: %w", err)
+		}
+	}
+	return h.next.Handle(ctx, req)
+}
+
+// connNotifier sends watcher notifications over a connection.
+type connNotifier struct {
+	conn *jsonrpc2.Connection
+}
+
+// ResourceUpdated implements watch.Notifier.
+func (n *connNotifier) ResourceUpdated(ctx context.Context, uri string) error {
+	return n.conn.Notify(ctx, notificationResourceUpdated, map[string]string{"uri": uri})
+}
+
+// ResourceListChanged implements watch.Notifier.
+func (n *connNotifier) ResourceListChanged(ctx context.Context) error {
+	return n.conn.Notify(ctx, notificationResourceListChanged, struct{}{})
+}
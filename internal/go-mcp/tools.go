@@ -0,0 +1,120 @@
+package scrapbox
+
+import (
+	"context"
+
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+const notificationToolListChanged = "notifications/tools/list_changed"
+
+// go-mcp lists the tools of the generated ToolList and cannot change it, so
+// the tools disabled by the registry are filtered out of tools/list, and
+// calls to tools disabled in the project they are called on are rejected by
+// the ServerToolHandler returned by NewToolHandler.
+
+// toolsHandler lists the tools enabled by a registry.
+type toolsHandler struct {
+	next     jsonrpc2.Handler
+	registry *tools.Registry
+}
+
+// listToolsResult is the result of tools/list.
+type listToolsResult struct {
+	Tools []protocol.Tool `json:"tools"`
+}
+
+// Handle implements jsonrpc2.Handler.
+func (h *toolsHandler) Handle(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+	switch req.Method {
+	case protocol.MethodToolsList:
+		result := &listToolsResult{Tools: []protocol.Tool{}}
+		for _, t := range ToolList {
+			if h.registry.Enabled(t.Name) {
+				result.Tools = append(result.Tools, t)
+			}
+		}
+		return result, nil
+	}
+	return h.next.Handle(ctx, req)
+}
+
+// checkedToolHandler passes the calls that a registry enables in the
+// project they are called on to the tool handlers of next.
+type checkedToolHandler struct {
+	next     ServerToolHandler
+	registry *tools.Registry
+	projects *projects.Set
+}
+
+// checkTool calls handler, the handler of the named tool, with req unless
+// the registry of h disables the tool in the project selected by project,
+// the primary project if nil.
+func checkTool[Req any](ctx context.Context, h *checkedToolHandler, name string, req Req, project *string, handler func(context.Context, Req) (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error) {
+	return tools.Wrap(h.registry, name, func(context.Context, Req) (tools.Call, error) {
+		call := tools.Call{Primary: h.projects.Primary().ProjectName()}
+		if project != nil {
+			call.Project = *project
+		}
+		return call, nil
+	}, handler)(ctx, req)
+}
+
+// HandleToolGetPage implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolGetPage(ctx context.Context, req *ToolGetPageRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "get_page", req, req.Project, h.next.HandleToolGetPage)
+}
+
+// HandleToolListPages implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolListPages(ctx context.Context, req *ToolListPagesRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "list_pages", req, req.Project, h.next.HandleToolListPages)
+}
+
+// HandleToolSearchPages implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolSearchPages(ctx context.Context, req *ToolSearchPagesRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "search_pages", req, req.Project, h.next.HandleToolSearchPages)
+}
+
+// HandleToolCreatePageUrl implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolCreatePageUrl(ctx context.Context, req *ToolCreatePageUrlRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "create_page_url", req, req.Project, h.next.HandleToolCreatePageUrl)
+}
+
+// HandleToolGetPageHistory implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolGetPageHistory(ctx context.Context, req *ToolGetPageHistoryRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "get_page_history", req, req.Project, h.next.HandleToolGetPageHistory)
+}
+
+// HandleToolGetPageAt implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolGetPageAt(ctx context.Context, req *ToolGetPageAtRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "get_page_at", req, req.Project, h.next.HandleToolGetPageAt)
+}
+
+// HandleToolDiffPageVersions implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolDiffPageVersions(ctx context.Context, req *ToolDiffPageVersionsRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "diff_page_versions", req, req.Project, h.next.HandleToolDiffPageVersions)
+}
+
+// HandleToolBlamePage implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolBlamePage(ctx context.Context, req *ToolBlamePageRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "blame_page", req, req.Project, h.next.HandleToolBlamePage)
+}
+
+// HandleToolRecentChanges implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolRecentChanges(ctx context.Context, req *ToolRecentChangesRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "recent_changes", req, req.Project, h.next.HandleToolRecentChanges)
+}
+
+// HandleToolSearchAll implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolSearchAll(ctx context.Context, req *ToolSearchAllRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "search_all", req, nil, h.next.HandleToolSearchAll)
+}
+
+// HandleToolListProjects implements ServerToolHandler.
+func (h *checkedToolHandler) HandleToolListProjects(ctx context.Context, req *ToolListProjectsRequest) (*mcp.CallToolResult, error) {
+	return checkTool(ctx, h, "list_projects", req, nil, h.next.HandleToolListProjects)
+}
@@ -0,0 +1,102 @@
+package scrapbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	mcp "github.com/ktr0731/go-mcp"
+	"github.com/ktr0731/go-mcp/protocol"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"golang.org/x/exp/jsonrpc2"
+)
+
+func TestToolsHandler(t *testing.T) {
+	registry := tools.NewRegistry(tools.Policy{ReadOnly: true, Deny: []string{"search_all"}}, map[string]tools.Policy{
+		"sandbox": {Deny: []string{"search_all"}},
+	})
+	h := &toolsHandler{
+		next: jsonrpc2.HandlerFunc(func(ctx context.Context, req *jsonrpc2.Request) (any, error) {
+			return "called", nil
+		}),
+		registry: registry,
+	}
+	ctx := context.Background()
+	handle := func(method string, params any) (any, error) {
+		t.Helper()
+		req, err := jsonrpc2.NewCall(jsonrpc2.Int64ID(1), method, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h.Handle(ctx, req)
+	}
+	listed := func() []string {
+		t.Helper()
+		result, err := handle(protocol.MethodToolsList, struct{}{})
+		if err != nil {
+			t.Fatalf("tools/list error = %v", err)
+		}
+		var names []string
+		for _, tool := range result.(*listToolsResult).Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	want := []string{"get_page", "list_pages", "search_pages", "create_page_url", "get_page_history", "get_page_at", "diff_page_versions", "blame_page", "recent_changes", "list_projects"}
+	if diff := cmp.Diff(want, listed()); diff != "" {
+		t.Errorf("tools/list mismatch (-want +got):\n%s", diff)
+	}
+
+	registry.SetPolicy(tools.Policy{Allow: []string{"get_page", "list_projects"}}, nil)
+	if diff := cmp.Diff([]string{"get_page", "list_projects"}, listed()); diff != "" {
+		t.Errorf("tools/list after SetPolicy mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewToolHandler(t *testing.T) {
+	registry := tools.NewRegistry(tools.Policy{ReadOnly: true, Deny: []string{"search_all"}}, map[string]tools.Policy{
+		"sandbox": {Deny: []string{"search_all"}},
+	})
+	set := projects.FromConfig(&config.Config{ProjectName: "main", Projects: []config.Project{{Name: "sandbox"}}})
+	h := NewToolHandler(set, registry)
+	ctx := context.Background()
+
+	sandbox := "sandbox"
+	tests := map[string]struct {
+		call    func() (*mcp.CallToolResult, error)
+		wantErr bool
+	}{
+		"ok: write tool in writable project": {
+			call: func() (*mcp.CallToolResult, error) {
+				return h.HandleToolCreatePageUrl(ctx, &ToolCreatePageUrlRequest{PageTitle: "Draft", Project: &sandbox})
+			},
+		},
+		"err: write tool in read-only project": {
+			call: func() (*mcp.CallToolResult, error) {
+				return h.HandleToolCreatePageUrl(ctx, &ToolCreatePageUrlRequest{PageTitle: "Draft"})
+			},
+			wantErr: true,
+		},
+		"err: denied tool": {
+			call: func() (*mcp.CallToolResult, error) {
+				return h.HandleToolSearchAll(ctx, &ToolSearchAllRequest{Query: "q"})
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := tt.call()
+			if tt.wantErr != errors.Is(err, tools.ErrDisabled) {
+				t.Fatalf("tool call error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (err != nil || result == nil) {
+				t.Errorf("tool call = %v, %v, want the result of the tool", result, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package history
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// sessionGap is the longest pause between edits of adjacent lines by the same
+// author that still counts as one editing session.
+const sessionGap = 10 * time.Minute
+
+// BlameSource is the subset of scrapbox.Client used to attribute lines.
+type BlameSource interface {
+	GetPage(ctx context.Context, title string) (*scrapbox.Page, error)
+	GetProject(ctx context.Context) (*scrapbox.Project, error)
+}
+
+// PageBlame attributes the lines of a page to their authors.
+type PageBlame struct {
+	Title string `json:"title"`
+	// Since is set when only lines changed at or after it are included.
+	Since   string          `json:"since,omitempty"`
+	Authors []AuthorSummary `json:"authors"`
+	Hunks   []BlameHunk     `json:"hunks"`
+}
+
+// AuthorSummary counts the lines last edited by one author.
+type AuthorSummary struct {
+	Author   string `json:"author"`
+	UserID   string `json:"user_id,omitempty"`
+	Lines    int    `json:"lines"`
+	LastEdit string `json:"last_edit"`
+}
+
+// BlameHunk is a run of adjacent lines last edited by the same author in
+// the same editing session.
+type BlameHunk struct {
+	Author string `json:"author"`
+	UserID string `json:"user_id,omitempty"`
+	// StartLine and EndLine are 1-based line numbers; the title is line 1.
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+	// From and To are the oldest and newest edit times in the hunk.
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Lines []string `json:"lines"`
+}
+
+// Blame groups the lines of the page titled title by author and editing
+// session. A non-zero since drops lines last updated before it.
+//
+// User IDs are resolved to display names from the project members; when the
+// member list is not available they fall back to the page's own user fields
+// and finally to the raw ID.
+func Blame(ctx context.Context, src BlameSource, title string, since time.Time) (*PageBlame, error) {
+	page, err := src.GetPage(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	names := memberNames(ctx, src)
+	addPageUsers(names, page)
+
+	b := &PageBlame{Title: page.Title, Authors: []AuthorSummary{}, Hunks: []BlameHunk{}}
+	if !since.IsZero() {
+		b.Since = since.UTC().Format(time.RFC3339)
+	}
+
+	authors := map[string]*AuthorSummary{}
+	var hunk *BlameHunk
+	var hunkLast int64
+	prev := -1
+	for i, l := range page.Lines {
+		if !since.IsZero() && l.Updated < since.Unix() {
+			continue
+		}
+		name := displayName(names, l.UserID)
+
+		a, ok := authors[l.UserID]
+		if !ok {
+			a = &AuthorSummary{Author: name, UserID: l.UserID}
+			authors[l.UserID] = a
+		}
+		a.Lines++
+		if edit := formatTime(l.Updated); edit > a.LastEdit {
+			a.LastEdit = edit
+		}
+
+		sameSession := hunk != nil && prev == i-1 && hunk.UserID == l.UserID &&
+			absDuration(l.Updated-hunkLast) <= int64(sessionGap/time.Second)
+		if !sameSession {
+			if hunk != nil {
+				b.Hunks = append(b.Hunks, *hunk)
+			}
+			hunk = &BlameHunk{Author: name, UserID: l.UserID, StartLine: i + 1, From: formatTime(l.Updated), To: formatTime(l.Updated)}
+		}
+		hunk.EndLine = i + 1
+		hunk.Lines = append(hunk.Lines, l.Text)
+		if t := formatTime(l.Updated); t < hunk.From {
+			hunk.From = t
+		} else if t > hunk.To {
+			hunk.To = t
+		}
+		hunkLast = l.Updated
+		prev = i
+	}
+	if hunk != nil {
+		b.Hunks = append(b.Hunks, *hunk)
+	}
+
+	for _, a := range authors {
+		b.Authors = append(b.Authors, *a)
+	}
+	sort.Slice(b.Authors, func(i, j int) bool {
+		if b.Authors[i].Lines != b.Authors[j].Lines {
+			return b.Authors[i].Lines > b.Authors[j].Lines
+		}
+		return b.Authors[i].Author < b.Authors[j].Author
+	})
+	return b, nil
+}
+
+// memberNames maps user IDs of the project members to display names.
+// Failing to load the members is not an error; the map is then empty.
+func memberNames(ctx context.Context, src interface {
+	GetProject(ctx context.Context) (*scrapbox.Project, error)
+}) map[string]string {
+	names := map[string]string{}
+	if project, err := src.GetProject(ctx); err == nil {
+		for _, u := range project.Users {
+			names[u.ID] = firstNonEmpty(u.DisplayName, u.Name)
+		}
+	}
+	return names
+}
+
+// addPageUsers adds the users embedded in page to names without overriding
+// names resolved from the member list.
+func addPageUsers(names map[string]string, page *scrapbox.Page) {
+	for _, u := range []*scrapbox.User{page.User, page.LastUpdateUser} {
+		if u != nil && u.ID != "" && names[u.ID] == "" {
+			names[u.ID] = firstNonEmpty(u.DisplayName, u.Name)
+		}
+	}
+}
+
+func displayName(names map[string]string, userID string) string {
+	if userID == "" {
+		return "unknown"
+	}
+	return firstNonEmpty(names[userID], userID)
+}
+
+func absDuration(d int64) int64 {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
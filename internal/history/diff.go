@@ -0,0 +1,197 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+)
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opInsert
+	opDelete
+)
+
+// lineOp is one step of an edit script turning a into b.
+type lineOp struct {
+	kind opKind
+	// a and b are the 0-based line indexes in the old and new text. For
+	// inserts a is the position in the old text, for deletes b is the
+	// position in the new text.
+	a, b int
+	text string
+}
+
+// diffLines computes a minimal line edit script from a to b with Myers'
+// linear space algorithm, which takes O((n+m)·d) time for an edit script of
+// d lines and O(n+m) memory, so that diffing two large, heavily rewritten
+// versions stays cheap.
+func diffLines(a, b []string) []lineOp {
+	n := len(a) + len(b) + 1
+	d := &differ{a: a, b: b, ops: make([]lineOp, 0, len(a)+len(b)), vf: make([]int, 2*n+1), vb: make([]int, 2*n+1)}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// differ computes the edit script of diffLines into ops. vf and vb hold the
+// furthest reaching forward and backward paths by diagonal, offset by len(vf)/2.
+type differ struct {
+	a, b   []string
+	ops    []lineOp
+	vf, vb []int
+}
+
+// compare appends the edit script from a[a0:a1] to b[b0:b1].
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.ops = append(d.ops, lineOp{kind: opEqual, a: a0, b: b0, text: d.a[a0]})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1-suffix > a0 && b1-suffix > b0 && d.a[a1-1-suffix] == d.b[b1-1-suffix] {
+		suffix++
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	switch {
+	case a0 == a1:
+		for j := b0; j < b1; j++ {
+			d.ops = append(d.ops, lineOp{kind: opInsert, a: a0, b: j, text: d.b[j]})
+		}
+	case b0 == b1:
+		for i := a0; i < a1; i++ {
+			d.ops = append(d.ops, lineOp{kind: opDelete, a: i, b: b0, text: d.a[i]})
+		}
+	default:
+		// Both halves are shorter edit scripts: without a common prefix
+		// or suffix, at least two edits are left.
+		x, y := d.split(a0, a1, b0, b1)
+		d.compare(a0, x, b0, y)
+		d.compare(x, a1, y, b1)
+	}
+
+	for k := 0; k < suffix; k++ {
+		d.ops = append(d.ops, lineOp{kind: opEqual, a: a1 + k, b: b1 + k, text: d.a[a1+k]})
+	}
+}
+
+// split returns a point on a minimal edit path from a[a0:a1] to b[b0:b1],
+// found where the forward path from the start meets the backward path from
+// the end, which splits the edit script in halves.
+func (d *differ) split(a0, a1, b0, b1 int) (int, int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta&1 != 0
+	off := len(d.vf) / 2
+	d.vf[off+1], d.vb[off+1] = 0, 0
+	for D := 0; D <= (n+m+1)/2; D++ {
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && d.vf[off+k-1] < d.vf[off+k+1]) {
+				x = d.vf[off+k+1]
+			} else {
+				x = d.vf[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			d.vf[off+k] = x
+			// The backward paths of D-1 edits end on diagonal delta-k of
+			// the reversed texts.
+			if odd && delta-k >= -(D-1) && delta-k <= D-1 && x+d.vb[off+delta-k] >= n {
+				return a0 + x, b0 + y
+			}
+		}
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && d.vb[off+k-1] < d.vb[off+k+1]) {
+				x = d.vb[off+k+1]
+			} else {
+				x = d.vb[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			d.vb[off+k] = x
+			if !odd && delta-k >= -D && delta-k <= D && x+d.vf[off+delta-k] >= n {
+				return a1 - x, b1 - y
+			}
+		}
+	}
+	// Unreachable: the paths meet within (n+m+1)/2 edits.
+	return a0, b0
+}
+
+// unified formats ops as a unified diff with the given number of context lines.
+// It returns an empty string when there are no changes.
+func unified(ops []lineOp, fromName, toName string, context int) string {
+	var b strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		first := max(start-context, 0)
+		// Extend the hunk while changes are separated by at most 2*context
+		// unchanged lines.
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != opEqual {
+				end = k
+				continue
+			}
+			if k-end > 2*context {
+				break
+			}
+		}
+		last := min(end+context, len(ops)-1)
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		var oldLines, newLines int
+		for _, op := range ops[first : last+1] {
+			if op.kind != opInsert {
+				oldLines++
+			}
+			if op.kind != opDelete {
+				newLines++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(ops[first].a, oldLines), hunkRange(ops[first].b, newLines))
+		for _, op := range ops[first : last+1] {
+			switch op.kind {
+			case opEqual:
+				b.WriteString(" ")
+			case opInsert:
+				b.WriteString("+")
+			case opDelete:
+				b.WriteString("-")
+			}
+			b.WriteString(op.text)
+			b.WriteString("\n")
+		}
+		start = last + 1
+	}
+	return b.String()
+}
+
+// hunkRange formats a 0-based start line and line count as a unified diff range.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
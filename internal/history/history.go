@@ -0,0 +1,215 @@
+// Package history answers questions about earlier versions of a page using
+// the page snapshots and commits endpoints.
+package history
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// Source is the subset of scrapbox.Client used to look up page history.
+type Source interface {
+	GetPage(ctx context.Context, title string) (*scrapbox.Page, error)
+	GetPageSnapshots(ctx context.Context, pageID string) (*scrapbox.PageSnapshotList, error)
+	GetPageCommits(ctx context.Context, pageID string) (*scrapbox.CommitList, error)
+}
+
+// PageHistory summarises the saved versions and edits of a page.
+type PageHistory struct {
+	Title     string            `json:"title"`
+	PageID    string            `json:"page_id"`
+	Updated   string            `json:"updated,omitempty"`
+	Snapshots []SnapshotSummary `json:"snapshots"`
+	Commits   []CommitSummary   `json:"commits"`
+}
+
+// SnapshotSummary describes one snapshot without its content.
+type SnapshotSummary struct {
+	Created string `json:"created"`
+	Title   string `json:"title"`
+	Lines   int    `json:"lines"`
+}
+
+// CommitSummary describes one commit without its line content.
+type CommitSummary struct {
+	ID       string `json:"id"`
+	Created  string `json:"created"`
+	UserID   string `json:"user_id"`
+	Inserted int    `json:"inserted"`
+	Updated  int    `json:"updated"`
+	Deleted  int    `json:"deleted"`
+}
+
+// Version is the content of a page at some point in time.
+type Version struct {
+	Title string `json:"title"`
+	// At is when this version was saved.
+	At string `json:"at"`
+	// Source is "current" for the live page or "snapshot" for a saved snapshot.
+	Source string   `json:"source"`
+	Lines  []string `json:"lines"`
+}
+
+// VersionDiff is the line-level difference between two versions of a page.
+type VersionDiff struct {
+	Title string `json:"title"`
+	// From and To are the times of the compared versions.
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	// Diff is the difference in unified diff format.
+	Diff string `json:"diff"`
+}
+
+// GetHistory lists the snapshots and commits of the page titled title,
+// newest first.
+func GetHistory(ctx context.Context, src Source, title string) (*PageHistory, error) {
+	page, err := src.GetPage(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := src.GetPageSnapshots(ctx, page.ID)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := src.GetPageCommits(ctx, page.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &PageHistory{Title: page.Title, PageID: page.ID, Snapshots: []SnapshotSummary{}, Commits: []CommitSummary{}}
+	if page.Updated != 0 {
+		h.Updated = formatTime(page.Updated)
+	}
+	snaps := sortedSnapshots(snapshots.Snapshots)
+	for i := len(snaps) - 1; i >= 0; i-- {
+		s := snaps[i]
+		h.Snapshots = append(h.Snapshots, SnapshotSummary{Created: formatTime(s.Created), Title: s.Title, Lines: len(s.Lines)})
+	}
+	cs := append([]scrapbox.Commit(nil), commits.Commits...)
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].Created > cs[j].Created })
+	for _, c := range cs {
+		sum := CommitSummary{ID: c.ID, Created: formatTime(c.Created), UserID: c.UserID}
+		for _, ch := range c.Changes {
+			switch {
+			case ch.Insert != "":
+				sum.Inserted++
+			case ch.Update != "":
+				sum.Updated++
+			case ch.Delete != "":
+				sum.Deleted++
+			}
+		}
+		h.Commits = append(h.Commits, sum)
+	}
+	return h, nil
+}
+
+// PageAt returns the page titled title as it was at time at: the live page
+// if it has not changed since, otherwise the newest snapshot taken at or
+// before at.
+func PageAt(ctx context.Context, src Source, title string, at time.Time) (*Version, error) {
+	page, err := src.GetPage(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	return versionAt(ctx, src, page, at)
+}
+
+// DiffVersions compares the page titled title at from with the page at to.
+// A zero to compares against the live page.
+func DiffVersions(ctx context.Context, src Source, title string, from, to time.Time) (*VersionDiff, error) {
+	page, err := src.GetPage(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	a, err := versionAt(ctx, src, page, from)
+	if err != nil {
+		return nil, err
+	}
+	b := currentVersion(page)
+	if !to.IsZero() {
+		if b, err = versionAt(ctx, src, page, to); err != nil {
+			return nil, err
+		}
+	}
+
+	ops := diffLines(a.Lines, b.Lines)
+	d := &VersionDiff{
+		Title: page.Title,
+		From:  a.At,
+		To:    b.At,
+		Diff:  unified(ops, page.Title+"@"+a.At, page.Title+"@"+b.At, 3),
+	}
+	for _, op := range ops {
+		switch op.kind {
+		case opInsert:
+			d.Added++
+		case opDelete:
+			d.Removed++
+		}
+	}
+	return d, nil
+}
+
+func versionAt(ctx context.Context, src Source, page *scrapbox.Page, at time.Time) (*Version, error) {
+	if page.Updated != 0 && page.Updated <= at.Unix() {
+		return currentVersion(page), nil
+	}
+	list, err := src.GetPageSnapshots(ctx, page.ID)
+	if err != nil {
+		return nil, err
+	}
+	snaps := sortedSnapshots(list.Snapshots)
+	i := sort.Search(len(snaps), func(i int) bool { return snaps[i].Created > at.Unix() })
+	if i == 0 {
+		return nil, fmt.Errorf("no version of %q exists at or before %s", page.Title, at.UTC().Format(time.RFC3339))
+	}
+	s := snaps[i-1]
+	v := &Version{Title: s.Title, At: formatTime(s.Created), Source: "snapshot"}
+	for _, l := range s.Lines {
+		v.Lines = append(v.Lines, l.Text)
+	}
+	return v, nil
+}
+
+func currentVersion(page *scrapbox.Page) *Version {
+	v := &Version{Title: page.Title, At: formatTime(page.Updated), Source: "current"}
+	for _, l := range page.Lines {
+		v.Lines = append(v.Lines, l.Text)
+	}
+	return v
+}
+
+func sortedSnapshots(snaps []scrapbox.PageSnapshot) []scrapbox.PageSnapshot {
+	sorted := append([]scrapbox.PageSnapshot(nil), snaps...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Created < sorted[j].Created })
+	return sorted
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+// ParseTime parses a tool time argument. It accepts RFC 3339 timestamps,
+// dates ("2006-01-02", interpreted as UTC midnight) and Unix seconds.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 (2006-01-02T15:04:05Z), a date (2006-01-02) or Unix seconds", s)
+}
@@ -0,0 +1,492 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+type fakeSource struct {
+	page      *scrapbox.Page
+	project   *scrapbox.Project
+	snapshots []scrapbox.PageSnapshot
+	commits   []scrapbox.Commit
+}
+
+func (f *fakeSource) GetProject(ctx context.Context) (*scrapbox.Project, error) {
+	if f.project == nil {
+		return nil, errors.New("forbidden")
+	}
+	return f.project, nil
+}
+
+func (f *fakeSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	return f.page, nil
+}
+
+func (f *fakeSource) GetPageSnapshots(ctx context.Context, pageID string) (*scrapbox.PageSnapshotList, error) {
+	return &scrapbox.PageSnapshotList{PageID: pageID, Snapshots: f.snapshots}, nil
+}
+
+func (f *fakeSource) GetPageCommits(ctx context.Context, pageID string) (*scrapbox.CommitList, error) {
+	return &scrapbox.CommitList{Commits: f.commits}, nil
+}
+
+func lines(texts ...string) []scrapbox.Line {
+	var ls []scrapbox.Line
+	for _, t := range texts {
+		ls = append(ls, scrapbox.Line{Text: t})
+	}
+	return ls
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		page: &scrapbox.Page{ID: "p1", Title: "Page", Updated: 300, Lines: lines("Page", "a", "B", "c", "d")},
+		snapshots: []scrapbox.PageSnapshot{
+			{Title: "Page", Created: 200, Lines: lines("Page", "a", "b", "c")},
+			{Title: "Page", Created: 100, Lines: lines("Page", "a")},
+		},
+		commits: []scrapbox.Commit{
+			{ID: "c1", Created: 100, UserID: "u1", Changes: []scrapbox.Change{{Insert: "x"}}},
+			{ID: "c2", Created: 300, UserID: "u2", Changes: []scrapbox.Change{{Update: "x"}, {Insert: "y"}, {Delete: "z"}}},
+		},
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	got, err := GetHistory(context.Background(), newFakeSource(), "Page")
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	want := &PageHistory{
+		Title:   "Page",
+		PageID:  "p1",
+		Updated: "1970-01-01T00:05:00Z",
+		Snapshots: []SnapshotSummary{
+			{Created: "1970-01-01T00:03:20Z", Title: "Page", Lines: 4},
+			{Created: "1970-01-01T00:01:40Z", Title: "Page", Lines: 2},
+		},
+		Commits: []CommitSummary{
+			{ID: "c2", Created: "1970-01-01T00:05:00Z", UserID: "u2", Inserted: 1, Updated: 1, Deleted: 1},
+			{ID: "c1", Created: "1970-01-01T00:01:40Z", UserID: "u1", Inserted: 1},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetHistory() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPageAt(t *testing.T) {
+	tests := map[string]struct {
+		at      int64
+		want    *Version
+		wantErr bool
+	}{
+		"after last update returns current page": {
+			at:   400,
+			want: &Version{Title: "Page", At: "1970-01-01T00:05:00Z", Source: "current", Lines: []string{"Page", "a", "B", "c", "d"}},
+		},
+		"between snapshots returns older snapshot": {
+			at:   150,
+			want: &Version{Title: "Page", At: "1970-01-01T00:01:40Z", Source: "snapshot", Lines: []string{"Page", "a"}},
+		},
+		"exactly at snapshot time": {
+			at:   200,
+			want: &Version{Title: "Page", At: "1970-01-01T00:03:20Z", Source: "snapshot", Lines: []string{"Page", "a", "b", "c"}},
+		},
+		"before first snapshot": {
+			at:      50,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := PageAt(context.Background(), newFakeSource(), "Page", time.Unix(tt.at, 0))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PageAt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("PageAt() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	tests := map[string]struct {
+		from, to    int64
+		wantAdded   int
+		wantRemoved int
+		wantDiff    string
+	}{
+		"snapshot to current": {
+			from:        200,
+			wantAdded:   2,
+			wantRemoved: 1,
+			wantDiff: "--- Page@1970-01-01T00:03:20Z\n+++ Page@1970-01-01T00:05:00Z\n" +
+				"@@ -1,4 +1,5 @@\n Page\n a\n-b\n+B\n c\n+d\n",
+		},
+		"snapshot to snapshot": {
+			from:      100,
+			to:        200,
+			wantAdded: 2,
+			wantDiff: "--- Page@1970-01-01T00:01:40Z\n+++ Page@1970-01-01T00:03:20Z\n" +
+				"@@ -1,2 +1,4 @@\n Page\n a\n+b\n+c\n",
+		},
+		"same version": {
+			from: 200,
+			to:   250,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var to time.Time
+			if tt.to != 0 {
+				to = time.Unix(tt.to, 0)
+			}
+			got, err := DiffVersions(context.Background(), newFakeSource(), "Page", time.Unix(tt.from, 0), to)
+			if err != nil {
+				t.Fatalf("DiffVersions() error = %v", err)
+			}
+			if got.Added != tt.wantAdded || got.Removed != tt.wantRemoved {
+				t.Errorf("DiffVersions() added/removed = %d/%d, want %d/%d", got.Added, got.Removed, tt.wantAdded, tt.wantRemoved)
+			}
+			if diff := cmp.Diff(tt.wantDiff, got.Diff); diff != "" {
+				t.Errorf("DiffVersions() diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnified_SeparateHunks(t *testing.T) {
+	var a []string
+	for i := 0; i < 20; i++ {
+		a = append(a, string(rune('a'+i)))
+	}
+	b := append([]string(nil), a...)
+	b[1] = "X"
+	b[18] = "Y"
+
+	got := unified(diffLines(a, b), "a", "b", 2)
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Errorf("unified() produced %d hunks, want 2:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,4 +1,4 @@\n a\n-b\n+X\n c\n d\n") {
+		t.Errorf("unified() first hunk mismatch:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -17,4 +17,4 @@\n q\n r\n-s\n+Y\n t\n") {
+		t.Errorf("unified() second hunk mismatch:\n%s", got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// lcs returns the length of the longest common subsequence of a and b.
+	lcs := func(a, b []string) int {
+		prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+		for i := range a {
+			for j := range b {
+				if a[i] == b[j] {
+					cur[j+1] = prev[j] + 1
+				} else {
+					cur[j+1] = max(prev[j+1], cur[j])
+				}
+			}
+			prev, cur = cur, prev
+		}
+		return prev[len(b)]
+	}
+	lines := func(r *rand.Rand, n, alphabet int) []string {
+		var l []string
+		for range n {
+			l = append(l, strconv.Itoa(r.IntN(alphabet)))
+		}
+		return l
+	}
+	check := func(t *testing.T, a, b []string, wantEdits int) {
+		t.Helper()
+		var gotA, gotB []string
+		edits := 0
+		for _, op := range diffLines(a, b) {
+			if op.kind != opInsert {
+				if op.a != len(gotA) {
+					t.Fatalf("op %+v at old line %d", op, len(gotA))
+				}
+				gotA = append(gotA, op.text)
+			}
+			if op.kind != opDelete {
+				if op.b != len(gotB) {
+					t.Fatalf("op %+v at new line %d", op, len(gotB))
+				}
+				gotB = append(gotB, op.text)
+			}
+			if op.kind != opEqual {
+				edits++
+			}
+		}
+		if !slices.Equal(a, gotA) || !slices.Equal(b, gotB) {
+			t.Fatalf("diffLines(%v, %v) does not rebuild both texts", a, b)
+		}
+		if wantEdits >= 0 && edits != wantEdits {
+			t.Fatalf("diffLines(%v, %v) has %d edits, want %d", a, b, edits, wantEdits)
+		}
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 500 {
+		a, b := lines(r, r.IntN(30), 4), lines(r, r.IntN(30), 4)
+		check(t, a, b, len(a)+len(b)-2*lcs(a, b))
+	}
+
+	// Two heavily rewritten large versions.
+	a, b := lines(r, 5000, 1000), lines(r, 5000, 1000)
+	start := time.Now()
+	check(t, a, b, -1)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("diffLines() of 5000 rewritten lines took %v", elapsed)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		"rfc3339": {
+			input: "2024-05-01T10:00:00+09:00",
+			want:  time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC),
+		},
+		"date": {
+			input: "2024-05-01",
+			want:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"unix seconds": {
+			input: "1714521600",
+			want:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"invalid": {
+			input:   "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseTime(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlame(t *testing.T) {
+	page := &scrapbox.Page{
+		ID:    "p1",
+		Title: "Spec",
+		User:  &scrapbox.User{ID: "u1", Name: "alice", DisplayName: "Alice"},
+		Lines: []scrapbox.Line{
+			{Text: "Spec", UserID: "u1", Updated: 1000},
+			{Text: "intro", UserID: "u1", Updated: 1100},
+			{Text: "new rule", UserID: "u2", Updated: 5000},
+			{Text: "another rule", UserID: "u2", Updated: 5060},
+			{Text: "much later", UserID: "u2", Updated: 9000},
+			{Text: "footer", UserID: "u1", Updated: 1200},
+		},
+	}
+	members := &scrapbox.Project{Users: []scrapbox.User{{ID: "u2", Name: "bob", DisplayName: "Bob"}}}
+
+	tests := map[string]struct {
+		project *scrapbox.Project
+		since   int64
+		want    *PageBlame
+	}{
+		"groups by author and session": {
+			project: members,
+			want: &PageBlame{
+				Title: "Spec",
+				Authors: []AuthorSummary{
+					{Author: "Alice", UserID: "u1", Lines: 3, LastEdit: "1970-01-01T00:20:00Z"},
+					{Author: "Bob", UserID: "u2", Lines: 3, LastEdit: "1970-01-01T02:30:00Z"},
+				},
+				Hunks: []BlameHunk{
+					{Author: "Alice", UserID: "u1", StartLine: 1, EndLine: 2, From: "1970-01-01T00:16:40Z", To: "1970-01-01T00:18:20Z", Lines: []string{"Spec", "intro"}},
+					{Author: "Bob", UserID: "u2", StartLine: 3, EndLine: 4, From: "1970-01-01T01:23:20Z", To: "1970-01-01T01:24:20Z", Lines: []string{"new rule", "another rule"}},
+					{Author: "Bob", UserID: "u2", StartLine: 5, EndLine: 5, From: "1970-01-01T02:30:00Z", To: "1970-01-01T02:30:00Z", Lines: []string{"much later"}},
+					{Author: "Alice", UserID: "u1", StartLine: 6, EndLine: 6, From: "1970-01-01T00:20:00Z", To: "1970-01-01T00:20:00Z", Lines: []string{"footer"}},
+				},
+			},
+		},
+		"since filter without members": {
+			since: 5000,
+			want: &PageBlame{
+				Title: "Spec",
+				Since: "1970-01-01T01:23:20Z",
+				Authors: []AuthorSummary{
+					{Author: "u2", UserID: "u2", Lines: 3, LastEdit: "1970-01-01T02:30:00Z"},
+				},
+				Hunks: []BlameHunk{
+					{Author: "u2", UserID: "u2", StartLine: 3, EndLine: 4, From: "1970-01-01T01:23:20Z", To: "1970-01-01T01:24:20Z", Lines: []string{"new rule", "another rule"}},
+					{Author: "u2", UserID: "u2", StartLine: 5, EndLine: 5, From: "1970-01-01T02:30:00Z", To: "1970-01-01T02:30:00Z", Lines: []string{"much later"}},
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			src := &fakeSource{page: page, project: tt.project}
+			var since time.Time
+			if tt.since != 0 {
+				since = time.Unix(tt.since, 0)
+			}
+			got, err := Blame(context.Background(), src, "Spec", since)
+			if err != nil {
+				t.Fatalf("Blame() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Blame() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type fakeRecentSource struct {
+	pages   []*scrapbox.Page
+	fetched []string
+	// gone are the titles of pages listed but deleted before GetPage.
+	gone []string
+}
+
+func (f *fakeRecentSource) ListPagesUpdatedSince(ctx context.Context, since time.Time) ([]scrapbox.Page, error) {
+	var list []scrapbox.Page
+	for _, p := range f.pages {
+		if p.Updated > since.Unix() {
+			list = append(list, scrapbox.Page{Title: p.Title, Updated: p.Updated})
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeRecentSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	f.fetched = append(f.fetched, title)
+	for _, p := range f.pages {
+		if p.Title == title && !slices.Contains(f.gone, title) {
+			return p, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeRecentSource) GetProject(ctx context.Context) (*scrapbox.Project, error) {
+	return &scrapbox.Project{Users: []scrapbox.User{{ID: "u1", DisplayName: "Alice"}, {ID: "u2", DisplayName: "Bob"}}}, nil
+}
+
+func TestRecentChanges(t *testing.T) {
+	newSource := func() *fakeRecentSource {
+		return &fakeRecentSource{pages: []*scrapbox.Page{
+			{Title: "New", Updated: 900, Lines: []scrapbox.Line{
+				{Text: "New", UserID: "u2", Updated: 900},
+				{Text: "hello", UserID: "u2", Updated: 900},
+			}},
+			{Title: "Edited", Updated: 800, Lines: []scrapbox.Line{
+				{Text: "Edited", UserID: "u1", Updated: 100},
+				{Text: "changed", UserID: "u1", Updated: 700},
+				{Text: "old", UserID: "u1", Updated: 100},
+				{Text: "added", UserID: "u2", Updated: 800},
+			}},
+			{Title: "Old", Updated: 100, Lines: []scrapbox.Line{{Text: "Old", UserID: "u1", Updated: 100}}},
+		}}
+	}
+
+	tests := map[string]struct {
+		maxChars    int
+		gone        []string
+		want        *Activity
+		wantFetched []string
+		wantErr     bool
+	}{
+		"all changes": {
+			want: &Activity{
+				Since: "1970-01-01T00:08:20Z",
+				Pages: []PageChanges{
+					{Title: "New", Updated: "1970-01-01T00:15:00Z", Authors: []AuthorChanges{
+						{Author: "Bob", UserID: "u2", Lines: []ChangedLine{
+							{Line: 1, Text: "New", Updated: "1970-01-01T00:15:00Z"},
+							{Line: 2, Text: "hello", Updated: "1970-01-01T00:15:00Z"},
+						}},
+					}},
+					{Title: "Edited", Updated: "1970-01-01T00:13:20Z", Authors: []AuthorChanges{
+						{Author: "Alice", UserID: "u1", Lines: []ChangedLine{{Line: 2, Text: "changed", Updated: "1970-01-01T00:11:40Z"}}},
+						{Author: "Bob", UserID: "u2", Lines: []ChangedLine{{Line: 4, Text: "added", Updated: "1970-01-01T00:13:20Z"}}},
+					}},
+				},
+			},
+			wantFetched: []string{"New", "Edited"},
+		},
+		"capped output": {
+			maxChars: 6,
+			want: &Activity{
+				Since: "1970-01-01T00:08:20Z",
+				Pages: []PageChanges{
+					{Title: "New", Updated: "1970-01-01T00:15:00Z", Authors: []AuthorChanges{
+						{Author: "Bob", UserID: "u2", Lines: []ChangedLine{{Line: 1, Text: "New", Updated: "1970-01-01T00:15:00Z"}}},
+					}},
+				},
+				Truncated:    true,
+				OmittedPages: 1,
+			},
+			wantFetched: []string{"New"},
+		},
+		"page deleted after listing": {
+			gone: []string{"New"},
+			want: &Activity{
+				Since: "1970-01-01T00:08:20Z",
+				Pages: []PageChanges{
+					{Title: "Edited", Updated: "1970-01-01T00:13:20Z", Authors: []AuthorChanges{
+						{Author: "Alice", UserID: "u1", Lines: []ChangedLine{{Line: 2, Text: "changed", Updated: "1970-01-01T00:11:40Z"}}},
+						{Author: "Bob", UserID: "u2", Lines: []ChangedLine{{Line: 4, Text: "added", Updated: "1970-01-01T00:13:20Z"}}},
+					}},
+				},
+				Skipped: []SkippedPage{{Title: "New", Error: "not found"}},
+			},
+			wantFetched: []string{"New", "Edited"},
+		},
+		"err: every page failed": {
+			gone:        []string{"New", "Edited"},
+			wantFetched: []string{"New", "Edited"},
+			wantErr:     true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			src := newSource()
+			src.gone = tt.gone
+			got, err := RecentChanges(context.Background(), src, time.Unix(500, 0), tt.maxChars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecentChanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("RecentChanges() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantFetched, src.fetched); diff != "" {
+				t.Errorf("RecentChanges() fetched pages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
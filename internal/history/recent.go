@@ -0,0 +1,126 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// RecentSource is the subset of scrapbox.Client used to build an activity feed.
+type RecentSource interface {
+	ListPagesUpdatedSince(ctx context.Context, since time.Time) ([]scrapbox.Page, error)
+	GetPage(ctx context.Context, title string) (*scrapbox.Page, error)
+	GetProject(ctx context.Context) (*scrapbox.Project, error)
+}
+
+// Activity lists the pages changed since a point in time.
+type Activity struct {
+	Since string        `json:"since"`
+	Pages []PageChanges `json:"pages"`
+	// Truncated is set when MaxChars was reached; OmittedPages pages
+	// updated since then are left out entirely.
+	Truncated    bool `json:"truncated,omitempty"`
+	OmittedPages int  `json:"omitted_pages,omitempty"`
+	// Skipped lists the pages that could not be read.
+	Skipped []SkippedPage `json:"skipped,omitempty"`
+}
+
+// SkippedPage is a page updated since then that could not be read, such as
+// a page deleted or denied after the pages were listed.
+type SkippedPage struct {
+	Title string `json:"title"`
+	Error string `json:"error"`
+}
+
+// PageChanges holds the lines of one page added or modified since then,
+// grouped by the author of the change.
+type PageChanges struct {
+	Title   string          `json:"title"`
+	Updated string          `json:"updated"`
+	Authors []AuthorChanges `json:"authors"`
+}
+
+// AuthorChanges holds the lines one author changed on a page.
+type AuthorChanges struct {
+	Author string        `json:"author"`
+	UserID string        `json:"user_id,omitempty"`
+	Lines  []ChangedLine `json:"lines"`
+}
+
+// ChangedLine is a line added or modified since then.
+type ChangedLine struct {
+	// Line is the 1-based line number; the title is line 1.
+	Line    int    `json:"line"`
+	Text    string `json:"text"`
+	Updated string `json:"updated"`
+}
+
+// RecentChanges lists the pages updated after since, most recent first, with
+// the lines each author added or modified after since. Pages whose changes
+// were deletions only appear with no authors.
+//
+// A page that cannot be read is reported in Skipped without affecting the
+// others; an error is returned only if every page fails.
+//
+// A positive maxChars caps the total length of the line text returned. Once
+// it is reached the current page is cut short and the remaining pages are
+// only counted, without being fetched.
+func RecentChanges(ctx context.Context, src RecentSource, since time.Time, maxChars int) (*Activity, error) {
+	pages, err := src.ListPagesUpdatedSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	names := memberNames(ctx, src)
+
+	a := &Activity{Since: since.UTC().Format(time.RFC3339), Pages: []PageChanges{}}
+	used := 0
+	var errs []error
+	for i, meta := range pages {
+		if maxChars > 0 && used >= maxChars {
+			a.Truncated = true
+			a.OmittedPages = len(pages) - i
+			break
+		}
+		page, err := src.GetPage(ctx, meta.Title)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			a.Skipped = append(a.Skipped, SkippedPage{Title: meta.Title, Error: err.Error()})
+			errs = append(errs, err)
+			continue
+		}
+		addPageUsers(names, page)
+
+		pc := PageChanges{Title: page.Title, Updated: formatTime(meta.Updated), Authors: []AuthorChanges{}}
+		byAuthor := map[string]int{}
+		for n, l := range page.Lines {
+			if l.Updated <= since.Unix() {
+				continue
+			}
+			if maxChars > 0 && used+len(l.Text) > maxChars {
+				a.Truncated = true
+				used = maxChars
+				break
+			}
+			used += len(l.Text)
+			idx, ok := byAuthor[l.UserID]
+			if !ok {
+				idx = len(pc.Authors)
+				byAuthor[l.UserID] = idx
+				pc.Authors = append(pc.Authors, AuthorChanges{Author: displayName(names, l.UserID), UserID: l.UserID})
+			}
+			pc.Authors[idx].Lines = append(pc.Authors[idx].Lines, ChangedLine{Line: n + 1, Text: l.Text, Updated: formatTime(l.Updated)})
+		}
+		sort.SliceStable(pc.Authors, func(i, j int) bool { return len(pc.Authors[i].Lines) > len(pc.Authors[j].Lines) })
+		a.Pages = append(a.Pages, pc)
+	}
+	if len(a.Pages) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("failed to read every page updated since then: %w", errors.Join(errs...))
+	}
+	return a, nil
+}
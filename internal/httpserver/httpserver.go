@@ -0,0 +1,181 @@
+// Package httpserver runs the HTTP mode of the MCP servers.
+//
+// The MCP endpoints are provided by the framework adapters; this package adds
+// what every deployment needs around them: a /healthz endpoint, expvar
+// metrics such as the redaction counts on /debug/vars, a limit on request
+// bodies, optional authentication and graceful shutdown. On shutdown
+// the server stops accepting connections, ends the long-lived GET streams of
+// the sessions and waits up to ShutdownTimeout for the requests in flight to
+// finish.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/auth"
+	"github.com/takak2166/scrapbox-mcp/internal/tenant"
+)
+
+const (
+	// HealthPath is the path of the health check endpoint.
+	HealthPath = "/healthz"
+	// MetricsPath is the path of the expvar metrics endpoint.
+	MetricsPath = "/debug/vars"
+
+	// DefaultMaxBodyBytes is the request body limit when MaxBodyBytes is not set.
+	DefaultMaxBodyBytes = 1 << 20
+	// DefaultShutdownTimeout is how long shutdown waits for requests in flight
+	// when ShutdownTimeout is not set.
+	DefaultShutdownTimeout = 10 * time.Second
+
+	readHeaderTimeout = 10 * time.Second
+)
+
+// Server serves MCP endpoints over HTTP.
+type Server struct {
+	// Addr is the TCP address to listen on, e.g. ":8080".
+	Addr string
+	// Handlers maps http.ServeMux patterns to the MCP endpoint handlers.
+	Handlers map[string]http.Handler
+	// MaxBodyBytes limits the size of request bodies.
+	MaxBodyBytes int64
+	// ShutdownTimeout bounds the wait for requests in flight on shutdown.
+	ShutdownTimeout time.Duration
+	// Auth, if set, authenticates the requests to Handlers and the metrics,
+	// and serves the protected resource metadata. The health check stays
+	// public.
+	Auth *auth.Authenticator
+	// Tenants, if set, resolves the Scrapbox credentials of the requests to
+	// Handlers in multi-tenant mode, after authentication.
+	Tenants *tenant.Manager
+
+	// maxBody overrides MaxBodyBytes once set by SetMaxBodyBytes.
+	maxBody atomic.Int64
+}
+
+// SetMaxBodyBytes changes the request body limit of a running server; n <= 0
+// selects DefaultMaxBodyBytes.
+func (s *Server) SetMaxBodyBytes(n int64) {
+	if n <= 0 {
+		n = DefaultMaxBodyBytes
+	}
+	s.maxBody.Store(n)
+}
+
+// maxBodyBytes returns the request body limit in effect.
+func (s *Server) maxBodyBytes() int64 {
+	if n := s.maxBody.Load(); n > 0 {
+		return n
+	}
+	if s.MaxBodyBytes > 0 {
+		return s.MaxBodyBytes
+	}
+	return DefaultMaxBodyBytes
+}
+
+// ListenAndServe listens on s.Addr and serves until ctx is canceled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.Addr, err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves on ln until ctx is canceled, then shuts down gracefully. It
+// returns nil after a clean shutdown.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	streams, endStreams := context.WithCancel(context.Background())
+	defer endStreams()
+	srv := &http.Server{
+		Handler:           s.Handler(streams),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	endStreams()
+	timeout := s.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Handler returns the handler of the server. GET requests, which open the
+// event streams of MCP sessions, are canceled when streams is done.
+func (s *Server) Handler(streams context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	})
+	metrics := expvar.Handler()
+	if s.Auth != nil {
+		mux.Handle(auth.MetadataPath, s.Auth.MetadataHandler())
+		metrics = s.Auth.Middleware(metrics)
+	}
+	mux.Handle("GET "+MetricsPath, metrics)
+	for pattern, h := range s.Handlers {
+		h = endWith(streams, h)
+		if s.Tenants != nil {
+			h = s.Tenants.Middleware(h)
+		}
+		if s.Auth != nil {
+			h = s.Auth.Middleware(h)
+		}
+		mux.Handle(pattern, limitBody(h, s.maxBodyBytes))
+	}
+	return mux
+}
+
+// limitBody rejects request bodies larger than the limit returned by
+// maxBytes.
+func limitBody(next http.Handler, maxBytes func() int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		max := maxBytes()
+		if r.ContentLength > max {
+			http.Error(w, fmt.Sprintf("request body exceeds %d bytes", max), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// endWith cancels GET requests when streams is done.
+func endWith(streams context.Context, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		stop := context.AfterFunc(streams, cancel)
+		defer stop()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,164 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_Handler(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(body)
+	})
+	s := &Server{Handlers: map[string]http.Handler{"/mcp": echo}, MaxBodyBytes: 8}
+	h := s.Handler(context.Background())
+
+	tests := map[string]struct {
+		method     string
+		path       string
+		body       string
+		chunked    bool
+		wantStatus int
+		wantBody   string
+	}{
+		"health check": {
+			method:     http.MethodGet,
+			path:       HealthPath,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"ok"}`,
+		},
+		"metrics": {
+			method:     http.MethodGet,
+			path:       MetricsPath,
+			wantStatus: http.StatusOK,
+		},
+		"body within the limit": {
+			method:     http.MethodPost,
+			path:       "/mcp",
+			body:       "12345678",
+			wantStatus: http.StatusOK,
+			wantBody:   "12345678",
+		},
+		"body over the limit": {
+			method:     http.MethodPost,
+			path:       "/mcp",
+			body:       "123456789",
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		"chunked body over the limit": {
+			method:     http.MethodPost,
+			path:       "/mcp",
+			body:       "123456789",
+			chunked:    true,
+			wantStatus: http.StatusBadRequest,
+		},
+		"unknown path": {
+			method:     http.MethodGet,
+			path:       "/nope",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	// The limit can be raised while the server runs.
+	s.SetMaxBodyBytes(16)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader("123456789")))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status after SetMaxBodyBytes = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestServer_Serve(t *testing.T) {
+	streamStarted := make(chan struct{})
+	postStarted := make(chan struct{})
+	releasePost := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			// A session stream only ends when its request is canceled.
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			close(streamStarted)
+			<-r.Context().Done()
+			return
+		}
+		close(postStarted)
+		<-releasePost
+		_, _ = w.Write([]byte("done"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + ln.Addr().String() + "/mcp"
+	s := &Server{Handlers: map[string]http.Handler{"/mcp": h}, ShutdownTimeout: 5 * time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, ln) }()
+
+	stream, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer stream.Body.Close()
+	<-streamStarted
+
+	post := make(chan string, 1)
+	go func() {
+		resp, err := http.Post(url, "application/json", strings.NewReader("{}"))
+		if err != nil {
+			post <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		post <- string(b)
+	}()
+	<-postStarted
+
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("Serve() returned %v before the request in flight finished", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(releasePost)
+
+	if got := <-post; got != "done" {
+		t.Errorf("request in flight got %q, want done", got)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after shutdown")
+	}
+}
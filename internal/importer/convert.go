@@ -0,0 +1,329 @@
+// Package importer converts Markdown and Obsidian notes to Scrapbox pages in
+// the JSON format accepted by the project import API.
+package importer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Warning describes something that could not be converted faithfully.
+type Warning struct {
+	// Line is the 1-based line number in the source file, or 0 for warnings
+	// about the file as a whole.
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Line == 0 {
+		return w.Message
+	}
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+var (
+	headingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	listRe     = regexp.MustCompile(`^([ \t]*)([-*+]|\d+[.)])\s+(.*)$`)
+	fenceRe    = regexp.MustCompile("^[ \t]*(```+|~~~+)\\s*(.*)$")
+	tableSepRe = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	ruleRe     = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	htmlTagRe  = regexp.MustCompile(`^</?[a-zA-Z][^>]*>`)
+	footnoteRe = regexp.MustCompile(`^\[\^[^\]]+\]`)
+)
+
+// indentWidth is the number of spaces that make up one Markdown nesting level.
+const indentWidth = 2
+
+// headingMarks maps Markdown heading levels to Scrapbox decoration sizes.
+var headingMarks = map[int]string{1: "****", 2: "***", 3: "**"}
+
+// converter turns Markdown lines into Scrapbox lines while collecting warnings.
+type converter struct {
+	warnings []Warning
+	lineNo   int
+}
+
+func (c *converter) warn(format string, args ...any) {
+	c.warnings = append(c.warnings, Warning{Line: c.lineNo, Message: fmt.Sprintf(format, args...)})
+}
+
+// closesFence reports whether line closes a code block opened by fence: as
+// in CommonMark, it must hold nothing but the fence character, repeated at
+// least as often as in fence.
+func closesFence(line, fence string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// convertBody converts Markdown body lines to Scrapbox lines. firstLine is
+// the source line number of lines[0], used in warnings.
+func (c *converter) convertBody(lines []string, firstLine int) []string {
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		c.lineNo = firstLine + i
+		line := strings.TrimRight(lines[i], " \t\r")
+
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			indent := listIndent(line)
+			name := strings.TrimSpace(m[2])
+			if name == "" {
+				name = "text"
+			}
+			out = append(out, strings.Repeat(" ", indent)+"code:"+name)
+			closed := false
+			for i++; i < len(lines); i++ {
+				c.lineNo = firstLine + i
+				if closesFence(lines[i], m[1]) {
+					closed = true
+					break
+				}
+				out = append(out, strings.Repeat(" ", indent+1)+strings.TrimRight(lines[i], "\r"))
+			}
+			if !closed {
+				c.warn("unterminated code fence")
+			}
+			continue
+		}
+
+		if isTableRow(line) && i+1 < len(lines) && tableSepRe.MatchString(lines[i+1]) {
+			out = append(out, "table:table")
+			out = append(out, " "+c.convertRow(line))
+			for i += 2; i < len(lines) && isTableRow(lines[i]); i++ {
+				c.lineNo = firstLine + i
+				out = append(out, " "+c.convertRow(lines[i]))
+			}
+			i--
+			continue
+		}
+
+		switch {
+		case line == "":
+			out = append(out, "")
+		case ruleRe.MatchString(line):
+			c.warn("horizontal rule dropped")
+			out = append(out, "")
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			marks, ok := headingMarks[len(m[1])]
+			if !ok {
+				marks = "*"
+			}
+			out = append(out, "["+marks+" "+c.convertInline(m[2])+"]")
+		case listRe.MatchString(line):
+			m := listRe.FindStringSubmatch(line)
+			depth := indentDepth(m[1])
+			text := c.convertInline(m[3])
+			if m[2] != "-" && m[2] != "*" && m[2] != "+" {
+				text = m[2] + " " + text
+			}
+			out = append(out, strings.Repeat(" ", depth+1)+text)
+		case strings.HasPrefix(strings.TrimLeft(line, " \t"), ">"):
+			text := strings.TrimPrefix(strings.TrimLeft(line, " \t"), ">")
+			text = strings.TrimPrefix(text, " ")
+			if strings.HasPrefix(text, "[!") {
+				c.warn("callout converted to a plain quote")
+			}
+			out = append(out, "> "+c.convertInline(text))
+		default:
+			depth := indentDepth(line[:len(line)-len(strings.TrimLeft(line, " \t"))])
+			out = append(out, strings.Repeat(" ", depth)+c.convertInline(strings.TrimLeft(line, " \t")))
+		}
+	}
+	return trimBlankLines(out)
+}
+
+// listIndent returns the Scrapbox indentation for a block that starts at the
+// Markdown indentation of line.
+func listIndent(line string) int {
+	return indentDepth(line[:len(line)-len(strings.TrimLeft(line, " \t"))])
+}
+
+// indentDepth converts leading Markdown whitespace to a nesting depth.
+func indentDepth(ws string) int {
+	width := 0
+	for _, r := range ws {
+		if r == '\t' {
+			width += indentWidth
+		} else {
+			width++
+		}
+	}
+	return width / indentWidth
+}
+
+func isTableRow(line string) bool {
+	t := strings.TrimSpace(line)
+	return strings.HasPrefix(t, "|") && strings.Count(t, "|") >= 2
+}
+
+// convertRow converts a Markdown table row to tab-separated Scrapbox cells.
+func (c *converter) convertRow(line string) string {
+	cells := splitTableRow(line)
+	for i, cell := range cells {
+		cells[i] = c.convertInline(cell)
+	}
+	return strings.Join(cells, "\t")
+}
+
+func splitTableRow(line string) []string {
+	t := strings.TrimSpace(line)
+	t = strings.TrimPrefix(strings.TrimSuffix(t, "|"), "|")
+	t = strings.ReplaceAll(t, `\|`, "\x00")
+	cells := strings.Split(t, "|")
+	for i, cell := range cells {
+		cells[i] = strings.ReplaceAll(strings.TrimSpace(cell), "\x00", "|")
+	}
+	return cells
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// convertInline converts Markdown inline syntax to Scrapbox notation.
+func (c *converter) convertInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1:
+			b.WriteByte(rest[1])
+			i += 2
+			continue
+		case rest[0] == '`':
+			n := len(rest) - len(strings.TrimLeft(rest, "`"))
+			fence := rest[:n]
+			if end := strings.Index(rest[n:], fence); end >= 0 {
+				b.WriteString("`" + strings.TrimSpace(rest[n:n+end]) + "`")
+				i += n + end + n
+				continue
+			}
+		case strings.HasPrefix(rest, "![["):
+			if end := strings.Index(rest, "]]"); end > 0 {
+				target := c.wikiTarget(rest[3:end])
+				c.warn("embed of %q converted to a link", target)
+				b.WriteString("[" + target + "]")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "[["):
+			if end := strings.Index(rest, "]]"); end > 0 {
+				target := c.wikiTarget(rest[2:end])
+				b.WriteString("[" + target + "]")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "!["):
+			if _, url, n, ok := mdLink(rest[1:]); ok {
+				b.WriteString("[" + url + "]")
+				i += n + 1
+				continue
+			}
+		case footnoteRe.MatchString(rest):
+			c.warn("footnote reference kept as text")
+		case rest[0] == '[':
+			if label, url, n, ok := mdLink(rest); ok {
+				b.WriteString(c.linkToScrapbox(label, url))
+				i += n
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				b.WriteString("[* " + c.convertInline(rest[2:2+end]) + "]")
+				i += end + 4
+				continue
+			}
+		case strings.HasPrefix(rest, "~~"):
+			if end := strings.Index(rest[2:], "~~"); end > 0 {
+				b.WriteString("[- " + c.convertInline(rest[2:2+end]) + "]")
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*' || (rest[0] == '_' && (i == 0 || s[i-1] == ' ')):
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 && rest[1] != ' ' {
+				b.WriteString("[/ " + c.convertInline(rest[1:1+end]) + "]")
+				i += end + 2
+				continue
+			}
+		case rest[0] == '$' && !strings.HasPrefix(rest, "$$"):
+			if end := strings.IndexByte(rest[1:], '$'); end > 0 {
+				b.WriteString("[$ " + rest[1:1+end] + "]")
+				i += end + 2
+				continue
+			}
+		case rest[0] == '<':
+			if m := htmlTagRe.FindString(rest); m != "" {
+				c.warn("HTML tag %s kept as text", m)
+				b.WriteString(m)
+				i += len(m)
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// wikiTarget returns the page title of an Obsidian wikilink body such as
+// "Page#Heading|Alias", warning about the parts Scrapbox cannot express.
+func (c *converter) wikiTarget(body string) string {
+	target, alias, hasAlias := strings.Cut(body, "|")
+	if hasAlias {
+		c.warn("alias %q of link to %q dropped", alias, target)
+	}
+	if t, heading, ok := strings.Cut(target, "#"); ok {
+		c.warn("heading %q of link to %q dropped", heading, t)
+		target = t
+	}
+	return strings.TrimSpace(target)
+}
+
+// linkToScrapbox converts a Markdown [label](url) link.
+func (c *converter) linkToScrapbox(label, url string) string {
+	switch {
+	case strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://"):
+		if label == "" || label == url {
+			return url
+		}
+		return "[" + c.convertInline(label) + " " + url + "]"
+	case strings.HasSuffix(strings.ToLower(url), ".md"):
+		// Relative link to another note of the vault.
+		name := strings.TrimSuffix(path.Base(url), path.Ext(url))
+		name = strings.ReplaceAll(name, "%20", " ")
+		return "[" + name + "]"
+	default:
+		c.warn("link to %q kept as text", url)
+		return label + " (" + url + ")"
+	}
+}
+
+// mdLink parses "[label](url)" at the start of s and reports its length.
+func mdLink(s string) (label, url string, n int, ok bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", "", 0, false
+	}
+	closeLabel := strings.Index(s, "](")
+	if closeLabel < 0 {
+		return "", "", 0, false
+	}
+	closeURL := strings.IndexByte(s[closeLabel+2:], ')')
+	if closeURL < 0 {
+		return "", "", 0, false
+	}
+	url = s[closeLabel+2 : closeLabel+2+closeURL]
+	if i := strings.IndexByte(url, ' '); i >= 0 {
+		// Drop an optional link title: [label](url "title").
+		url = url[:i]
+	}
+	return s[1:closeLabel], url, closeLabel + 2 + closeURL + 1, true
+}
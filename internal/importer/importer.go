@@ -0,0 +1,197 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"gopkg.in/yaml.v3"
+)
+
+// ImportData is the document accepted by the Scrapbox project import API.
+type ImportData struct {
+	Pages []scrapbox.Page `json:"pages"`
+}
+
+// FileReport describes the conversion of a single Markdown file.
+type FileReport struct {
+	Path     string    `json:"path"`
+	Title    string    `json:"title"`
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Result is the outcome of importing a directory.
+type Result struct {
+	Data  ImportData   `json:"data"`
+	Files []FileReport `json:"files"`
+}
+
+// frontMatter holds the front matter keys the importer understands.
+type frontMatter struct {
+	Title    string     `yaml:"title"`
+	Aliases  stringList `yaml:"aliases"`
+	Tags     stringList `yaml:"tags"`
+	Created  string     `yaml:"created"`
+	Date     string     `yaml:"date"`
+	Updated  string     `yaml:"updated"`
+	Modified string     `yaml:"modified"`
+}
+
+// stringList accepts either a YAML sequence or a single comma or space
+// separated string, both of which Obsidian writes for tags and aliases.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = strings.FieldsFunc(value.Value, func(r rune) bool { return r == ',' || r == ' ' })
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// timeLayouts are the date formats accepted in front matter.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ImportDir converts every .md file below dir. Hidden directories such as
+// .obsidian and .git are skipped.
+func ImportDir(dir string) (*Result, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".md") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+
+	result := &Result{Data: ImportData{Pages: []scrapbox.Page{}}}
+	titles := map[string]string{}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		rel, _ := filepath.Rel(dir, path)
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		page, warnings := Convert(name, src, info.ModTime())
+
+		key := strings.ToLower(strings.ReplaceAll(page.Title, " ", "_"))
+		if other, ok := titles[key]; ok {
+			warnings = append(warnings, Warning{Message: fmt.Sprintf("title %q is also used by %s; page skipped", page.Title, other)})
+		} else {
+			titles[key] = rel
+			result.Data.Pages = append(result.Data.Pages, *page)
+		}
+		result.Files = append(result.Files, FileReport{Path: rel, Title: page.Title, Warnings: warnings})
+	}
+	return result, nil
+}
+
+// Convert converts one Markdown document to a Scrapbox page. name is used as
+// the title unless the front matter sets one, and modTime is the fallback
+// for missing front matter dates.
+func Convert(name string, src []byte, modTime time.Time) (*scrapbox.Page, []Warning) {
+	c := &converter{}
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+
+	var fm frontMatter
+	bodyStart := 0
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if t := strings.TrimSpace(lines[i]); t == "---" || t == "..." {
+				raw := strings.Join(lines[1:i], "\n")
+				if err := yaml.NewDecoder(bytes.NewReader([]byte(raw))).Decode(&fm); err != nil && raw != "" {
+					c.warnings = append(c.warnings, Warning{Line: 1, Message: fmt.Sprintf("front matter ignored: %v", err)})
+					fm = frontMatter{}
+				}
+				bodyStart = i + 1
+				break
+			}
+		}
+	}
+
+	title := strings.TrimSpace(fm.Title)
+	if title == "" {
+		title = name
+	}
+	created := c.frontMatterTime("created", firstNonEmpty(fm.Created, fm.Date), modTime)
+	updated := c.frontMatterTime("updated", firstNonEmpty(fm.Updated, fm.Modified), modTime)
+	if updated < created {
+		updated = created
+	}
+	if len(fm.Aliases) > 0 {
+		c.warnings = append(c.warnings, Warning{Message: fmt.Sprintf("aliases %q dropped", fm.Aliases)})
+	}
+
+	body := c.convertBody(lines[bodyStart:], bodyStart+1)
+	// A leading "# Title" heading duplicates the page title.
+	if len(body) > 0 && body[0] == "[**** "+title+"]" {
+		body = trimBlankLines(body[1:])
+	}
+	if len(fm.Tags) > 0 {
+		tags := make([]string, len(fm.Tags))
+		for i, t := range fm.Tags {
+			tags[i] = "#" + strings.ReplaceAll(strings.TrimPrefix(t, "#"), " ", "_")
+		}
+		body = append(body, "", strings.Join(tags, " "))
+	}
+
+	page := &scrapbox.Page{Title: title, Created: created, Updated: updated}
+	for _, text := range append([]string{title}, body...) {
+		page.Lines = append(page.Lines, scrapbox.Line{Text: text, Created: created, Updated: updated})
+	}
+	return page, c.warnings
+}
+
+// frontMatterTime parses a front matter date, falling back to fallback.
+func (c *converter) frontMatterTime(key, value string, fallback time.Time) int64 {
+	if value == "" {
+		return fallback.Unix()
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Unix()
+		}
+	}
+	c.warnings = append(c.warnings, Warning{Line: 1, Message: fmt.Sprintf("unrecognised %s date %q; using file modification time", key, value)})
+	return fallback.Unix()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConvert(t *testing.T) {
+	src := "---\n" +
+		"title: Meeting Notes\n" +
+		"created: 2024-01-02T03:04:05Z\n" +
+		"tags: [meeting, team work]\n" +
+		"---\n" +
+		"# Meeting Notes\n" +
+		"\n" +
+		"## Agenda\n" +
+		"- Review [[Design Doc|the design]] and **decide**\n" +
+		"  - see [site](https://example.com) and [other](Other%20Note.md)\n" +
+		"1. *first* ~~old~~ `x*y`\n" +
+		"> quoted #tag\n" +
+		"```go\n" +
+		"fmt.Println(1)\n" +
+		"```\n" +
+		"| a | b |\n" +
+		"| --- | --- |\n" +
+		"| 1 | $x$ |\n" +
+		"![[diagram.png]]\n"
+	modTime := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	page, warnings := Convert("file-name", []byte(src), modTime)
+
+	var got []string
+	for _, l := range page.Lines {
+		got = append(got, l.Text)
+	}
+	want := []string{
+		"Meeting Notes",
+		"[*** Agenda]",
+		" Review [Design Doc] and [* decide]",
+		"  see [site https://example.com] and [Other Note]",
+		" 1. [/ first] [- old] `x*y`",
+		"> quoted #tag",
+		"code:go",
+		" fmt.Println(1)",
+		"table:table",
+		" a\tb",
+		" 1\t[$ x]",
+		"[diagram.png]",
+		"",
+		"#meeting #team_work",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Convert() lines mismatch (-want +got):\n%s", diff)
+	}
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Unix()
+	if page.Created != created || page.Lines[0].Created != created {
+		t.Errorf("Convert() created = %d, want %d", page.Created, created)
+	}
+	if page.Updated != modTime.Unix() || page.Lines[3].Updated != modTime.Unix() {
+		t.Errorf("Convert() updated = %d, want %d", page.Updated, modTime.Unix())
+	}
+
+	wantWarnings := []Warning{
+		{Line: 9, Message: `alias "the design" of link to "Design Doc" dropped`},
+		{Line: 19, Message: `embed of "diagram.png" converted to a link`},
+	}
+	if diff := cmp.Diff(wantWarnings, warnings); diff != "" {
+		t.Errorf("Convert() warnings mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestImportDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.md":              "hello",
+		"sub/b.md":          "[[a]]",
+		"sub/A.md":          "duplicate title",
+		".obsidian/x.md":    "ignored",
+		"notes.txt":         "ignored",
+		"sub/deeper/c c.md": "---\ntitle: C\n---\nbody",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := ImportDir(dir)
+	if err != nil {
+		t.Fatalf("ImportDir() error = %v", err)
+	}
+	var titles []string
+	for _, p := range res.Data.Pages {
+		titles = append(titles, p.Title)
+	}
+	if diff := cmp.Diff([]string{"a", "b", "C"}, titles); diff != "" {
+		t.Errorf("ImportDir() titles mismatch (-want +got):\n%s", diff)
+	}
+	if len(res.Files) != 4 {
+		t.Fatalf("ImportDir() reported %d files, want 4", len(res.Files))
+	}
+	if dup := res.Files[1]; dup.Path != filepath.Join("sub", "A.md") || len(dup.Warnings) != 1 {
+		t.Errorf("ImportDir() duplicate report = %+v, want one warning for sub/A.md", dup)
+	}
+}
+
+func TestConvert_CodeFence(t *testing.T) {
+	tests := map[string]struct {
+		src  string
+		want []string
+	}{
+		"ok: closing fence": {
+			src:  "```go\nx := 1\n```\nafter",
+			want: []string{"code:go", " x := 1", "after"},
+		},
+		"ok: longer closing fence": {
+			src:  "```\nx\n`````\nafter",
+			want: []string{"code:text", " x", "after"},
+		},
+		"ok: shorter fence inside a longer one": {
+			src:  "````md\n```\ncode\n```\n````\nafter",
+			want: []string{"code:md", " ```", " code", " ```", "after"},
+		},
+		"ok: opening fence inside a block": {
+			src:  "```md\n```go\n```\nafter",
+			want: []string{"code:md", " ```go", "after"},
+		},
+		"ok: other fence character inside a block": {
+			src:  "~~~\n```\n~~~\nafter",
+			want: []string{"code:text", " ```", "after"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			page, warnings := Convert("Page", []byte(tt.src), time.Time{})
+			var got []string
+			for _, l := range page.Lines[1:] {
+				got = append(got, l.Text)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Convert() lines mismatch (-want +got):\n%s", diff)
+			}
+			if len(warnings) != 0 {
+				t.Errorf("Convert() warnings = %v, want none", warnings)
+			}
+		})
+	}
+}
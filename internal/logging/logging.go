@@ -0,0 +1,172 @@
+// Package logging sets up the structured logs of the servers: records are
+// written to stderr as text or JSON at or above a level that can change
+// while the server runs, and the records logged while serving a request of
+// an MCP client, such as the Scrapbox requests made by a tool call, are also
+// sent to that client as notifications/message, at or above the level it
+// selected with logging/setLevel.
+//
+// Records are only sent to the client whose request they belong to, so that
+// in multi-tenant mode no session sees the pages another one reads.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// LoggerName is the logger of the log messages sent to clients.
+const LoggerName = "scrapbox"
+
+// Client sends log messages to an MCP client, unless they are below the
+// level it selected.
+type Client interface {
+	Log(ctx context.Context, level slog.Level, data json.RawMessage)
+}
+
+type clientKey struct{}
+
+// NewContext returns a copy of ctx whose records are sent to c.
+func NewContext(ctx context.Context, c Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// clientFrom returns the client of ctx, if any.
+func clientFrom(ctx context.Context) (Client, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	c, ok := ctx.Value(clientKey{}).(Client)
+	return c, ok
+}
+
+// Handler writes records to its output and sends them to the client of
+// their context.
+type Handler struct {
+	out   slog.Handler
+	level *slog.LevelVar
+	// data formats the records sent to clients as JSON objects into buf,
+	// which mu guards; both are shared with the handlers derived from this
+	// one.
+	data slog.Handler
+	mu   *sync.Mutex
+	buf  *bytes.Buffer
+}
+
+// New creates a Handler writing the records at or above level to w, as JSON
+// if format is FormatJSON and as text otherwise.
+func New(w io.Writer, format string, level slog.Level) *Handler {
+	h := &Handler{level: new(slog.LevelVar), mu: new(sync.Mutex), buf: new(bytes.Buffer)}
+	h.level.Set(level)
+	opts := &slog.HandlerOptions{Level: h.level}
+	if format == FormatJSON {
+		h.out = slog.NewJSONHandler(w, opts)
+	} else {
+		h.out = slog.NewTextHandler(w, opts)
+	}
+	// The level and time are part of the notification, not of its data.
+	h.data = slog.NewJSONHandler(h.buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	return h
+}
+
+// SetLevel changes the level of the records written to the output.
+func (h *Handler) SetLevel(level slog.Level) {
+	h.level.Set(level)
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if _, ok := clientFrom(ctx); ok {
+		return true
+	}
+	return h.out.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if h.out.Enabled(ctx, r.Level) {
+		err = h.out.Handle(ctx, r)
+	}
+	if c, ok := clientFrom(ctx); ok {
+		h.mu.Lock()
+		h.buf.Reset()
+		dataErr := h.data.Handle(ctx, r)
+		data := json.RawMessage(bytes.TrimSpace(bytes.Clone(h.buf.Bytes())))
+		h.mu.Unlock()
+		if dataErr != nil {
+			return dataErr
+		}
+		// The notification must not fail with the request it belongs to.
+		c.Log(context.WithoutCancel(ctx), r.Level, data)
+	}
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.out, h2.data = h.out.WithAttrs(attrs), h.data.WithAttrs(attrs)
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.out, h2.data = h.out.WithGroup(name), h.data.WithGroup(name)
+	return &h2
+}
+
+// mcpLevels are the MCP logging levels with the slog levels they start at,
+// in increasing order, as the MCP SDKs map them.
+var mcpLevels = []struct {
+	name  string
+	level slog.Level
+}{
+	{"debug", slog.LevelDebug},
+	{"info", slog.LevelInfo},
+	{"notice", slog.LevelInfo + 1},
+	{"warning", slog.LevelWarn},
+	{"error", slog.LevelError},
+	{"critical", slog.LevelError + 1},
+	{"alert", slog.LevelError + 2},
+	{"emergency", slog.LevelError + 3},
+}
+
+// MCPLevel returns the MCP logging level of level.
+func MCPLevel(level slog.Level) string {
+	name := mcpLevels[0].name
+	for _, l := range mcpLevels {
+		if level >= l.level {
+			name = l.name
+		}
+	}
+	return name
+}
+
+// ParseMCPLevel returns the slog level of the MCP logging level name, and
+// reports whether name is one.
+func ParseMCPLevel(name string) (slog.Level, bool) {
+	for _, l := range mcpLevels {
+		if l.name == name {
+			return l.level, true
+		}
+	}
+	return 0, false
+}
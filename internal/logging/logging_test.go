@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// timeAttr matches the time of a text or JSON record.
+var timeAttr = regexp.MustCompile(`^time=\S+ |"time":"[^"]+",`)
+
+// recorder records the log messages sent to a client.
+type recorder struct {
+	messages []string
+}
+
+func (r *recorder) Log(_ context.Context, level slog.Level, data json.RawMessage) {
+	r.messages = append(r.messages, MCPLevel(level)+" "+string(data))
+}
+
+func TestHandler(t *testing.T) {
+	tests := map[string]struct {
+		format     string
+		client     bool
+		wantOutput []string
+		wantSent   []string
+	}{
+		"ok: text": {
+			format:     FormatText,
+			wantOutput: []string{`level=WARN msg="cookie rejected" project=main`},
+		},
+		"ok: json": {
+			format:     FormatJSON,
+			wantOutput: []string{`{"level":"WARN","msg":"cookie rejected","project":"main"}`},
+		},
+		"ok: sent to the client of the context": {
+			format:     FormatText,
+			client:     true,
+			wantOutput: []string{`level=WARN msg="cookie rejected" project=main`},
+			wantSent: []string{
+				`debug {"msg":"request","project":"main","path":"/pages/main"}`,
+				`warning {"msg":"cookie rejected","project":"main"}`,
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := New(&buf, tt.format, slog.LevelInfo)
+			ctx := context.Background()
+			rec := &recorder{}
+			if tt.client {
+				ctx = NewContext(ctx, rec)
+			}
+			logger := slog.New(h).With("project", "main")
+			logger.DebugContext(ctx, "request", "path", "/pages/main")
+			logger.WarnContext(ctx, "cookie rejected")
+
+			var output []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				output = append(output, timeAttr.ReplaceAllString(line, ""))
+			}
+			if diff := cmp.Diff(tt.wantOutput, output); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantSent, rec.messages); diff != "" {
+				t.Errorf("sent messages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandler_SetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	h := New(&buf, FormatText, slog.LevelInfo)
+	logger := slog.New(h)
+	logger.Debug("hidden")
+	h.SetLevel(slog.LevelDebug)
+	logger.Debug("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Errorf("output = %q, want only the record logged after SetLevel", out)
+	}
+}
+
+func TestMCPLevel(t *testing.T) {
+	tests := map[string]struct {
+		level slog.Level
+		want  string
+	}{
+		"ok: below debug": {level: slog.LevelDebug - 4, want: "debug"},
+		"ok: debug":       {level: slog.LevelDebug, want: "debug"},
+		"ok: info":        {level: slog.LevelInfo, want: "info"},
+		"ok: notice":      {level: slog.LevelInfo + 1, want: "notice"},
+		"ok: warn":        {level: slog.LevelWarn, want: "warning"},
+		"ok: error":       {level: slog.LevelError, want: "error"},
+		"ok: emergency":   {level: slog.LevelError + 8, want: "emergency"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := MCPLevel(tt.level); got != tt.want {
+				t.Errorf("MCPLevel(%v) = %s, want %s", tt.level, got, tt.want)
+			}
+			if tt.level >= slog.LevelDebug && tt.level <= slog.LevelError {
+				if level, ok := ParseMCPLevel(tt.want); !ok || MCPLevel(level) != tt.want {
+					t.Errorf("ParseMCPLevel(%s) = %v, %v", tt.want, level, ok)
+				}
+			}
+		})
+	}
+	if _, ok := ParseMCPLevel("verbose"); ok {
+		t.Error("ParseMCPLevel(verbose) ok, want not ok")
+	}
+}
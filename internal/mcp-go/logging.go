@@ -0,0 +1,52 @@
+package mcpgo
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// logTool sends the records logged while calling a tool to the session
+// calling it.
+func logTool(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return next(withSessionLogger(ctx), req)
+	}
+}
+
+// withSessionLogger returns a copy of ctx whose Scrapbox requests are logged
+// with a request ID of their own and whose records are sent to the session
+// serving it, if any.
+func withSessionLogger(ctx context.Context) context.Context {
+	ctx = scrapbox.ContextWithRequestID(ctx, scrapbox.NewRequestID())
+	srv := server.ServerFromContext(ctx)
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithLogging)
+	if srv == nil || !ok {
+		return ctx
+	}
+	return logging.NewContext(ctx, sessionLogger{srv: srv, session: session})
+}
+
+// sessionLogger sends log messages to a session at or above the level it
+// selected with logging/setLevel, error by default.
+type sessionLogger struct {
+	srv     *server.MCPServer
+	session server.SessionWithLogging
+}
+
+// Log implements logging.Client.
+func (l sessionLogger) Log(ctx context.Context, level slog.Level, data json.RawMessage) {
+	if min, ok := logging.ParseMCPLevel(string(l.session.GetLogLevel())); ok && level < min {
+		return
+	}
+	_ = l.srv.SendNotificationToClient(ctx, "notifications/message", map[string]any{
+		"level":  logging.MCPLevel(level),
+		"logger": logging.LoggerName,
+		"data":   data,
+	})
+}
@@ -0,0 +1,41 @@
+package mcpgo
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+)
+
+// redactTool redacts the results of every tool with redactor.
+func redactTool(redactor *redact.Redactor) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, req)
+			if err != nil || result == nil || redactor == nil {
+				return result, err
+			}
+			for i, c := range result.Content {
+				result.Content[i] = redactContent(redactor, c)
+			}
+			return result, nil
+		}
+	}
+}
+
+// redactContent returns c with its text redacted by redactor.
+func redactContent(redactor *redact.Redactor, c mcp.Content) mcp.Content {
+	switch c := c.(type) {
+	case mcp.TextContent:
+		c.Text = redactor.Text(c.Text)
+		return c
+	case mcp.EmbeddedResource:
+		if r, ok := c.Resource.(mcp.TextResourceContents); ok {
+			r.Text = redactor.Text(r.Text)
+			c.Resource = r
+		}
+		return c
+	}
+	return c
+}
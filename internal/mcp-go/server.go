@@ -3,63 +3,261 @@ package mcpgo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/takak2166/scrapbox-mcp/internal/history"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/prompts"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/internal/tenant"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
 	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
 )
 
 // Server wraps the MCP server and Scrapbox client
 type Server struct {
 	mcpServer *server.MCPServer
-	client    *scrapbox.Client
+	projects  *projects.Set
+	tenants   *tenant.Manager
+	// registry decides which of tools, every tool by name, are served.
+	registry *tools.Registry
+	tools    map[string]server.ServerTool
+	// redactor redacts the outputs of the server, unless nil.
+	redactor *redact.Redactor
+
+	// listedMu guards listed, the page resources currently registered by URI.
+	listedMu sync.Mutex
+	listed   map[string]resources.Resource
+
+	// watchersMu guards watchers, the resource watchers by session ID.
+	watchersMu sync.Mutex
+	watchers   map[string]*watch.Watcher
 }
 
-// NewServer creates a new MCP server instance
-func NewServer(client *scrapbox.Client) *server.MCPServer {
+// NewServer creates a new MCP server instance. watchOpts configures the
+// polling behind resource list change notifications. In multi-tenant mode
+// tenants provides the projects of every session and set is not used;
+// tenants is nil otherwise. Only the tools enabled by registry are served,
+// and clients are notified when that changes. Tool results, resources and
+// prompts are redacted by redactor, unless nil. The records logged while
+// serving a request are sent to its client as log messages.
+func NewServer(set *projects.Set, watchOpts watch.Options, tenants *tenant.Manager, registry *tools.Registry, redactor *redact.Redactor) *server.MCPServer {
+	hooks := &server.Hooks{}
 	mcpSrv := server.NewMCPServer(
 		"Scrapbox MCP Server",
 		"1.0.0",
+		server.WithResourceCapabilities(false, true),
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(redactTool(redactor)),
+		server.WithToolHandlerMiddleware(logTool),
+		server.WithLogging(),
+		server.WithHooks(hooks),
 	)
 
 	s := &Server{
 		mcpServer: mcpSrv,
-		client:    client,
+		projects:  set,
+		tenants:   tenants,
+		registry:  registry,
+		redactor:  redactor,
+		tools:     map[string]server.ServerTool{},
+		listed:    map[string]resources.Resource{},
+		watchers:  map[string]*watch.Watcher{},
 	}
 
+	set.OnExpired(s.logExpired)
+	registry.OnChange(s.syncTools)
+
 	s.registerTools()
+	s.registerResources(hooks)
+	s.registerWatchers(hooks, watchOpts)
+	s.registerPrompts()
 	return mcpSrv
 }
 
+// logExpired tells every session that the cookie of a project expired.
+func (s *Server) logExpired(err error) {
+	s.mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{
+		"level":  mcp.LoggingLevelError,
+		"logger": "scrapbox",
+		"data":   err.Error(),
+	})
+}
+
+// projectsFor returns the projects of the session serving ctx.
+func (s *Server) projectsFor(ctx context.Context) (*projects.Set, error) {
+	if s.tenants == nil {
+		return s.projects, nil
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil, tenant.ErrNoCredentials
+	}
+	return s.sessionProjects(ctx, session.SessionID())
+}
+
+// clientFor returns the Scrapbox client of the named project of the session
+// serving ctx, or of its primary project if project is empty.
+func (s *Server) clientFor(ctx context.Context, project string) (*scrapbox.Client, error) {
+	set, err := s.projectsFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return set.Client(project)
+}
+
+// sessionProjects returns the projects of the Tenant of session id, whose
+// credentials come from the request of ctx.
+func (s *Server) sessionProjects(ctx context.Context, id string) (*projects.Set, error) {
+	t, err := s.tenants.Session(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return t.Projects, nil
+}
+
+// projectDescription describes the project argument of every tool.
+const projectDescription = "Project to use (defaults to the primary project)"
+
 func (s *Server) registerTools() {
 	// get_page
 	getPageTool := mcp.NewTool("get_page",
 		mcp.WithDescription("Get a Scrapbox page by title"),
 		mcp.WithString("title", mcp.Required(), mcp.Description("Page title to retrieve")),
+		mcp.WithString("project", mcp.Description(projectDescription)),
 	)
-	s.mcpServer.AddTool(getPageTool, s.handleGetPage)
+	s.tools[getPageTool.Name] = server.ServerTool{Tool: getPageTool, Handler: s.handleGetPage}
 
 	// list_pages
 	listPagesTool := mcp.NewTool("list_pages",
 		mcp.WithDescription("Get a list of pages in the project (max 1000 pages)"),
+		mcp.WithString("project", mcp.Description(projectDescription)),
 	)
-	s.mcpServer.AddTool(listPagesTool, s.handleListPages)
+	s.tools[listPagesTool.Name] = server.ServerTool{Tool: listPagesTool, Handler: s.handleListPages}
 
 	// search_pages
 	searchPagesTool := mcp.NewTool("search_pages",
 		mcp.WithDescription("Full-text search across all pages in the project (max 100 pages)"),
 		mcp.WithString("query", mcp.Required(), mcp.Description("Search query")),
+		mcp.WithString("project", mcp.Description(projectDescription)),
 	)
-	s.mcpServer.AddTool(searchPagesTool, s.handleSearchPages)
+	s.tools[searchPagesTool.Name] = server.ServerTool{Tool: searchPagesTool, Handler: s.handleSearchPages}
 
 	// create_page_url
 	createPageURLTool := mcp.NewTool("create_page_url",
 		mcp.WithDescription("Generate a URL for creating a new page"),
 		mcp.WithString("title", mcp.Required(), mcp.Description("Page title")),
 		mcp.WithString("body_text", mcp.Description("Body text for the new page")),
+		mcp.WithString("project", mcp.Description(projectDescription)),
+	)
+	s.tools[createPageURLTool.Name] = server.ServerTool{Tool: createPageURLTool, Handler: s.handleCreatePageURL}
+
+	// get_page_history
+	getPageHistoryTool := mcp.NewTool("get_page_history",
+		mcp.WithDescription("List the saved snapshots and commits of a page, newest first"),
+		mcp.WithString("page_title", mcp.Required(), mcp.Description("Page title")),
+		mcp.WithString("project", mcp.Description(projectDescription)),
+	)
+	s.tools[getPageHistoryTool.Name] = server.ServerTool{Tool: getPageHistoryTool, Handler: s.handleGetPageHistory}
+
+	// get_page_at
+	getPageAtTool := mcp.NewTool("get_page_at",
+		mcp.WithDescription("Get the content of a page as it was at a point in time"),
+		mcp.WithString("page_title", mcp.Required(), mcp.Description("Page title")),
+		mcp.WithString("time", mcp.Required(), mcp.Description("Point in time as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds")),
+		mcp.WithString("project", mcp.Description(projectDescription)),
+	)
+	s.tools[getPageAtTool.Name] = server.ServerTool{Tool: getPageAtTool, Handler: s.handleGetPageAt}
+
+	// diff_page_versions
+	diffPageVersionsTool := mcp.NewTool("diff_page_versions",
+		mcp.WithDescription("Show a unified diff between two versions of a page"),
+		mcp.WithString("page_title", mcp.Required(), mcp.Description("Page title")),
+		mcp.WithString("from", mcp.Required(), mcp.Description("Time of the older version")),
+		mcp.WithString("to", mcp.Description("Time of the newer version (defaults to the current page)")),
+		mcp.WithString("project", mcp.Description(projectDescription)),
 	)
-	s.mcpServer.AddTool(createPageURLTool, s.handleCreatePageURL)
+	s.tools[diffPageVersionsTool.Name] = server.ServerTool{Tool: diffPageVersionsTool, Handler: s.handleDiffPageVersions}
+
+	// blame_page
+	blamePageTool := mcp.NewTool("blame_page",
+		mcp.WithDescription("Show who last edited each line of a page, grouped by author and editing session"),
+		mcp.WithString("page_title", mcp.Required(), mcp.Description("Page title")),
+		mcp.WithString("since", mcp.Description("Only include lines changed at or after this time (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)")),
+		mcp.WithString("project", mcp.Description(projectDescription)),
+	)
+	s.tools[blamePageTool.Name] = server.ServerTool{Tool: blamePageTool, Handler: s.handleBlamePage}
+
+	// recent_changes
+	recentChangesTool := mcp.NewTool("recent_changes",
+		mcp.WithDescription("List pages updated since a time with the lines each author added or modified"),
+		mcp.WithString("since", mcp.Required(), mcp.Description("Start of the period (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)")),
+		mcp.WithNumber("max_chars", mcp.Description("Maximum total characters of line text to return")),
+		mcp.WithString("project", mcp.Description(projectDescription)),
+	)
+	s.tools[recentChangesTool.Name] = server.ServerTool{Tool: recentChangesTool, Handler: s.handleRecentChanges}
+
+	// search_all
+	searchAllTool := mcp.NewTool("search_all",
+		mcp.WithDescription("Full-text search across every project at once, with the project of each page and the projects whose search failed"),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Search query")),
+	)
+	s.tools[searchAllTool.Name] = server.ServerTool{Tool: searchAllTool, Handler: s.handleSearchAll}
+
+	// list_projects
+	listProjectsTool := mcp.NewTool("list_projects",
+		mcp.WithDescription("List the Scrapbox projects served, which the project argument of the other tools selects"),
+	)
+	s.tools[listProjectsTool.Name] = server.ServerTool{Tool: listProjectsTool, Handler: s.handleListProjects}
+
+	for name, t := range s.tools {
+		t.Handler = s.checkTool(name, t.Handler)
+		s.tools[name] = t
+	}
+	s.syncTools()
+}
+
+// syncTools serves the tools enabled by the registry and removes the others,
+// notifying every session with notifications/tools/list_changed.
+func (s *Server) syncTools() {
+	var enabled []server.ServerTool
+	var disabled []string
+	for _, name := range tools.Names {
+		if s.registry.Enabled(name) {
+			enabled = append(enabled, s.tools[name])
+		} else {
+			disabled = append(disabled, name)
+		}
+	}
+	s.mcpServer.DeleteTools(disabled...)
+	s.mcpServer.AddTools(enabled...)
+}
+
+// checkTool wraps handler, the handler of the named tool, to reject the
+// calls that the registry disables in the project they are called on.
+func (s *Server) checkTool(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	checked := tools.Wrap(s.registry, name, func(ctx context.Context, req mcp.CallToolRequest) (tools.Call, error) {
+		set, err := s.projectsFor(ctx)
+		if err != nil {
+			return tools.Call{}, err
+		}
+		return tools.Call{Project: req.GetString("project", ""), Primary: set.Primary().ProjectName()}, nil
+	}, handler)
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := checked(ctx, req)
+		if errors.Is(err, tools.ErrDisabled) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return result, err
+	}
 }
 
 func (s *Server) handleGetPage(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -67,7 +265,11 @@ func (s *Server) handleGetPage(ctx context.Context, req mcp.CallToolRequest) (*m
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	page, err := s.client.GetPage(ctx, title)
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	page, err := client.GetPage(ctx, title)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to get page: %v", err)), nil
 	}
@@ -79,7 +281,11 @@ func (s *Server) handleGetPage(ctx context.Context, req mcp.CallToolRequest) (*m
 }
 
 func (s *Server) handleListPages(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	pages, err := s.client.ListPages(ctx)
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	pages, err := client.ListPages(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to list pages: %v", err)), nil
 	}
@@ -95,7 +301,11 @@ func (s *Server) handleSearchPages(ctx context.Context, req mcp.CallToolRequest)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	pages, err := s.client.SearchPages(ctx, query)
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	pages, err := client.SearchPages(ctx, query)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to search pages: %v", err)), nil
 	}
@@ -109,9 +319,274 @@ func (s *Server) handleCreatePageURL(ctx context.Context, req mcp.CallToolReques
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 	bodyText := req.GetString("body_text", "")
-	url, err := s.client.CreatePageURL(ctx, title, bodyText)
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	url, err := client.CreatePageURL(ctx, title, bodyText)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to generate page URL: %v", err)), nil
 	}
 	return mcp.NewToolResultText(url), nil
 }
+
+func (s *Server) handleGetPageHistory(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	title, err := req.RequireString("page_title")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	h, err := history.GetHistory(ctx, client, title)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get page history: %v", err)), nil
+	}
+	return jsonResult(h), nil
+}
+
+func (s *Server) handleGetPageAt(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	title, err := req.RequireString("page_title")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	timeArg, err := req.RequireString("time")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	at, err := history.ParseTime(timeArg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	v, err := history.PageAt(ctx, client, title, at)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get page version: %v", err)), nil
+	}
+	return jsonResult(v), nil
+}
+
+func (s *Server) handleDiffPageVersions(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	title, err := req.RequireString("page_title")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	fromArg, err := req.RequireString("from")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	from, err := history.ParseTime(fromArg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var to time.Time
+	if toArg := req.GetString("to", ""); toArg != "" {
+		if to, err = history.ParseTime(toArg); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	d, err := history.DiffVersions(ctx, client, title, from, to)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to diff page versions: %v", err)), nil
+	}
+	return jsonResult(d), nil
+}
+
+func (s *Server) handleBlamePage(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	title, err := req.RequireString("page_title")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var since time.Time
+	if sinceArg := req.GetString("since", ""); sinceArg != "" {
+		if since, err = history.ParseTime(sinceArg); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	b, err := history.Blame(ctx, client, title, since)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to blame page: %v", err)), nil
+	}
+	return jsonResult(b), nil
+}
+
+func (s *Server) handleRecentChanges(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sinceArg, err := req.RequireString("since")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	since, err := history.ParseTime(sinceArg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	client, err := s.clientFor(ctx, req.GetString("project", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	a, err := history.RecentChanges(ctx, client, since, req.GetInt("max_chars", 0))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to list recent changes: %v", err)), nil
+	}
+	return jsonResult(a), nil
+}
+
+func (s *Server) handleSearchAll(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	set, err := s.projectsFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	r, err := set.SearchAll(ctx, query, 0)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to search projects: %v", err)), nil
+	}
+	return jsonResult(r), nil
+}
+
+func (s *Server) handleListProjects(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	set, err := s.projectsFor(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonResult(set.List()), nil
+}
+
+// jsonResult returns v marshalled as JSON text, or a tool error if it cannot be marshalled.
+func jsonResult(v any) *mcp.CallToolResult {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err))
+	}
+	return mcp.NewToolResultText(string(b))
+}
+
+// registerResources exposes pages through the scrapbox:// resource template
+// and keeps the pinned and recently updated pages registered as concrete
+// resources, refreshing them before every resources/list. In multi-tenant
+// mode no concrete resources are registered.
+func (s *Server) registerResources(hooks *server.Hooks) {
+	description := "A page of the Scrapbox project as Markdown. Append ?format=json for the page JSON."
+	for _, tmpl := range []string{resources.URITemplate, resources.URITemplate + "{?format}"} {
+		s.mcpServer.AddResourceTemplate(
+			mcp.NewResourceTemplate(tmpl, "page",
+				mcp.WithTemplateDescription(description),
+				mcp.WithTemplateMIMEType(resources.MIMEMarkdown),
+			),
+			s.handleReadResource,
+		)
+	}
+	hooks.AddBeforeListResources(func(ctx context.Context, _ any, _ *mcp.ListResourcesRequest) {
+		// Resources registered with mcp-go are shared by all sessions, so
+		// in multi-tenant mode only the template is listed.
+		if s.tenants != nil {
+			return
+		}
+		if err := s.refreshResources(ctx); err != nil {
+			slog.Default().WarnContext(ctx, "failed to refresh resources", "error", err)
+		}
+	})
+}
+
+// refreshResources registers the current pinned and recent pages and removes
+// the ones that dropped out of the list.
+func (s *Server) refreshResources(ctx context.Context) error {
+	list, err := resources.List(ctx, s.projects.Primary(), resources.DefaultLimit)
+	if err != nil {
+		return err
+	}
+	s.listedMu.Lock()
+	defer s.listedMu.Unlock()
+	current := map[string]resources.Resource{}
+	for _, r := range list {
+		current[r.URI] = r
+		if prev, ok := s.listed[r.URI]; ok && prev == r {
+			continue
+		}
+		// mcp-go has no lastModified annotation, so the time is only part of
+		// the description.
+		s.mcpServer.AddResource(
+			mcp.NewResource(r.URI, r.Name,
+				mcp.WithResourceDescription(s.redactor.Redact(r.Description)),
+				mcp.WithMIMEType(r.MIMEType),
+			),
+			s.handleReadResource,
+		)
+	}
+	for uri := range s.listed {
+		if _, ok := current[uri]; !ok {
+			s.mcpServer.RemoveResource(uri)
+		}
+	}
+	s.listed = current
+	return nil
+}
+
+func (s *Server) handleReadResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	ctx = withSessionLogger(ctx)
+	set, err := s.projectsFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := resources.Read(ctx, set.ForURI(req.Params.URI), req.Params.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: c.URI, MIMEType: c.MIMEType, Text: s.redactor.Text(c.Text)},
+	}, nil
+}
+
+// registerPrompts registers the prompts for common Scrapbox workflows.
+func (s *Server) registerPrompts() {
+	for _, p := range prompts.Prompts {
+		opts := []mcp.PromptOption{mcp.WithPromptDescription(p.Description)}
+		for _, a := range p.Arguments {
+			argOpts := []mcp.ArgumentOption{mcp.ArgumentDescription(a.Description)}
+			if a.Required {
+				argOpts = append(argOpts, mcp.RequiredArgument())
+			}
+			opts = append(opts, mcp.WithArgument(a.Name, argOpts...))
+		}
+		s.mcpServer.AddPrompt(mcp.NewPrompt(p.Name, opts...), s.handleGetPrompt)
+	}
+}
+
+func (s *Server) handleGetPrompt(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	ctx = withSessionLogger(ctx)
+	client, err := s.clientFor(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	r, err := prompts.Get(ctx, client, req.Params.Name, req.Params.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+	var messages []mcp.PromptMessage
+	for _, m := range r.Messages {
+		var content mcp.Content = mcp.NewTextContent(m.Text)
+		if m.Resource != nil {
+			content = mcp.NewEmbeddedResource(mcp.TextResourceContents{
+				URI:      m.Resource.URI,
+				MIMEType: m.Resource.MIMEType,
+				Text:     m.Resource.Text,
+			})
+		}
+		messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, redactContent(s.redactor, content)))
+	}
+	return mcp.NewGetPromptResult(r.Description, messages), nil
+}
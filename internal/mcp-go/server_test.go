@@ -0,0 +1,177 @@
+package mcpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+func TestNewServer_ToolPolicy(t *testing.T) {
+	set := projects.FromConfig(&config.Config{ProjectName: "main", Projects: []config.Project{{Name: "sandbox"}}})
+	registry := tools.NewRegistry(tools.Policy{ReadOnly: true, Deny: []string{"search_all"}}, map[string]tools.Policy{
+		"sandbox": {Deny: []string{"search_all"}},
+	})
+	s := NewServer(set, watch.Options{}, nil, registry, nil)
+	ctx := context.Background()
+
+	id := 0
+	call := func(method string, params any) mcp.JSONRPCMessage {
+		t.Helper()
+		id++
+		msg, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.HandleMessage(ctx, msg)
+	}
+	listed := func() []string {
+		t.Helper()
+		resp, ok := call("tools/list", map[string]any{}).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("tools/list failed")
+		}
+		var names []string
+		for _, tool := range resp.Result.(mcp.ListToolsResult).Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	call("initialize", map[string]any{"protocolVersion": mcp.LATEST_PROTOCOL_VERSION, "clientInfo": map[string]any{"name": "test", "version": "1.0.0"}})
+
+	want := []string{"blame_page", "create_page_url", "diff_page_versions", "get_page", "get_page_at", "get_page_history", "list_pages", "list_projects", "recent_changes", "search_pages"}
+	if diff := cmp.Diff(want, listed()); diff != "" {
+		t.Errorf("tools/list mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := map[string]struct {
+		tool    string
+		args    map[string]any
+		wantErr bool
+	}{
+		"ok: write tool in writable project": {
+			tool: "create_page_url",
+			args: map[string]any{"title": "Draft", "project": "sandbox"},
+		},
+		"err: write tool in read-only project": {
+			tool:    "create_page_url",
+			args:    map[string]any{"title": "Draft"},
+			wantErr: true,
+		},
+		"err: denied tool": {
+			tool:    "search_all",
+			args:    map[string]any{"query": "q"},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotErr error
+			switch resp := call("tools/call", map[string]any{"name": tt.tool, "arguments": tt.args}).(type) {
+			case mcp.JSONRPCError:
+				gotErr = fmt.Errorf("%s", resp.Error.Message)
+			case mcp.JSONRPCResponse:
+				if result := resp.Result.(mcp.CallToolResult); result.IsError {
+					gotErr = fmt.Errorf("%v", result.Content)
+				}
+			}
+			if (gotErr != nil) != tt.wantErr {
+				t.Errorf("tools/call error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+
+	registry.SetPolicy(tools.Policy{Allow: []string{"get_page", "list_projects"}}, nil)
+	if diff := cmp.Diff([]string{"get_page", "list_projects"}, listed()); diff != "" {
+		t.Errorf("tools/list after SetPolicy mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// loggingSession is a session receiving the notifications sent to it.
+type loggingSession struct {
+	notifications chan mcp.JSONRPCNotification
+	level         mcp.LoggingLevel
+}
+
+func (s *loggingSession) Initialize()                                         {}
+func (s *loggingSession) Initialized() bool                                   { return true }
+func (s *loggingSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *loggingSession) SessionID() string                                   { return "session" }
+func (s *loggingSession) SetLogLevel(level mcp.LoggingLevel)                  { s.level = level }
+func (s *loggingSession) GetLogLevel() mcp.LoggingLevel                       { return s.level }
+
+func TestNewServer_Logging(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"pages":[]}`))
+	}))
+	defer ts.Close()
+	logger := slog.New(logging.New(io.Discard, logging.FormatText, slog.LevelInfo))
+	set := projects.FromConfig(&config.Config{ProjectName: "main", ScrapboxSID: "secret-sid"}, scrapbox.WithBaseURL(ts.URL), scrapbox.WithLogger(logger))
+	s := NewServer(set, watch.Options{}, nil, tools.NewRegistry(tools.Policy{}, nil), nil)
+
+	tests := map[string]struct {
+		level mcp.LoggingLevel
+		want  []string
+	}{
+		"ok: debug": {
+			level: mcp.LoggingLevelDebug,
+			want:  []string{"debug scrapbox request", "debug scrapbox response"},
+		},
+		"ok: default level": {
+			level: mcp.LoggingLevelError,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			session := &loggingSession{notifications: make(chan mcp.JSONRPCNotification, 10), level: tt.level}
+			ctx := s.WithContext(context.Background(), session)
+			if err := s.RegisterSession(ctx, session); err != nil {
+				t.Fatal(err)
+			}
+			defer s.UnregisterSession(ctx, session.SessionID())
+			msg, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": map[string]any{
+				"name": "search_pages", "arguments": map[string]any{"query": "private words"},
+			}})
+			if _, ok := s.HandleMessage(ctx, msg).(mcp.JSONRPCResponse); !ok {
+				t.Fatal("tools/call failed")
+			}
+
+			var got []string
+			for len(session.notifications) > 0 {
+				n := <-session.notifications
+				if n.Method != "notifications/message" {
+					continue
+				}
+				fields := n.Params.AdditionalFields
+				data := string(fields["data"].(json.RawMessage))
+				for _, secret := range []string{"secret-sid", "private"} {
+					if strings.Contains(data, secret) {
+						t.Errorf("log message %s contains %q", data, secret)
+					}
+				}
+				var record struct{ Msg string }
+				if err := json.Unmarshal([]byte(data), &record); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, fmt.Sprintf("%s %s", fields["level"], record.Msg))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("log messages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package mcpgo
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+)
+
+// mcp-go does not route resources/subscribe to servers, so only the resource
+// list is watched: every session gets a watch.Watcher without subscriptions
+// that sends notifications/resources/list_changed when pages are created or
+// deleted.
+
+// registerWatchers starts a watcher for every session and stops it when the
+// session ends.
+func (s *Server) registerWatchers(hooks *server.Hooks, opts watch.Options) {
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		set := s.projects
+		if s.tenants != nil {
+			var err error
+			if set, err = s.sessionProjects(ctx, session.SessionID()); err != nil {
+				return
+			}
+		}
+		w := watch.New(set.Primary(), &sessionNotifier{server: s.mcpServer, sessionID: session.SessionID()}, opts)
+		s.watchersMu.Lock()
+		s.watchers[session.SessionID()] = w
+		s.watchersMu.Unlock()
+	})
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		s.watchersMu.Lock()
+		w, ok := s.watchers[session.SessionID()]
+		delete(s.watchers, session.SessionID())
+		s.watchersMu.Unlock()
+		if s.tenants != nil {
+			s.tenants.CloseSession(session.SessionID())
+		}
+		if ok {
+			w.Close()
+		}
+	})
+}
+
+// sessionNotifier sends watcher notifications to one session.
+type sessionNotifier struct {
+	server    *server.MCPServer
+	sessionID string
+}
+
+// ResourceUpdated implements watch.Notifier.
+func (n *sessionNotifier) ResourceUpdated(_ context.Context, uri string) error {
+	return n.server.SendNotificationToSpecificClient(n.sessionID, string(mcp.MethodNotificationResourceUpdated), map[string]any{"uri": uri})
+}
+
+// ResourceListChanged implements watch.Notifier.
+func (n *sessionNotifier) ResourceListChanged(_ context.Context) error {
+	return n.server.SendNotificationToSpecificClient(n.sessionID, string(mcp.MethodNotificationResourcesListChanged), nil)
+}
@@ -0,0 +1,44 @@
+package mcpgolang
+
+import (
+	"reflect"
+
+	mcp "github.com/metoro-io/mcp-golang"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+)
+
+// redactTool wraps handler, a tool handler returning a *mcp.ToolResponse, to
+// redact its responses with redactor.
+func redactTool(redactor *redact.Redactor, handler any) any {
+	if redactor == nil {
+		return handler
+	}
+	h := reflect.ValueOf(handler)
+	return reflect.MakeFunc(h.Type(), func(in []reflect.Value) []reflect.Value {
+		out := h.Call(in)
+		if resp, ok := out[0].Interface().(*mcp.ToolResponse); ok && resp != nil {
+			for _, c := range resp.Content {
+				redactContent(redactor, c)
+			}
+		}
+		return out
+	}).Interface()
+}
+
+// redactContent redacts the text of c in place.
+func redactContent(redactor *redact.Redactor, c *mcp.Content) {
+	switch {
+	case c == nil:
+	case c.TextContent != nil:
+		c.TextContent.Text = redactor.Text(c.TextContent.Text)
+	case c.EmbeddedResource != nil:
+		redactResource(redactor, c.EmbeddedResource)
+	}
+}
+
+// redactResource redacts the text of r in place.
+func redactResource(redactor *redact.Redactor, r *mcp.EmbeddedResource) {
+	if r.TextResourceContents != nil {
+		r.TextResourceContents.Text = redactor.Text(r.TextResourceContents.Text)
+	}
+}
@@ -4,8 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
 
 	mcp "github.com/metoro-io/mcp-golang"
+	"github.com/takak2166/scrapbox-mcp/internal/history"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/prompts"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
 	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
 )
 
@@ -13,29 +22,122 @@ import (
 
 // GetPageArgs represents arguments for the get_page tool
 type GetPageArgs struct {
-	PageTitle string `json:"page_title" jsonschema:"required,description=Page title to retrieve"`
+	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title to retrieve"`
+	Project   *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
 }
 
 // ListPagesArgs represents arguments for the list_pages tool
 type ListPagesArgs struct {
-	// No arguments needed for list_pages
+	Project *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
 }
 
 // SearchPagesArgs represents arguments for the search_pages tool
 type SearchPagesArgs struct {
-	Query string `json:"query" jsonschema:"required,description=Search query"`
+	Query   string  `json:"query" jsonschema:"required,description=Search query"`
+	Project *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
 }
 
 // CreatePageURLArgs represents arguments for the create_page_url tool
 type CreatePageURLArgs struct {
 	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title"`
 	BodyText  *string `json:"body_text" jsonschema:"description=Body text for the new page"`
+	Project   *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// GetPageHistoryArgs represents arguments for the get_page_history tool
+type GetPageHistoryArgs struct {
+	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title"`
+	Project   *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
 }
 
-// RegisterTools registers all Scrapbox tools with the MCP server
-func RegisterTools(server *mcp.Server, client *scrapbox.Client) error {
+// GetPageAtArgs represents arguments for the get_page_at tool
+type GetPageAtArgs struct {
+	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title"`
+	Time      string  `json:"time" jsonschema:"required,description=Point in time as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds"`
+	Project   *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// DiffPageVersionsArgs represents arguments for the diff_page_versions tool
+type DiffPageVersionsArgs struct {
+	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title"`
+	From      string  `json:"from" jsonschema:"required,description=Time of the older version"`
+	To        *string `json:"to" jsonschema:"description=Time of the newer version (defaults to the current page)"`
+	Project   *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// BlamePageArgs represents arguments for the blame_page tool
+type BlamePageArgs struct {
+	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title"`
+	Since     *string `json:"since" jsonschema:"description=Only include lines changed at or after this time (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)"`
+	Project   *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// RecentChangesArgs represents arguments for the recent_changes tool
+type RecentChangesArgs struct {
+	Since    string  `json:"since" jsonschema:"required,description=Start of the period (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)"`
+	MaxChars *int    `json:"max_chars" jsonschema:"description=Maximum total characters of line text to return"`
+	Project  *string `json:"project" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// SearchAllArgs represents arguments for the search_all tool
+type SearchAllArgs struct {
+	Query string `json:"query" jsonschema:"required,description=Search query"`
+}
+
+// ListProjectsArgs represents arguments for the list_projects tool
+type ListProjectsArgs struct {
+	// No arguments needed for list_projects
+}
+
+// SummarizePageArgs represents arguments for the summarize_page prompt
+type SummarizePageArgs struct {
+	PageTitle string `json:"page_title" jsonschema:"required,description=Title of the page to summarize"`
+}
+
+// WeeklyDigestArgs represents arguments for the weekly_digest prompt
+type WeeklyDigestArgs struct {
+	Since *string `json:"since" jsonschema:"description=Start of the period as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds (defaults to 7 days ago)"`
+}
+
+// DraftMeetingNotesArgs represents arguments for the draft_meeting_notes prompt
+type DraftMeetingNotesArgs struct {
+	Topic string `json:"topic" jsonschema:"required,description=Topic of the meeting"`
+}
+
+// AnswerFromProjectArgs represents arguments for the answer_from_project prompt
+type AnswerFromProjectArgs struct {
+	Question string `json:"question" jsonschema:"required,description=Question to answer"`
+}
+
+// toolDef is a tool as passed to RegisterTool, kept to register it again
+// when it is enabled.
+type toolDef struct {
+	name        string
+	description string
+	handler     any
+}
+
+// RegisterTools registers the Scrapbox tools enabled by registry with the MCP
+// server, and registers or deregisters them as that changes. The project
+// argument of every tool selects one of set, the primary project by default.
+// Their responses are redacted by redactor, unless nil.
+func RegisterTools(server *mcp.Server, set *projects.Set, registry *tools.Registry, redactor *redact.Redactor) error {
+	var defs []toolDef
+	register := func(name, description string, handler any) error {
+		handler = checkTool(registry, set, name, redactTool(redactor, handler))
+		defs = append(defs, toolDef{name: name, description: description, handler: handler})
+		if !registry.Enabled(name) {
+			return nil
+		}
+		return server.RegisterTool(name, description, handler)
+	}
+
 	// Register get_page tool
-	err := server.RegisterTool("get_page", "Get a Scrapbox page by title", func(args GetPageArgs) (*mcp.ToolResponse, error) {
+	err := register("get_page", "Get a Scrapbox page by title", func(args GetPageArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
 		page, err := client.GetPage(context.Background(), args.PageTitle)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to get page: %w", err)
@@ -53,7 +155,11 @@ func RegisterTools(server *mcp.Server, client *scrapbox.Client) error {
 	}
 
 	// Register list_pages tool
-	err = server.RegisterTool("list_pages", "Get a list of pages in the project (max 1000 pages)", func(args ListPagesArgs) (*mcp.ToolResponse, error) {
+	err = register("list_pages", "Get a list of pages in the project (max 1000 pages)", func(args ListPagesArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
 		pages, err := client.ListPages(context.Background())
 		if err != nil {
 			return nil, fmt.Errorf("Failed to list pages: %w", err)
@@ -71,7 +177,11 @@ func RegisterTools(server *mcp.Server, client *scrapbox.Client) error {
 	}
 
 	// Register search_pages tool
-	err = server.RegisterTool("search_pages", "Full-text search across all pages in the project (max 100 pages)", func(args SearchPagesArgs) (*mcp.ToolResponse, error) {
+	err = register("search_pages", "Full-text search across all pages in the project (max 100 pages)", func(args SearchPagesArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
 		pages, err := client.SearchPages(context.Background(), args.Query)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to search pages: %w", err)
@@ -89,7 +199,11 @@ func RegisterTools(server *mcp.Server, client *scrapbox.Client) error {
 	}
 
 	// Register create_page_url tool
-	err = server.RegisterTool("create_page_url", "Generate a URL for creating a new page", func(args CreatePageURLArgs) (*mcp.ToolResponse, error) {
+	err = register("create_page_url", "Generate a URL for creating a new page", func(args CreatePageURLArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
 		bodyText := ""
 		if args.BodyText != nil {
 			bodyText = *args.BodyText
@@ -106,5 +220,294 @@ func RegisterTools(server *mcp.Server, client *scrapbox.Client) error {
 		return fmt.Errorf("Failed to register create_page_url tool: %w", err)
 	}
 
+	// Register get_page_history tool
+	err = register("get_page_history", "List the saved snapshots and commits of a page, newest first", func(args GetPageHistoryArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
+		h, err := history.GetHistory(context.Background(), client, args.PageTitle)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get page history: %w", err)
+		}
+		return jsonResponse(h)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register get_page_history tool: %w", err)
+	}
+
+	// Register get_page_at tool
+	err = register("get_page_at", "Get the content of a page as it was at a point in time", func(args GetPageAtArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
+		at, err := history.ParseTime(args.Time)
+		if err != nil {
+			return nil, err
+		}
+		v, err := history.PageAt(context.Background(), client, args.PageTitle, at)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get page version: %w", err)
+		}
+		return jsonResponse(v)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register get_page_at tool: %w", err)
+	}
+
+	// Register diff_page_versions tool
+	err = register("diff_page_versions", "Show a unified diff between two versions of a page", func(args DiffPageVersionsArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
+		from, err := history.ParseTime(args.From)
+		if err != nil {
+			return nil, err
+		}
+		var to time.Time
+		if args.To != nil && *args.To != "" {
+			if to, err = history.ParseTime(*args.To); err != nil {
+				return nil, err
+			}
+		}
+		d, err := history.DiffVersions(context.Background(), client, args.PageTitle, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to diff page versions: %w", err)
+		}
+		return jsonResponse(d)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register diff_page_versions tool: %w", err)
+	}
+
+	// Register blame_page tool
+	err = register("blame_page", "Show who last edited each line of a page, grouped by author and editing session", func(args BlamePageArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
+		var since time.Time
+		if args.Since != nil && *args.Since != "" {
+			if since, err = history.ParseTime(*args.Since); err != nil {
+				return nil, err
+			}
+		}
+		b, err := history.Blame(context.Background(), client, args.PageTitle, since)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to blame page: %w", err)
+		}
+		return jsonResponse(b)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register blame_page tool: %w", err)
+	}
+
+	// Register recent_changes tool
+	err = register("recent_changes", "List pages updated since a time with the lines each author added or modified", func(args RecentChangesArgs) (*mcp.ToolResponse, error) {
+		client, err := clientFor(set, args.Project)
+		if err != nil {
+			return nil, err
+		}
+		since, err := history.ParseTime(args.Since)
+		if err != nil {
+			return nil, err
+		}
+		maxChars := 0
+		if args.MaxChars != nil {
+			maxChars = *args.MaxChars
+		}
+		a, err := history.RecentChanges(context.Background(), client, since, maxChars)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list recent changes: %w", err)
+		}
+		return jsonResponse(a)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register recent_changes tool: %w", err)
+	}
+
+	// Register search_all tool
+	err = register("search_all", "Full-text search across every project at once, with the project of each page and the projects whose search failed", func(args SearchAllArgs) (*mcp.ToolResponse, error) {
+		r, err := set.SearchAll(context.Background(), args.Query, 0)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to search projects: %w", err)
+		}
+		return jsonResponse(r)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register search_all tool: %w", err)
+	}
+
+	// Register list_projects tool
+	err = register("list_projects", "List the Scrapbox projects served, which the project argument of the other tools selects", func(args ListProjectsArgs) (*mcp.ToolResponse, error) {
+		return jsonResponse(set.List())
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register list_projects tool: %w", err)
+	}
+
+	registry.OnChange(func() {
+		syncTools(server, registry, defs)
+	})
 	return nil
 }
+
+// checkTool wraps handler, a tool handler taking an argument structure, to
+// reject the calls that registry disables in the project of set selected by
+// the Project field of the arguments, if any.
+func checkTool(registry *tools.Registry, set *projects.Set, name string, handler any) any {
+	h := reflect.ValueOf(handler)
+	checked := tools.Wrap(registry, name, func(_ context.Context, in []reflect.Value) (tools.Call, error) {
+		call := tools.Call{Primary: set.Primary().ProjectName()}
+		if f := in[0].FieldByName("Project"); f.IsValid() && !f.IsNil() {
+			call.Project = f.Elem().String()
+		}
+		return call, nil
+	}, func(_ context.Context, in []reflect.Value) ([]reflect.Value, error) {
+		return h.Call(in), nil
+	})
+	return reflect.MakeFunc(h.Type(), func(in []reflect.Value) []reflect.Value {
+		out, err := checked(context.Background(), in)
+		if err != nil {
+			return []reflect.Value{reflect.Zero(h.Type().Out(0)), reflect.ValueOf(&err).Elem()}
+		}
+		return out
+	}).Interface()
+}
+
+// syncTools registers the tools of defs that registry enables and
+// deregisters the others, each change notifying the client.
+func syncTools(server *mcp.Server, registry *tools.Registry, defs []toolDef) {
+	for _, d := range defs {
+		var err error
+		switch enabled, registered := registry.Enabled(d.name), server.CheckToolRegistered(d.name); {
+		case enabled && !registered:
+			err = server.RegisterTool(d.name, d.description, d.handler)
+		case !enabled && registered:
+			err = server.DeregisterTool(d.name)
+		}
+		if err != nil {
+			slog.Default().Warn("failed to update tool", "tool", d.name, "error", err)
+		}
+	}
+}
+
+// clientFor returns the client of the project selected by a project argument
+func clientFor(set *projects.Set, project *string) (*scrapbox.Client, error) {
+	if project == nil {
+		return set.Primary(), nil
+	}
+	return set.Client(*project)
+}
+
+// jsonResponse returns v marshalled as JSON text content
+func jsonResponse(v any) (*mcp.ToolResponse, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(b))), nil
+}
+
+// RegisterResources registers the scrapbox:// resource template and the
+// pinned and recently updated pages as resources. mcp-golang can only read
+// resources registered up front, so the page list is taken once at startup.
+// Resources and their descriptions are redacted by redactor, unless nil.
+func RegisterResources(ctx context.Context, server *mcp.Server, client *scrapbox.Client, redactor *redact.Redactor) error {
+	err := server.RegisterResourceTemplate(resources.URITemplate, "page", "A page of the Scrapbox project as Markdown. Append ?format=json for the page JSON.", resources.MIMEMarkdown)
+	if err != nil {
+		return fmt.Errorf("Failed to register resource template: %w", err)
+	}
+
+	list, err := resources.List(ctx, client, resources.DefaultLimit)
+	if err != nil {
+		return fmt.Errorf("Failed to list resources: %w", err)
+	}
+	for _, r := range list {
+		uri := r.URI
+		err := server.RegisterResource(uri, r.Name, redactor.Redact(r.Description), r.MIMEType, func(ctx context.Context) (*mcp.ResourceResponse, error) {
+			c, err := resources.Read(ctx, client, uri)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read resource: %w", err)
+			}
+			return mcp.NewResourceResponse(mcp.NewTextEmbeddedResource(c.URI, redactor.Text(c.Text), c.MIMEType)), nil
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to register resource %s: %w", uri, err)
+		}
+	}
+	return nil
+}
+
+// RegisterPrompts registers the prompts for common Scrapbox workflows, whose
+// messages are redacted by redactor, unless nil.
+func RegisterPrompts(server *mcp.Server, client *scrapbox.Client, redactor *redact.Redactor) error {
+	description := func(name string) string {
+		for _, p := range prompts.Prompts {
+			if p.Name == name {
+				return p.Description
+			}
+		}
+		return ""
+	}
+	get := func(name string, args map[string]string) (*mcp.PromptResponse, error) {
+		r, err := prompts.Get(context.Background(), client, name, args)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get prompt: %w", err)
+		}
+		resp := promptResponse(r)
+		for _, m := range resp.Messages {
+			redactContent(redactor, m.Content)
+		}
+		return resp, nil
+	}
+
+	err := server.RegisterPrompt(prompts.SummarizePage, description(prompts.SummarizePage), func(args SummarizePageArgs) (*mcp.PromptResponse, error) {
+		return get(prompts.SummarizePage, map[string]string{"page_title": args.PageTitle})
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register summarize_page prompt: %w", err)
+	}
+
+	err = server.RegisterPrompt(prompts.WeeklyDigest, description(prompts.WeeklyDigest), func(args WeeklyDigestArgs) (*mcp.PromptResponse, error) {
+		promptArgs := map[string]string{}
+		if args.Since != nil {
+			promptArgs["since"] = *args.Since
+		}
+		return get(prompts.WeeklyDigest, promptArgs)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register weekly_digest prompt: %w", err)
+	}
+
+	err = server.RegisterPrompt(prompts.DraftMeetingNotes, description(prompts.DraftMeetingNotes), func(args DraftMeetingNotesArgs) (*mcp.PromptResponse, error) {
+		return get(prompts.DraftMeetingNotes, map[string]string{"topic": args.Topic})
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register draft_meeting_notes prompt: %w", err)
+	}
+
+	err = server.RegisterPrompt(prompts.AnswerFromProject, description(prompts.AnswerFromProject), func(args AnswerFromProjectArgs) (*mcp.PromptResponse, error) {
+		return get(prompts.AnswerFromProject, map[string]string{"question": args.Question})
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register answer_from_project prompt: %w", err)
+	}
+
+	return nil
+}
+
+// promptResponse converts a prompt result, embedding pages as resources
+func promptResponse(r *prompts.Result) *mcp.PromptResponse {
+	var messages []*mcp.PromptMessage
+	for _, m := range r.Messages {
+		content := mcp.NewTextContent(m.Text)
+		if m.Resource != nil {
+			content = mcp.NewTextResourceContent(m.Resource.URI, m.Resource.Text, m.Resource.MIMEType)
+		}
+		messages = append(messages, mcp.NewPromptMessage(content, mcp.RoleUser))
+	}
+	return mcp.NewPromptResponse(r.Description, messages...)
+}
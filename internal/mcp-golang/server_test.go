@@ -0,0 +1,72 @@
+package mcpgolang
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	mcp "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport/stdio"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+)
+
+func TestRegisterTools_ToolPolicy(t *testing.T) {
+	set := projects.FromConfig(&config.Config{ProjectName: "main", Projects: []config.Project{{Name: "sandbox"}}})
+	registry := tools.NewRegistry(tools.Policy{ReadOnly: true, Deny: []string{"search_all"}}, map[string]tools.Policy{
+		"sandbox": {Deny: []string{"search_all"}},
+	})
+	server := mcp.NewServer(stdio.NewStdioServerTransport())
+	if err := RegisterTools(server, set, registry, nil); err != nil {
+		t.Fatalf("RegisterTools() error = %v", err)
+	}
+
+	registered := func() []string {
+		var names []string
+		for _, name := range tools.Names {
+			if server.CheckToolRegistered(name) {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	want := []string{"get_page", "list_pages", "search_pages", "create_page_url", "get_page_history", "get_page_at", "diff_page_versions", "blame_page", "recent_changes", "list_projects"}
+	if diff := cmp.Diff(want, registered()); diff != "" {
+		t.Errorf("registered tools mismatch (-want +got):\n%s", diff)
+	}
+
+	registry.SetPolicy(tools.Policy{Allow: []string{"get_page", "list_projects"}}, nil)
+	if diff := cmp.Diff([]string{"get_page", "list_projects"}, registered()); diff != "" {
+		t.Errorf("registered tools after SetPolicy mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckTool(t *testing.T) {
+	registry := tools.NewRegistry(tools.Policy{ReadOnly: true}, map[string]tools.Policy{"sandbox": {}})
+	set := projects.FromConfig(&config.Config{ProjectName: "main", Projects: []config.Project{{Name: "sandbox"}}})
+	handler := checkTool(registry, set, "create_page_url", func(args CreatePageURLArgs) (*mcp.ToolResponse, error) {
+		return mcp.NewToolResponse(mcp.NewTextContent(args.PageTitle)), nil
+	}).(func(CreatePageURLArgs) (*mcp.ToolResponse, error))
+
+	sandbox, primary := "sandbox", "main"
+	tests := map[string]struct {
+		project *string
+		wantErr bool
+	}{
+		"ok: writable project":           {project: &sandbox},
+		"err: primary project":           {wantErr: true},
+		"err: read-only project by name": {project: &primary, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := handler(CreatePageURLArgs{PageTitle: "Draft", Project: tt.project})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("handler error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (resp != nil) == tt.wantErr {
+				t.Errorf("handler response = %v, wantErr %v", resp, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Package mirror keeps a local git repository with one text file per page
+// and records every page change as a commit authored by the page's last
+// editor at the page's updated time.
+//
+// Only local git commands are run; the repository needs no remote.
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/export"
+	"github.com/takak2166/scrapbox-mcp/internal/pagestore"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// stateFile lives inside .git so that it is never committed.
+const stateFile = "scrapbox-mirror.json"
+
+// fallbackAuthor is used for commits that have no Scrapbox user, such as deletions.
+const fallbackAuthor = "scrapbox-mirror"
+
+type stateEntry struct {
+	File    string `json:"file"`
+	Title   string `json:"title"`
+	Updated int64  `json:"updated"`
+}
+
+// Result reports the commits made by a Sync.
+type Result struct {
+	Commits int
+}
+
+// Mirror is a git working tree mirroring a page store.
+type Mirror struct {
+	Store *pagestore.Store
+	Dir   string
+	// Now returns the time used for commits without a page timestamp.
+	// It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Sync commits every page that changed in the store since the previous sync,
+// oldest change first, and commits the removal of deleted pages.
+func (m *Mirror) Sync(ctx context.Context) (*Result, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+	state, err := m.readState()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := m.Store.Entries()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Updated < entries[j].Updated })
+
+	used := map[string]string{}
+	for id, s := range state {
+		used[strings.ToLower(s.File)] = id
+	}
+
+	result := &Result{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		seen[entry.ID] = true
+		prev, ok := state[entry.ID]
+		if ok && prev.Updated == entry.Updated && prev.Title == entry.Title {
+			continue
+		}
+		page, err := m.Store.Page(entry.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		file := prev.File
+		if !ok || prev.Title != entry.Title {
+			file = uniqueFileName(entry.Title, entry.ID, used)
+		}
+		if ok && prev.File != file {
+			if err := m.git(ctx, nil, "rm", "-q", "--ignore-unmatch", "--", prev.File); err != nil {
+				return nil, err
+			}
+			delete(used, strings.ToLower(prev.File))
+		}
+		used[strings.ToLower(file)] = entry.ID
+
+		if err := os.WriteFile(filepath.Join(m.Dir, file), []byte(pageText(page)), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		if err := m.git(ctx, nil, "add", "--", file); err != nil {
+			return nil, err
+		}
+
+		message := "Update " + entry.Title
+		switch {
+		case !ok:
+			message = "Create " + entry.Title
+		case prev.Title != entry.Title:
+			message = fmt.Sprintf("Rename %s to %s", prev.Title, entry.Title)
+		}
+		when := time.Unix(entry.Updated, 0)
+		if entry.Updated == 0 {
+			when = m.now()
+		}
+		committed, err := m.commit(ctx, message, editor(page), when)
+		if err != nil {
+			return nil, err
+		}
+		if committed {
+			result.Commits++
+		}
+		state[entry.ID] = stateEntry{File: file, Title: entry.Title, Updated: entry.Updated}
+		if err := m.writeState(state); err != nil {
+			return nil, err
+		}
+	}
+
+	var deleted []string
+	for id := range state {
+		if !seen[id] {
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return state[deleted[i]].Title < state[deleted[j]].Title })
+	for _, id := range deleted {
+		s := state[id]
+		if err := m.git(ctx, nil, "rm", "-q", "--ignore-unmatch", "--", s.File); err != nil {
+			return nil, err
+		}
+		committed, err := m.commit(ctx, "Delete "+s.Title, nil, m.now())
+		if err != nil {
+			return nil, err
+		}
+		if committed {
+			result.Commits++
+		}
+		delete(state, id)
+		if err := m.writeState(state); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (m *Mirror) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// init creates the working tree and repository if they do not exist yet.
+func (m *Mirror) init(ctx context.Context) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(m.Dir, ".git")); err == nil {
+		return nil
+	}
+	return m.git(ctx, nil, "init", "-q")
+}
+
+// commit commits the staged changes, reporting false if nothing was staged.
+func (m *Mirror) commit(ctx context.Context, message string, author *scrapbox.User, when time.Time) (bool, error) {
+	if err := m.git(ctx, nil, "diff", "--cached", "--quiet"); err == nil {
+		return false, nil
+	}
+	name, email := fallbackAuthor, fallbackAuthor+"@localhost"
+	if author != nil {
+		name = firstNonEmpty(author.DisplayName, author.Name, author.ID)
+		email = firstNonEmpty(author.Name, author.ID) + "@users.scrapbox.invalid"
+	}
+	date := "@" + strconv.FormatInt(when.Unix(), 10) + " +0000"
+	env := []string{
+		"GIT_AUTHOR_NAME=" + name,
+		"GIT_AUTHOR_EMAIL=" + email,
+		"GIT_AUTHOR_DATE=" + date,
+		"GIT_COMMITTER_NAME=" + name,
+		"GIT_COMMITTER_EMAIL=" + email,
+		"GIT_COMMITTER_DATE=" + date,
+	}
+	if err := m.git(ctx, env, "-c", "commit.gpgsign=false", "commit", "-q", "--no-verify", "-m", message); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// git runs a git command in the working tree with extra environment.
+func (m *Mirror) git(ctx context.Context, env []string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = m.Dir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (m *Mirror) readState() (map[string]stateEntry, error) {
+	state := map[string]stateEntry{}
+	b, err := os.ReadFile(filepath.Join(m.Dir, ".git", stateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read mirror state: %w", err)
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode mirror state: %w", err)
+	}
+	return state, nil
+}
+
+func (m *Mirror) writeState(state map[string]stateEntry) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal mirror state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(m.Dir, ".git", stateFile), b, 0o644); err != nil {
+		return fmt.Errorf("failed to write mirror state: %w", err)
+	}
+	return nil
+}
+
+// uniqueFileName returns a .txt file name for title that is not used by
+// another page. used maps lower-cased file names to page IDs.
+func uniqueFileName(title, id string, used map[string]string) string {
+	base := export.SanitizeFileName(title)
+	name := base + ".txt"
+	for i := 2; ; i++ {
+		if owner, ok := used[strings.ToLower(name)]; !ok || owner == id {
+			return name
+		}
+		name = base + " (" + strconv.Itoa(i) + ").txt"
+	}
+}
+
+// pageText returns the page as plain Scrapbox text, one line per line.
+func pageText(page *scrapbox.Page) string {
+	var b strings.Builder
+	for _, l := range page.Lines {
+		b.WriteString(l.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// editor returns the user who last changed the page.
+func editor(page *scrapbox.Page) *scrapbox.User {
+	if page.LastUpdateUser != nil {
+		return page.LastUpdateUser
+	}
+	return page.User
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package mirror
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/errors"
+	"github.com/takak2166/scrapbox-mcp/internal/pagestore"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+type fakeSource struct {
+	pages []*scrapbox.Page
+}
+
+func (f *fakeSource) ListAllPages(ctx context.Context) ([]scrapbox.Page, error) {
+	var list []scrapbox.Page
+	for _, p := range f.pages {
+		list = append(list, scrapbox.Page{ID: p.ID, Title: p.Title, Updated: p.Updated})
+	}
+	return list, nil
+}
+
+func (f *fakeSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	for _, p := range f.pages {
+		if p.Title == title {
+			return p, nil
+		}
+	}
+	return nil, &errors.ScrapboxError{Code: errors.ErrNotFound, Message: "unexpected status code"}
+}
+
+func gitLog(t *testing.T, dir string) []string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", dir, "log", "--reverse", "--format=%an|%at|%s").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
+
+func TestMirror_Sync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	store, err := pagestore.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	alice := &scrapbox.User{ID: "1", Name: "alice", DisplayName: "Alice"}
+	bob := &scrapbox.User{ID: "2", Name: "bob", DisplayName: "Bob"}
+	src := &fakeSource{pages: []*scrapbox.Page{
+		{ID: "b", Title: "B", Updated: 200, LastUpdateUser: bob, Lines: []scrapbox.Line{{Text: "B"}}},
+		{ID: "a", Title: "A", Updated: 100, LastUpdateUser: alice, Lines: []scrapbox.Line{{Text: "A"}, {Text: "body"}}},
+	}}
+	if _, err := store.Sync(ctx, src); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	m := &Mirror{Store: store, Dir: dir, Now: func() time.Time { return time.Unix(400, 0) }}
+	res, err := m.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Mirror.Sync() error = %v", err)
+	}
+	if res.Commits != 2 {
+		t.Errorf("first Mirror.Sync() commits = %d, want 2", res.Commits)
+	}
+
+	// Rename A, delete B and sync again.
+	src.pages = []*scrapbox.Page{
+		{ID: "a", Title: "A2", Updated: 300, LastUpdateUser: bob, Lines: []scrapbox.Line{{Text: "A2"}, {Text: "body"}}},
+	}
+	if _, err := store.Sync(ctx, src); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if _, err := m.Sync(ctx); err != nil {
+		t.Fatalf("Mirror.Sync() error = %v", err)
+	}
+	// A sync without changes commits nothing.
+	res, err = m.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Mirror.Sync() error = %v", err)
+	}
+	if res.Commits != 0 {
+		t.Errorf("idle Mirror.Sync() commits = %d, want 0", res.Commits)
+	}
+
+	want := []string{
+		"Alice|100|Create A",
+		"Bob|200|Create B",
+		"Bob|300|Rename A to A2",
+		"scrapbox-mirror|400|Delete B",
+	}
+	if diff := cmp.Diff(want, gitLog(t, dir)); diff != "" {
+		t.Errorf("git log mismatch (-want +got):\n%s", diff)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "A2.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if diff := cmp.Diff("A2\nbody\n", string(b)); diff != "" {
+		t.Errorf("A2.txt mismatch (-want +got):\n%s", diff)
+	}
+	for _, name := range []string{"A.txt", "B.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", name)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package officialmcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const methodComplete = "completion/complete"
+
+// completeParams are the parameters of completion/complete. Prompts are
+// referenced by name and resource templates by URI.
+type completeParams struct {
+	Ref struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+		URI  string `json:"uri"`
+	} `json:"ref"`
+	Argument struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"argument"`
+}
+
+// completeResult is the result of completion/complete.
+type completeResult struct {
+	Completion completionValues `json:"completion"`
+}
+
+// completionValues are the completions of an argument.
+type completionValues struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+// handleComplete answers a completion/complete request from the completion
+// cache of the session.
+func (c *serverConn) handleComplete(ctx context.Context, req *mcp.JSONRPCRequest) (*completeResult, error) {
+	var params completeParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Ref.Type == "" || params.Argument.Name == "" {
+		return nil, fmt.Errorf("Invalid %s params: a ref and an argument are required", req.Method)
+	}
+	refName := params.Ref.Name
+	if refName == "" {
+		refName = params.Ref.URI
+	}
+	result, err := c.completer.Complete(ctx, params.Ref.Type, refName, params.Argument.Name, params.Argument.Value)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to complete: %w", err)
+	}
+	return &completeResult{Completion: completionValues{Values: result.Values, Total: result.Total, HasMore: result.HasMore}}, nil
+}
@@ -0,0 +1,318 @@
+package officialmcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/auth"
+	"github.com/takak2166/scrapbox-mcp/internal/tenant"
+)
+
+// The HTTP handlers of the SDK connect sessions themselves, which would
+// bypass serverTransport. These handlers follow the SDK's but connect every
+// session through it, so that subscriptions and completions work over HTTP.
+
+const headerSessionID = "Mcp-Session-Id"
+
+// sessionIdleTimeout is how long a Streamable HTTP session without requests
+// is kept before it is closed.
+const sessionIdleTimeout = time.Hour
+
+// StreamableHTTPHandler returns a handler serving sessions over the
+// Streamable HTTP transport. Sessions are created by a POST without an
+// Mcp-Session-Id header and ended by a DELETE, or closed once unused for an
+// hour.
+func (s *Server) StreamableHTTPHandler() http.Handler {
+	return &streamableHandler{server: s, idleTimeout: sessionIdleTimeout, sessions: make(map[string]*streamableSession)}
+}
+
+type streamableHandler struct {
+	server      *Server
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*streamableSession
+}
+
+// streamableSession is a session of a streamableHandler.
+type streamableSession struct {
+	transport *mcp.StreamableServerTransport
+	session   *mcp.ServerSession
+	// subject is the caller that created the session, see callerSubject.
+	subject string
+	// timer closes the session once idle. It is stopped while requests,
+	// which include the event stream of a GET, are in flight.
+	timer  *time.Timer
+	active int
+}
+
+func (h *streamableHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var jsonOK, streamOK bool
+	for _, c := range strings.Split(strings.Join(req.Header.Values("Accept"), ","), ",") {
+		switch strings.TrimSpace(c) {
+		case "application/json":
+			jsonOK = true
+		case "text/event-stream":
+			streamOK = true
+		}
+	}
+	switch req.Method {
+	case http.MethodGet:
+		if !streamOK {
+			http.Error(w, "Accept must contain 'text/event-stream' for GET requests", http.StatusBadRequest)
+			return
+		}
+	case http.MethodPost:
+		if !jsonOK || !streamOK {
+			http.Error(w, "Accept must contain both 'application/json' and 'text/event-stream'", http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := req.Header.Get(headerSessionID)
+	if id == "" {
+		if req.Method != http.MethodPost {
+			http.Error(w, req.Method+" requires an Mcp-Session-Id header", http.StatusBadRequest)
+			return
+		}
+		id = newSessionID()
+		ctx, err := h.server.sessionContext(req, id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		t := mcp.NewStreamableServerTransport(id)
+		ss, err := h.server.connect(ctx, t)
+		if err != nil {
+			http.Error(w, "failed connection", http.StatusInternalServerError)
+			return
+		}
+		sess := &streamableSession{transport: t, session: ss, subject: callerSubject(req), active: 1}
+		sess.timer = time.AfterFunc(h.idleTimeout, func() { h.expire(id, sess) })
+		sess.timer.Stop()
+		h.mu.Lock()
+		h.sessions[id] = sess
+		h.mu.Unlock()
+		defer h.release(sess)
+		t.ServeHTTP(w, req)
+		return
+	}
+
+	sess := h.acquire(id)
+	if sess == nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	defer h.release(sess)
+	if sess.subject != callerSubject(req) {
+		http.Error(w, errSessionCaller.Error(), http.StatusForbidden)
+		return
+	}
+	if _, err := h.server.sessionContext(req, id); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if req.Method == http.MethodDelete {
+		h.mu.Lock()
+		delete(h.sessions, id)
+		h.mu.Unlock()
+		h.close(id, sess)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	sess.transport.ServeHTTP(w, req)
+}
+
+// acquire returns session id, or nil if there is none, and holds off its
+// idle timeout until release is called.
+func (h *streamableHandler) acquire(id string) *streamableSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sess := h.sessions[id]
+	if sess != nil {
+		sess.active++
+		sess.timer.Stop()
+	}
+	return sess
+}
+
+// release ends a request of sess, starting its idle timeout once it has no
+// more requests in flight.
+func (h *streamableHandler) release(sess *streamableSession) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sess.active--
+	if sess.active == 0 {
+		sess.timer.Reset(h.idleTimeout)
+	}
+}
+
+// expire closes session id, sess, unless it was used or ended meanwhile.
+func (h *streamableHandler) expire(id string, sess *streamableSession) {
+	h.mu.Lock()
+	if h.sessions[id] != sess || sess.active > 0 {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.sessions, id)
+	h.mu.Unlock()
+	h.close(id, sess)
+}
+
+// close closes session id, sess, which stops its watcher, and drops its
+// Tenant.
+func (h *streamableHandler) close(id string, sess *streamableSession) {
+	sess.timer.Stop()
+	_ = sess.session.Close()
+	sess.transport.Close()
+	h.server.closeTenant(id)
+}
+
+// SSEHandler returns a handler serving sessions over the SSE transport of
+// the 2024-11-05 protocol for older clients. A GET opens a session, which
+// lasts as long as the request, and messages are POSTed to the endpoint
+// announced on its event stream.
+func (s *Server) SSEHandler() http.Handler {
+	return &sseHandler{server: s, sessions: make(map[string]*sseSession)}
+}
+
+type sseHandler struct {
+	server *Server
+
+	mu       sync.Mutex
+	sessions map[string]*sseSession
+}
+
+// sseSession is a session of an sseHandler.
+type sseSession struct {
+	transport *mcp.SSEServerTransport
+	// subject is the caller that created the session, see callerSubject.
+	subject string
+}
+
+func (h *sseHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		id := req.URL.Query().Get("sessionid")
+		h.mu.Lock()
+		sess := h.sessions[id]
+		h.mu.Unlock()
+		if sess == nil {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		if sess.subject != callerSubject(req) {
+			http.Error(w, errSessionCaller.Error(), http.StatusForbidden)
+			return
+		}
+		if _, err := h.server.sessionContext(req, id); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		sess.transport.ServeHTTP(w, req)
+		return
+	case http.MethodGet:
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	id := newSessionID()
+	ctx, err := h.server.sessionContext(req, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	defer h.server.closeTenant(id)
+	endpoint, err := req.URL.Parse("?sessionid=" + id)
+	if err != nil {
+		http.Error(w, "failed to create endpoint", http.StatusInternalServerError)
+		return
+	}
+	t := mcp.NewSSEServerTransport(endpoint.RequestURI(), w)
+	h.mu.Lock()
+	h.sessions[id] = &sseSession{transport: t, subject: callerSubject(req)}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, id)
+		h.mu.Unlock()
+	}()
+
+	ss, err := h.server.connect(ctx, t)
+	if err != nil {
+		http.Error(w, "failed connection", http.StatusInternalServerError)
+		return
+	}
+	defer ss.Close()
+
+	done := make(chan struct{})
+	go func() {
+		_ = ss.Wait()
+		close(done)
+	}()
+	select {
+	case <-req.Context().Done():
+	case <-done:
+	}
+}
+
+// sessionContext returns the context to connect session id with: in
+// multi-tenant mode it carries the Tenant of the session, which must belong
+// to the caller of req.
+func (s *Server) sessionContext(req *http.Request, id string) (context.Context, error) {
+	if s.tenants == nil {
+		return req.Context(), nil
+	}
+	t, err := s.tenants.Session(req.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	return tenant.NewContext(req.Context(), t), nil
+}
+
+// errSessionCaller is returned for requests to a session created by another
+// caller.
+var errSessionCaller = errors.New("session belongs to another caller")
+
+// callerSubject returns the subject of the authenticated caller of req, or
+// "" when authentication is disabled. A session may only be used by the
+// caller that created it, also in single-tenant mode, where the Tenant does
+// not tie it to the caller.
+func callerSubject(req *http.Request) string {
+	if id, ok := auth.FromContext(req.Context()); ok {
+		return id.Subject
+	}
+	return ""
+}
+
+// closeTenant drops the Tenant of session id in multi-tenant mode.
+func (s *Server) closeTenant(id string) {
+	if s.tenants != nil {
+		s.tenants.CloseSession(id)
+	}
+}
+
+// newSessionID returns a random session ID.
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
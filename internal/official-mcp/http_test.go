@@ -0,0 +1,287 @@
+package officialmcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/auth"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+const (
+	initializeRequest  = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+	initializedMessage = `{"jsonrpc":"2.0","method":"notifications/initialized","params":{}}`
+	getPageRequest     = `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_page","arguments":{"page_title":"Page"}}}`
+)
+
+// rpcMessage is a JSON-RPC response or notification read from a stream.
+type rpcMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readEvents returns a channel receiving the data of every server-sent
+// event of r, by event type, until r ends.
+func readEvents(r io.Reader) <-chan [2]string {
+	events := make(chan [2]string, 16)
+	go func() {
+		defer close(events)
+		sc := bufio.NewScanner(r)
+		event := "message"
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				events <- [2]string{event, strings.TrimPrefix(line, "data: ")}
+				event = "message"
+			}
+		}
+	}()
+	return events
+}
+
+// nextResponse returns the next message of events answering the request
+// with ID id.
+func nextResponse(t *testing.T, events <-chan [2]string, id string) rpcMessage {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("stream ended before the response to request %s", id)
+			}
+			var msg rpcMessage
+			if err := json.Unmarshal([]byte(e[1]), &msg); err != nil {
+				t.Fatalf("invalid message %q: %v", e[1], err)
+			}
+			if string(msg.ID) == id {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("no response to request %s", id)
+		}
+	}
+}
+
+// checkGetPage fails unless msg is a successful get_page result for Page.
+func checkGetPage(t *testing.T, msg rpcMessage) {
+	t.Helper()
+	if msg.Error != nil {
+		t.Fatalf("tools/call error = %s", msg.Error.Message)
+	}
+	var result struct {
+		IsError bool `json:"isError"`
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(msg.Result, &result); err != nil || result.IsError || len(result.Content) == 0 || !strings.Contains(result.Content[0].Text, "Page") {
+		t.Fatalf("tools/call result = %s, want the page", msg.Result)
+	}
+}
+
+// newHTTPTestServer returns a Server whose project is served by a fake
+// Scrapbox API.
+func newHTTPTestServer(t *testing.T) *Server {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"1","title":"Page","lines":[{"text":"Page"},{"text":"body"}]}`))
+	}))
+	t.Cleanup(api.Close)
+	set := projects.FromConfig(&config.Config{ProjectName: "main"}, scrapbox.WithBaseURL(api.URL))
+	return NewServer(set, watch.Options{}, nil, tools.NewRegistry(tools.Policy{}, nil), nil)
+}
+
+func TestStreamableHandler(t *testing.T) {
+	ts := httptest.NewServer(newHTTPTestServer(t).StreamableHTTPHandler())
+	defer ts.Close()
+
+	send := func(method, id, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		req.Header.Set("Content-Type", "application/json")
+		if id != "" {
+			req.Header.Set(headerSessionID, id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s error = %v", method, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// Each POST is a request of its own, so the session must outlive the
+	// request that created it.
+	resp := send(http.MethodPost, "", initializeRequest)
+	id := resp.Header.Get(headerSessionID)
+	if resp.StatusCode != http.StatusOK || id == "" {
+		t.Fatalf("initialize status = %d, session = %q", resp.StatusCode, id)
+	}
+	if msg := nextResponse(t, readEvents(resp.Body), "1"); msg.Error != nil {
+		t.Fatalf("initialize error = %s", msg.Error.Message)
+	}
+	if resp := send(http.MethodPost, id, initializedMessage); resp.StatusCode >= 300 {
+		t.Fatalf("notifications/initialized status = %d", resp.StatusCode)
+	}
+	resp = send(http.MethodPost, id, getPageRequest)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("tools/call status = %d", resp.StatusCode)
+	}
+	checkGetPage(t, nextResponse(t, readEvents(resp.Body), "2"))
+
+	if resp := send(http.MethodDelete, id, ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if resp := send(http.MethodPost, id, getPageRequest); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("tools/call after DELETE status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestSSEHandler(t *testing.T) {
+	ts := httptest.NewServer(newHTTPTestServer(t).SSEHandler())
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer stream.Body.Close()
+	events := readEvents(stream.Body)
+
+	var endpoint string
+	select {
+	case e := <-events:
+		if e[0] != "endpoint" {
+			t.Fatalf("first event = %v, want the endpoint", e)
+		}
+		endpoint = ts.URL + e[1]
+	case <-time.After(5 * time.Second):
+		t.Fatal("no endpoint event")
+	}
+	post := func(body string) {
+		t.Helper()
+		resp, err := http.Post(endpoint, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			t.Fatalf("POST status = %d", resp.StatusCode)
+		}
+	}
+
+	post(initializeRequest)
+	if msg := nextResponse(t, events, "1"); msg.Error != nil {
+		t.Fatalf("initialize error = %s", msg.Error.Message)
+	}
+	post(initializedMessage)
+	post(getPageRequest)
+	checkGetPage(t, nextResponse(t, events, "2"))
+}
+
+func TestHTTPHandlers_SessionCaller(t *testing.T) {
+	s := newHTTPTestServer(t)
+	// The caller is authenticated as the subject in the X-Subject header.
+	withCaller := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := &auth.Identity{Subject: r.Header.Get("X-Subject")}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), id)))
+		})
+	}
+	post := func(url, session, subject, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Subject", subject)
+		if session != "" {
+			req.Header.Set(headerSessionID, session)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST error = %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("streamable", func(t *testing.T) {
+		ts := httptest.NewServer(withCaller(s.StreamableHTTPHandler()))
+		defer ts.Close()
+
+		id := post(ts.URL, "", "alice", initializeRequest).Header.Get(headerSessionID)
+		if id == "" {
+			t.Fatal("initialize returned no session")
+		}
+		if resp := post(ts.URL, id, "mallory", getPageRequest); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("POST by another caller status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+		}
+		if resp := post(ts.URL, id, "alice", initializedMessage); resp.StatusCode >= 300 {
+			t.Errorf("POST by the creator status = %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("sse", func(t *testing.T) {
+		ts := httptest.NewServer(withCaller(s.SSEHandler()))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Header.Set("X-Subject", "alice")
+		stream, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET error = %v", err)
+		}
+		defer stream.Body.Close()
+		var endpoint string
+		select {
+		case e := <-readEvents(stream.Body):
+			endpoint = ts.URL + e[1]
+		case <-time.After(5 * time.Second):
+			t.Fatal("no endpoint event")
+		}
+
+		if resp := post(endpoint, "", "mallory", initializeRequest); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("POST by another caller status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+		}
+		if resp := post(endpoint, "", "alice", initializeRequest); resp.StatusCode >= 300 {
+			t.Errorf("POST by the creator status = %d", resp.StatusCode)
+		}
+	})
+}
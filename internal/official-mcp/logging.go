@@ -0,0 +1,46 @@
+package officialmcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// loggingMiddleware logs the Scrapbox requests made for each request of a
+// client with a request ID of their own and sends the records logged while
+// serving it to that client.
+func loggingMiddleware(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		return next(withSessionLogger(ctx, session), session, method, params)
+	}
+}
+
+// withSessionLogger returns a copy of ctx whose Scrapbox requests are logged
+// with a request ID of their own and whose records are sent to session,
+// unless nil.
+func withSessionLogger(ctx context.Context, session *mcp.ServerSession) context.Context {
+	ctx = scrapbox.ContextWithRequestID(ctx, scrapbox.NewRequestID())
+	if session == nil {
+		return ctx
+	}
+	return logging.NewContext(ctx, sessionLogger{session})
+}
+
+// sessionLogger sends log messages to a session, which drops them until the
+// client selects a level with logging/setLevel.
+type sessionLogger struct {
+	session *mcp.ServerSession
+}
+
+// Log implements logging.Client.
+func (l sessionLogger) Log(ctx context.Context, level slog.Level, data json.RawMessage) {
+	_ = l.session.Log(ctx, &mcp.LoggingMessageParams{
+		Level:  mcp.LoggingLevel(logging.MCPLevel(level)),
+		Logger: logging.LoggerName,
+		Data:   data,
+	})
+}
@@ -0,0 +1,50 @@
+package officialmcp
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+)
+
+// redactMiddleware redacts the tool results, resources, resource lists and
+// prompts sent to clients with the redactor of the server.
+func (s *Server) redactMiddleware(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		result, err := next(ctx, session, method, params)
+		if err != nil || s.redactor == nil {
+			return result, err
+		}
+		switch r := result.(type) {
+		case *mcp.CallToolResult:
+			for _, c := range r.Content {
+				redactContent(s.redactor, c)
+			}
+		case *mcp.ReadResourceResult:
+			for _, c := range r.Contents {
+				c.Text = s.redactor.Text(c.Text)
+			}
+		case *mcp.ListResourcesResult:
+			for _, res := range r.Resources {
+				res.Description = s.redactor.Redact(res.Description)
+			}
+		case *mcp.GetPromptResult:
+			for _, m := range r.Messages {
+				redactContent(s.redactor, m.Content)
+			}
+		}
+		return result, nil
+	}
+}
+
+// redactContent redacts the text of c in place.
+func redactContent(r *redact.Redactor, c mcp.Content) {
+	switch c := c.(type) {
+	case *mcp.TextContent:
+		c.Text = r.Text(c.Text)
+	case *mcp.EmbeddedResource:
+		if c.Resource != nil {
+			c.Resource.Text = r.Text(c.Resource.Text)
+		}
+	}
+}
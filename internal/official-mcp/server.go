@@ -3,9 +3,20 @@ package officialmcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/completion"
+	"github.com/takak2166/scrapbox-mcp/internal/history"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/prompts"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/internal/tenant"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
 	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
 )
 
@@ -14,41 +25,114 @@ import (
 // GetPageParams represents arguments for the get_page tool
 type GetPageParams struct {
 	PageTitle string `json:"page_title" jsonschema:"required,description=Page title to retrieve"`
+	Project   string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
 }
 
 // ListPagesParams represents arguments for the list_pages tool
 type ListPagesParams struct {
-	// No arguments needed for list_pages
+	Project string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
 }
 
 // SearchPagesParams represents arguments for the search_pages tool
 type SearchPagesParams struct {
-	Query string `json:"query" jsonschema:"required,description=Search query"`
+	Query   string `json:"query" jsonschema:"required,description=Search query"`
+	Project string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
 }
 
 // CreatePageURLParams represents arguments for the create_page_url tool
 type CreatePageURLParams struct {
 	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title"`
 	BodyText  *string `json:"body_text" jsonschema:"description=Body text for the new page"`
+	Project   string  `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// GetPageHistoryParams represents arguments for the get_page_history tool
+type GetPageHistoryParams struct {
+	PageTitle string `json:"page_title" jsonschema:"required,description=Page title"`
+	Project   string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// GetPageAtParams represents arguments for the get_page_at tool
+type GetPageAtParams struct {
+	PageTitle string `json:"page_title" jsonschema:"required,description=Page title"`
+	Time      string `json:"time" jsonschema:"required,description=Point in time as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds"`
+	Project   string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// DiffPageVersionsParams represents arguments for the diff_page_versions tool
+type DiffPageVersionsParams struct {
+	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title"`
+	From      string  `json:"from" jsonschema:"required,description=Time of the older version"`
+	To        *string `json:"to" jsonschema:"description=Time of the newer version (defaults to the current page)"`
+	Project   string  `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// BlamePageParams represents arguments for the blame_page tool
+type BlamePageParams struct {
+	PageTitle string  `json:"page_title" jsonschema:"required,description=Page title"`
+	Since     *string `json:"since" jsonschema:"description=Only include lines changed at or after this time (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)"`
+	Project   string  `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// RecentChangesParams represents arguments for the recent_changes tool
+type RecentChangesParams struct {
+	Since    string `json:"since" jsonschema:"required,description=Start of the period (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)"`
+	MaxChars *int   `json:"max_chars" jsonschema:"description=Maximum total characters of line text to return"`
+	Project  string `json:"project,omitempty" jsonschema:"description=Project to use (defaults to the primary project)"`
+}
+
+// SearchAllParams represents arguments for the search_all tool
+type SearchAllParams struct {
+	Query string `json:"query" jsonschema:"required,description=Search query"`
+}
+
+// ListProjectsParams represents arguments for the list_projects tool
+type ListProjectsParams struct {
+	// No arguments needed for list_projects
 }
 
 // Server represents the MCP server with Scrapbox tools
 type Server struct {
-	client    *scrapbox.Client
+	projects  *projects.Set
 	mcpServer *mcp.Server
+	watchOpts watch.Options
+	completer *completion.Completer
+	tenants   *tenant.Manager
+	// registry decides which of tools, every tool by name, are served.
+	registry *tools.Registry
+	tools    map[string]*mcp.ServerTool
+	// redactor redacts the outputs of the server, unless nil.
+	redactor *redact.Redactor
 }
 
-// NewServer creates a new MCP server with Scrapbox tools
-func NewServer(client *scrapbox.Client) *Server {
+// NewServer creates a new MCP server with Scrapbox tools. watchOpts configures
+// the polling behind resource subscriptions. In multi-tenant mode tenants
+// provides the projects of every session and set is not used; tenants is nil
+// otherwise. Only the tools enabled by registry are served, and clients are
+// notified when that changes. Tool results, resources and prompts are
+// redacted by redactor, unless nil. The records logged while serving a
+// request are sent to its client as log messages.
+func NewServer(set *projects.Set, watchOpts watch.Options, tenants *tenant.Manager, registry *tools.Registry, redactor *redact.Redactor) *Server {
 	server := mcp.NewServer("Scrapbox MCP Server", "1.0.0", nil)
 
 	s := &Server{
-		client:    client,
+		projects:  set,
 		mcpServer: server,
+		watchOpts: watchOpts,
+		completer: completion.New(set.Primary(), completion.DefaultTTL),
+		tenants:   tenants,
+		registry:  registry,
+		redactor:  redactor,
 	}
 
+	set.OnExpired(s.logExpired)
+	registry.OnChange(s.syncTools)
+
 	// Register tools
 	s.registerTools()
+	s.registerResources()
+	s.registerPrompts()
+	s.mcpServer.AddReceivingMiddleware(s.redactMiddleware, loggingMiddleware)
 
 	return s
 }
@@ -58,47 +142,217 @@ func (s *Server) GetServer() *mcp.Server {
 	return s.mcpServer
 }
 
+// Run serves a single session over t, with support for resource
+// subscriptions and completions, until the client disconnects.
+func (s *Server) Run(ctx context.Context, t mcp.Transport) error {
+	ss, err := s.connect(ctx, t)
+	if err != nil {
+		return err
+	}
+	return ss.Wait()
+}
+
+// projectsFor returns the projects of the session serving ctx.
+func (s *Server) projectsFor(ctx context.Context) (*projects.Set, error) {
+	if s.tenants == nil {
+		return s.projects, nil
+	}
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoCredentials
+	}
+	return t.Projects, nil
+}
+
+// logExpired tells every session that the cookie of a project expired. The
+// SDK only sends the message to sessions that have set a log level.
+func (s *Server) logExpired(err error) {
+	for session := range s.mcpServer.Sessions() {
+		_ = session.Log(context.Background(), &mcp.LoggingMessageParams{Level: "error", Logger: "scrapbox", Data: err.Error()})
+	}
+}
+
+// clientFor returns the Scrapbox client of the named project of the session
+// serving ctx, or of its primary project if project is empty.
+func (s *Server) clientFor(ctx context.Context, project string) (*scrapbox.Client, error) {
+	set, err := s.projectsFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return set.Client(project)
+}
+
+// projectDescription describes the project argument of every tool.
+const projectDescription = "Project to use (defaults to the primary project)"
+
 // registerTools registers all Scrapbox tools with the MCP server
 func (s *Server) registerTools() {
 	getPageTool := mcp.NewServerTool("get_page",
 		"Get a Scrapbox page by title",
-		s.handleGetPage,
+		checkTool(s, "get_page", s.handleGetPage),
 		mcp.Input(
 			mcp.Property("page_title", mcp.Description("Page title to retrieve")),
+			mcp.Property("project", mcp.Description(projectDescription)),
 		),
 	)
 	listPagesTool := mcp.NewServerTool("list_pages",
 		"Get a list of pages in the project (max 1000 pages)",
-		s.handleListPages,
-		mcp.Input(),
+		checkTool(s, "list_pages", s.handleListPages),
+		mcp.Input(
+			mcp.Property("project", mcp.Description(projectDescription)),
+		),
 	)
 	searchPagesTool := mcp.NewServerTool("search_pages",
 		"Full-text search across all pages in the project (max 100 pages)",
-		s.handleSearchPages,
+		checkTool(s, "search_pages", s.handleSearchPages),
 		mcp.Input(
 			mcp.Property("query", mcp.Description("Search query")),
+			mcp.Property("project", mcp.Description(projectDescription)),
 		),
 	)
 	createPageURLTool := mcp.NewServerTool("create_page_url",
 		"Generate a URL for creating a new page",
-		s.handleCreatePageURL,
+		checkTool(s, "create_page_url", s.handleCreatePageURL),
 		mcp.Input(
 			mcp.Property("page_title", mcp.Description("Page title")),
 			mcp.Property("body_text", mcp.Description("Body text for the new page")),
+			mcp.Property("project", mcp.Description(projectDescription)),
 		),
 	)
+	getPageHistoryTool := mcp.NewServerTool("get_page_history",
+		"List the saved snapshots and commits of a page, newest first",
+		checkTool(s, "get_page_history", s.handleGetPageHistory),
+		mcp.Input(
+			mcp.Property("page_title", mcp.Description("Page title")),
+			mcp.Property("project", mcp.Description(projectDescription)),
+		),
+	)
+	getPageAtTool := mcp.NewServerTool("get_page_at",
+		"Get the content of a page as it was at a point in time",
+		checkTool(s, "get_page_at", s.handleGetPageAt),
+		mcp.Input(
+			mcp.Property("page_title", mcp.Description("Page title")),
+			mcp.Property("time", mcp.Description("Point in time as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds")),
+			mcp.Property("project", mcp.Description(projectDescription)),
+		),
+	)
+	diffPageVersionsTool := mcp.NewServerTool("diff_page_versions",
+		"Show a unified diff between two versions of a page",
+		checkTool(s, "diff_page_versions", s.handleDiffPageVersions),
+		mcp.Input(
+			mcp.Property("page_title", mcp.Description("Page title")),
+			mcp.Property("from", mcp.Description("Time of the older version")),
+			mcp.Property("to", mcp.Description("Time of the newer version (defaults to the current page)")),
+			mcp.Property("project", mcp.Description(projectDescription)),
+		),
+	)
+	blamePageTool := mcp.NewServerTool("blame_page",
+		"Show who last edited each line of a page, grouped by author and editing session",
+		checkTool(s, "blame_page", s.handleBlamePage),
+		mcp.Input(
+			mcp.Property("page_title", mcp.Description("Page title")),
+			mcp.Property("since", mcp.Description("Only include lines changed at or after this time (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)")),
+			mcp.Property("project", mcp.Description(projectDescription)),
+		),
+	)
+	recentChangesTool := mcp.NewServerTool("recent_changes",
+		"List pages updated since a time with the lines each author added or modified",
+		checkTool(s, "recent_changes", s.handleRecentChanges),
+		mcp.Input(
+			mcp.Property("since", mcp.Description("Start of the period (RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds)")),
+			mcp.Property("max_chars", mcp.Description("Maximum total characters of line text to return")),
+			mcp.Property("project", mcp.Description(projectDescription)),
+		),
+	)
+	searchAllTool := mcp.NewServerTool("search_all",
+		"Full-text search across every project at once, with the project of each page and the projects whose search failed",
+		checkTool(s, "search_all", s.handleSearchAll),
+		mcp.Input(
+			mcp.Property("query", mcp.Description("Search query")),
+		),
+	)
+	listProjectsTool := mcp.NewServerTool("list_projects",
+		"List the Scrapbox projects served, which the project argument of the other tools selects",
+		checkTool(s, "list_projects", s.handleListProjects),
+		mcp.Input(),
+	)
 
-	s.mcpServer.AddTools(
+	s.tools = map[string]*mcp.ServerTool{}
+	for _, t := range []*mcp.ServerTool{
 		getPageTool,
 		listPagesTool,
 		searchPagesTool,
 		createPageURLTool,
-	)
+		getPageHistoryTool,
+		getPageAtTool,
+		diffPageVersionsTool,
+		blamePageTool,
+		recentChangesTool,
+		searchAllTool,
+		listProjectsTool,
+	} {
+		s.tools[t.Tool.Name] = t
+	}
+	s.syncTools()
+}
+
+// syncTools serves the tools enabled by the registry and removes the others.
+// The SDK sends notifications/tools/list_changed to every session.
+func (s *Server) syncTools() {
+	var enabled []*mcp.ServerTool
+	var disabled []string
+	for _, name := range tools.Names {
+		if s.registry.Enabled(name) {
+			enabled = append(enabled, s.tools[name])
+		} else {
+			disabled = append(disabled, name)
+		}
+	}
+	s.mcpServer.RemoveTools(disabled...)
+	s.mcpServer.AddTools(enabled...)
+}
+
+// checkTool wraps handler, the handler of the named tool, to reject the
+// calls that the registry disables in the project they are called on.
+func checkTool[In any](s *Server, name string, handler mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	type call struct {
+		session *mcp.ServerSession
+		params  *mcp.CallToolParamsFor[In]
+	}
+	checked := tools.Wrap(s.registry, name, func(ctx context.Context, c call) (tools.Call, error) {
+		set, err := s.projectsFor(ctx)
+		if err != nil {
+			return tools.Call{}, err
+		}
+		args, err := json.Marshal(c.params.Arguments)
+		if err != nil {
+			return tools.Call{}, err
+		}
+		return tools.Call{Project: tools.ProjectArgument(args), Primary: set.Primary().ProjectName()}, nil
+	}, func(ctx context.Context, c call) (*mcp.CallToolResultFor[any], error) {
+		return handler(ctx, c.session, c.params)
+	})
+	return func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[any], error) {
+		return checked(ctx, call{session: session, params: params})
+	}
+}
+
+// SetCompletionTTL changes how long page titles are cached for completion,
+// in every session.
+func (s *Server) SetCompletionTTL(ttl time.Duration) {
+	s.completer.SetTTL(ttl)
+	if s.tenants != nil {
+		s.tenants.SetCompletionTTL(ttl)
+	}
 }
 
 // handleGetPage handles the get_page tool call
 func (s *Server) handleGetPage(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[GetPageParams]) (*mcp.CallToolResultFor[any], error) {
-	page, err := s.client.GetPage(ctx, params.Arguments.PageTitle)
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	page, err := client.GetPage(ctx, params.Arguments.PageTitle)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get page: %w", err)
 	}
@@ -114,8 +368,12 @@ func (s *Server) handleGetPage(ctx context.Context, _ *mcp.ServerSession, params
 }
 
 // handleListPages handles the list_pages tool call
-func (s *Server) handleListPages(ctx context.Context, _ *mcp.ServerSession, _ *mcp.CallToolParamsFor[ListPagesParams]) (*mcp.CallToolResultFor[any], error) {
-	pages, err := s.client.ListPages(ctx)
+func (s *Server) handleListPages(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[ListPagesParams]) (*mcp.CallToolResultFor[any], error) {
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := client.ListPages(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to list pages: %w", err)
 	}
@@ -132,7 +390,11 @@ func (s *Server) handleListPages(ctx context.Context, _ *mcp.ServerSession, _ *m
 
 // handleSearchPages handles the search_pages tool call
 func (s *Server) handleSearchPages(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[SearchPagesParams]) (*mcp.CallToolResultFor[any], error) {
-	pages, err := s.client.SearchPages(ctx, params.Arguments.Query)
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := client.SearchPages(ctx, params.Arguments.Query)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to search pages: %w", err)
 	}
@@ -154,7 +416,11 @@ func (s *Server) handleCreatePageURL(ctx context.Context, _ *mcp.ServerSession,
 		bodyText = *params.Arguments.BodyText
 	}
 
-	pageURL, err := s.client.CreatePageURL(ctx, params.Arguments.PageTitle, bodyText)
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	pageURL, err := client.CreatePageURL(ctx, params.Arguments.PageTitle, bodyText)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to generate page URL: %w", err)
 	}
@@ -163,3 +429,238 @@ func (s *Server) handleCreatePageURL(ctx context.Context, _ *mcp.ServerSession,
 		Content: []mcp.Content{&mcp.TextContent{Text: pageURL}},
 	}, nil
 }
+
+// handleGetPageHistory handles the get_page_history tool call
+func (s *Server) handleGetPageHistory(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[GetPageHistoryParams]) (*mcp.CallToolResultFor[any], error) {
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	h, err := history.GetHistory(ctx, client, params.Arguments.PageTitle)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get page history: %w", err)
+	}
+	return jsonResult(h)
+}
+
+// handleGetPageAt handles the get_page_at tool call
+func (s *Server) handleGetPageAt(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[GetPageAtParams]) (*mcp.CallToolResultFor[any], error) {
+	at, err := history.ParseTime(params.Arguments.Time)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	v, err := history.PageAt(ctx, client, params.Arguments.PageTitle, at)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get page version: %w", err)
+	}
+	return jsonResult(v)
+}
+
+// handleDiffPageVersions handles the diff_page_versions tool call
+func (s *Server) handleDiffPageVersions(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[DiffPageVersionsParams]) (*mcp.CallToolResultFor[any], error) {
+	from, err := history.ParseTime(params.Arguments.From)
+	if err != nil {
+		return nil, err
+	}
+	var to time.Time
+	if params.Arguments.To != nil && *params.Arguments.To != "" {
+		if to, err = history.ParseTime(*params.Arguments.To); err != nil {
+			return nil, err
+		}
+	}
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	d, err := history.DiffVersions(ctx, client, params.Arguments.PageTitle, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to diff page versions: %w", err)
+	}
+	return jsonResult(d)
+}
+
+// handleBlamePage handles the blame_page tool call
+func (s *Server) handleBlamePage(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[BlamePageParams]) (*mcp.CallToolResultFor[any], error) {
+	var since time.Time
+	if params.Arguments.Since != nil && *params.Arguments.Since != "" {
+		var err error
+		if since, err = history.ParseTime(*params.Arguments.Since); err != nil {
+			return nil, err
+		}
+	}
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	b, err := history.Blame(ctx, client, params.Arguments.PageTitle, since)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to blame page: %w", err)
+	}
+	return jsonResult(b)
+}
+
+// handleRecentChanges handles the recent_changes tool call
+func (s *Server) handleRecentChanges(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[RecentChangesParams]) (*mcp.CallToolResultFor[any], error) {
+	since, err := history.ParseTime(params.Arguments.Since)
+	if err != nil {
+		return nil, err
+	}
+	maxChars := 0
+	if params.Arguments.MaxChars != nil {
+		maxChars = *params.Arguments.MaxChars
+	}
+	client, err := s.clientFor(ctx, params.Arguments.Project)
+	if err != nil {
+		return nil, err
+	}
+	a, err := history.RecentChanges(ctx, client, since, maxChars)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list recent changes: %w", err)
+	}
+	return jsonResult(a)
+}
+
+// handleSearchAll handles the search_all tool call
+func (s *Server) handleSearchAll(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[SearchAllParams]) (*mcp.CallToolResultFor[any], error) {
+	set, err := s.projectsFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := set.SearchAll(ctx, params.Arguments.Query, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to search projects: %w", err)
+	}
+	return jsonResult(r)
+}
+
+// handleListProjects handles the list_projects tool call
+func (s *Server) handleListProjects(ctx context.Context, _ *mcp.ServerSession, _ *mcp.CallToolParamsFor[ListProjectsParams]) (*mcp.CallToolResultFor[any], error) {
+	set, err := s.projectsFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResult(set.List())
+}
+
+// jsonResult returns v marshalled as JSON text content
+func jsonResult(v any) (*mcp.CallToolResultFor[any], error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal result: %w", err)
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{&mcp.TextContent{Text: string(b)}},
+	}, nil
+}
+
+// registerResources exposes pages through the scrapbox:// resource template.
+// The concrete resource list is computed on every resources/list request, so
+// it is served by middleware rather than registered up front. Subscriptions
+// are served by the transport wrapper installed by Run.
+func (s *Server) registerResources() {
+	s.mcpServer.AddResourceTemplates(&mcp.ServerResourceTemplate{
+		ResourceTemplate: &mcp.ResourceTemplate{
+			Name:        "page",
+			Title:       "Scrapbox page",
+			Description: "A page of the Scrapbox project as Markdown. Append ?format=json for the page JSON.",
+			MIMEType:    resources.MIMEMarkdown,
+			URITemplate: resources.URITemplate,
+		},
+		Handler: s.handleReadResource,
+	})
+	s.mcpServer.AddReceivingMiddleware(s.listResourcesMiddleware, subscribeCapabilityMiddleware)
+}
+
+// listResourcesMiddleware answers resources/list with the pinned and recently
+// updated pages of the project.
+func (s *Server) listResourcesMiddleware(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		if method != "resources/list" {
+			return next(ctx, session, method, params)
+		}
+		set, err := s.projectsFor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		list, err := resources.List(ctx, set.Primary(), resources.DefaultLimit)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list resources: %w", err)
+		}
+		result := &mcp.ListResourcesResult{Resources: []*mcp.Resource{}}
+		for _, r := range list {
+			res := &mcp.Resource{
+				URI:         r.URI,
+				Name:        r.Name,
+				Description: r.Description,
+				MIMEType:    r.MIMEType,
+			}
+			if !r.LastModified.IsZero() {
+				res.Annotations = &mcp.Annotations{LastModified: resources.FormatTime(r.LastModified)}
+			}
+			result.Resources = append(result.Resources, res)
+		}
+		return result, nil
+	}
+}
+
+// handleReadResource handles resources/read for page URIs
+func (s *Server) handleReadResource(ctx context.Context, _ *mcp.ServerSession, params *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error) {
+	set, err := s.projectsFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := resources.Read(ctx, set.ForURI(params.URI), params.URI)
+	if errors.Is(err, resources.ErrNotFound) {
+		return nil, mcp.ResourceNotFoundError(params.URI)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read resource: %w", err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{URI: c.URI, MIMEType: c.MIMEType, Text: c.Text}},
+	}, nil
+}
+
+// registerPrompts registers the prompts for common Scrapbox workflows
+func (s *Server) registerPrompts() {
+	for _, p := range prompts.Prompts {
+		prompt := &mcp.Prompt{Name: p.Name, Description: p.Description}
+		for _, a := range p.Arguments {
+			prompt.Arguments = append(prompt.Arguments, &mcp.PromptArgument{
+				Name:        a.Name,
+				Description: a.Description,
+				Required:    a.Required,
+			})
+		}
+		s.mcpServer.AddPrompts(&mcp.ServerPrompt{Prompt: prompt, Handler: s.handleGetPrompt})
+	}
+}
+
+// handleGetPrompt handles prompts/get for every prompt
+func (s *Server) handleGetPrompt(ctx context.Context, _ *mcp.ServerSession, params *mcp.GetPromptParams) (*mcp.GetPromptResult, error) {
+	client, err := s.clientFor(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	r, err := prompts.Get(ctx, client, params.Name, params.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get prompt: %w", err)
+	}
+	result := &mcp.GetPromptResult{Description: r.Description}
+	for _, m := range r.Messages {
+		var content mcp.Content = &mcp.TextContent{Text: m.Text}
+		if m.Resource != nil {
+			content = &mcp.EmbeddedResource{Resource: &mcp.ResourceContents{
+				URI:      m.Resource.URI,
+				MIMEType: m.Resource.MIMEType,
+				Text:     m.Resource.Text,
+			}}
+		}
+		result.Messages = append(result.Messages, &mcp.PromptMessage{Role: "user", Content: content})
+	}
+	return result, nil
+}
@@ -0,0 +1,346 @@
+package officialmcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/logging"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/internal/redact"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/internal/tenant"
+	"github.com/takak2166/scrapbox-mcp/internal/tools"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+func TestServer_ToolPolicy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"count":0,"pages":[]}`))
+	}))
+	defer ts.Close()
+	cfg := &config.Config{ProjectName: "main", Projects: []config.Project{{Name: "sandbox"}}}
+	set := projects.FromConfig(cfg, scrapbox.WithBaseURL(ts.URL))
+	registry := tools.NewRegistry(tools.Policy{ReadOnly: true, Deny: []string{"search_all"}}, map[string]tools.Policy{
+		"sandbox": {Deny: []string{"search_all"}},
+	})
+	s := NewServer(set, watch.Options{}, nil, registry, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	go func() { _ = s.Run(ctx, serverTransport) }()
+	session, err := mcp.NewClient("test", "1.0.0", nil).Connect(ctx, clientTransport)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer session.Close()
+
+	listed := func() []string {
+		t.Helper()
+		res, err := session.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatalf("ListTools() error = %v", err)
+		}
+		var names []string
+		for _, tool := range res.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	want := []string{"blame_page", "create_page_url", "diff_page_versions", "get_page", "get_page_at", "get_page_history", "list_pages", "list_projects", "recent_changes", "search_pages"}
+	if diff := cmp.Diff(want, listed()); diff != "" {
+		t.Errorf("ListTools() mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := map[string]struct {
+		tool    string
+		args    map[string]any
+		wantErr bool
+	}{
+		"ok: write tool in writable project": {
+			tool: "create_page_url",
+			args: map[string]any{"page_title": "Draft", "project": "sandbox"},
+		},
+		"err: write tool in read-only project": {
+			tool:    "create_page_url",
+			args:    map[string]any{"page_title": "Draft"},
+			wantErr: true,
+		},
+		"err: denied tool": {
+			tool:    "search_all",
+			args:    map[string]any{"query": "q"},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := session.CallTool(ctx, &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CallTool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	registry.SetPolicy(tools.Policy{Allow: []string{"get_page", "list_projects"}}, nil)
+	if diff := cmp.Diff([]string{"get_page", "list_projects"}, listed()); diff != "" {
+		t.Errorf("ListTools() after SetPolicy mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServer_Redaction(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"1","title":"Contacts","lines":[{"text":"Contacts"},{"text":"Hanako hanako@example.com"}]}`))
+	}))
+	defer ts.Close()
+	set := projects.FromConfig(&config.Config{ProjectName: "main"}, scrapbox.WithBaseURL(ts.URL))
+	redactor, err := redact.New(redact.Rules{})
+	if err != nil {
+		t.Fatalf("redact.New() error = %v", err)
+	}
+	s := NewServer(set, watch.Options{}, nil, tools.NewRegistry(tools.Policy{}, nil), redactor)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	go func() { _ = s.Run(ctx, serverTransport) }()
+	session, err := mcp.NewClient("test", "1.0.0", nil).Connect(ctx, clientTransport)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer session.Close()
+
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "get_page", Arguments: map[string]any{"page_title": "Contacts"}})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if text := res.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "Hanako [REDACTED:email]") {
+		t.Errorf("get_page result = %s, want the email redacted", text)
+	}
+	read, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: resources.URI("main", "Contacts")})
+	if err != nil {
+		t.Fatalf("ReadResource() error = %v", err)
+	}
+	if text := read.Contents[0].Text; !strings.Contains(text, "Hanako [REDACTED:email]") {
+		t.Errorf("resource = %s, want the email redacted", text)
+	}
+}
+
+func TestServer_Logging(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"pages":[]}`))
+	}))
+	defer ts.Close()
+	var stderr strings.Builder
+	logger := slog.New(logging.New(&stderr, logging.FormatText, slog.LevelInfo))
+	set := projects.FromConfig(&config.Config{ProjectName: "main", ScrapboxSID: "secret-sid"}, scrapbox.WithBaseURL(ts.URL), scrapbox.WithLogger(logger))
+	s := NewServer(set, watch.Options{}, nil, tools.NewRegistry(tools.Policy{}, nil), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages := make(chan *mcp.LoggingMessageParams, 10)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	go func() { _ = s.Run(ctx, serverTransport) }()
+	client := mcp.NewClient("test", "1.0.0", &mcp.ClientOptions{
+		LoggingMessageHandler: func(_ context.Context, _ *mcp.ClientSession, params *mcp.LoggingMessageParams) {
+			messages <- params
+		},
+	})
+	transport := &captureTransport{Transport: clientTransport}
+	session, err := client.Connect(ctx, transport)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer session.Close()
+
+	search := &mcp.CallToolParams{Name: "search_pages", Arguments: map[string]any{"query": "private words"}}
+	if _, err := session.CallTool(ctx, search); err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if err := session.SetLevel(ctx, &mcp.SetLevelParams{Level: "debug"}); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	if _, err := session.CallTool(ctx, search); err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+
+	// The client cannot send completion/complete, so it is written to its
+	// connection as a notification, which the server answers without a
+	// response.
+	complete, _ := json.Marshal(map[string]any{
+		"ref":      map[string]any{"type": "ref/prompt", "name": "summarize_page"},
+		"argument": map[string]any{"name": "page_title", "value": "private"},
+	})
+	if err := transport.conn.Write(ctx, &mcp.JSONRPCRequest{Method: methodComplete, Params: complete}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var got []string
+	for len(got) < 4 {
+		select {
+		case m := <-messages:
+			data, _ := json.Marshal(m.Data)
+			for _, secret := range []string{"secret-sid", "private"} {
+				if strings.Contains(string(data), secret) {
+					t.Errorf("log message %s contains %q", data, secret)
+				}
+			}
+			got = append(got, string(m.Level)+" "+m.Logger+" "+m.Data.(map[string]any)["msg"].(string))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("log messages = %v, want 4", got)
+		}
+	}
+	want := []string{"debug scrapbox scrapbox request", "debug scrapbox scrapbox response", "debug scrapbox scrapbox request", "debug scrapbox scrapbox response"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("log messages mismatch (-want +got):\n%s", diff)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stderr = %q, want nothing below info", stderr.String())
+	}
+}
+
+// captureTransport keeps the connection it makes.
+type captureTransport struct {
+	mcp.Transport
+	conn mcp.Connection
+}
+
+func (t *captureTransport) Connect(ctx context.Context) (mcp.Connection, error) {
+	conn, err := t.Transport.Connect(ctx)
+	t.conn = conn
+	return conn, err
+}
+
+func TestStreamableHandler_IdleTimeout(t *testing.T) {
+	set := projects.FromConfig(&config.Config{ProjectName: "main"})
+	s := NewServer(set, watch.Options{}, nil, tools.NewRegistry(tools.Policy{}, nil), nil)
+	h := s.StreamableHTTPHandler().(*streamableHandler)
+	h.idleTimeout = 50 * time.Millisecond
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	post := func(id, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		req.Header.Set("Content-Type", "application/json")
+		if id != "" {
+			req.Header.Set(headerSessionID, id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST error = %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+	resp := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`)
+	id := resp.Header.Get(headerSessionID)
+	if id == "" {
+		t.Fatalf("initialize status = %d, want a session", resp.StatusCode)
+	}
+	ping := `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+	if resp := post(id, ping); resp.StatusCode == http.StatusNotFound {
+		t.Fatal("session not found right after initialize")
+	}
+
+	time.Sleep(4 * h.idleTimeout)
+	if resp := post(id, ping); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("idle session status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.sessions) != 0 {
+		t.Errorf("sessions = %d, want 0", len(h.sessions))
+	}
+}
+
+func TestServerConn_StartsWatcherOnSubscribe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"1","title":"Page","updated":1}`))
+	}))
+	defer ts.Close()
+	set := projects.FromConfig(&config.Config{ProjectName: "main"}, scrapbox.WithBaseURL(ts.URL))
+	s := NewServer(set, watch.Options{}, nil, tools.NewRegistry(tools.Policy{}, nil), nil)
+
+	ctx := context.Background()
+	serverT, _ := mcp.NewInMemoryTransports()
+	conn, err := (&serverTransport{Transport: serverT, server: s}).Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	c := conn.(*serverConn)
+	defer c.Close()
+	if c.currentWatcher() != nil {
+		t.Fatal("watcher started before any subscription")
+	}
+
+	params, _ := json.Marshal(subscribeParams{URI: resources.URI("main", "Page")})
+	if err := c.handleSubscription(ctx, &mcp.JSONRPCRequest{Method: methodSubscribe, Params: params}); err != nil {
+		t.Fatalf("handleSubscription() error = %v", err)
+	}
+	if w := c.currentWatcher(); w == nil || w.Subscriptions() != 1 {
+		t.Errorf("watcher = %v, want one subscription", w)
+	}
+}
+
+// headerTransport adds headers to every request.
+type headerTransport map[string]string
+
+func (h headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range h {
+		req.Header.Set(k, v)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestServer_ToolPolicyMultiTenant(t *testing.T) {
+	set := projects.FromConfig(&config.Config{ProjectName: "main"})
+	tenants := tenant.New(tenant.Options{SIDHeader: "X-Scrapbox-SID", ProjectHeader: "X-Scrapbox-Project"})
+	registry := tools.NewRegistry(tools.Policy{ReadOnly: true}, map[string]tools.Policy{"sandbox": {}})
+	s := NewServer(set, watch.Options{}, tenants, registry, nil)
+	ts := httptest.NewServer(tenants.Middleware(s.StreamableHTTPHandler()))
+	defer ts.Close()
+
+	tests := map[string]struct {
+		project string
+		wantErr bool
+	}{
+		"ok: writable primary project":   {project: "sandbox"},
+		"err: read-only primary project": {project: "notes", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			transport := mcp.NewStreamableClientTransport(ts.URL, &mcp.StreamableClientTransportOptions{
+				HTTPClient: &http.Client{Transport: headerTransport{"X-Scrapbox-SID": "sid", "X-Scrapbox-Project": tt.project}},
+			})
+			session, err := mcp.NewClient("test", "1.0.0", nil).Connect(ctx, transport)
+			if err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer session.Close()
+
+			_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "create_page_url", Arguments: map[string]any{"page_title": "Draft"}})
+			disabled := err != nil && strings.Contains(err.Error(), tools.ErrDisabled.Error())
+			if err != nil && !disabled || disabled != tt.wantErr {
+				t.Errorf("CallTool() without a project error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package officialmcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+)
+
+// Subscriptions are answered by serverConn, which writes the change
+// notifications of a per-session watch.Watcher started by the first
+// subscription.
+
+const (
+	methodSubscribe                 = "resources/subscribe"
+	methodUnsubscribe               = "resources/unsubscribe"
+	notificationResourceUpdated     = "notifications/resources/updated"
+	notificationResourceListChanged = "notifications/resources/list_changed"
+)
+
+// subscribeParams are the parameters of resources/subscribe and
+// resources/unsubscribe, and of notifications/resources/updated.
+type subscribeParams struct {
+	URI string `json:"uri"`
+}
+
+// handleSubscription applies a subscribe or unsubscribe request.
+func (c *serverConn) handleSubscription(ctx context.Context, req *mcp.JSONRPCRequest) error {
+	var params subscribeParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return fmt.Errorf("Invalid %s params: a uri is required", req.Method)
+	}
+	if req.Method == methodUnsubscribe {
+		if w := c.currentWatcher(); w != nil {
+			w.Unsubscribe(params.URI)
+		}
+		return nil
+	}
+	w := c.startWatcher()
+	if w == nil {
+		return fmt.Errorf("Failed to subscribe: %w", watch.ErrClosed)
+	}
+	err := w.Subscribe(ctx, params.URI)
+	if errors.Is(err, resources.ErrNotFound) {
+		return mcp.ResourceNotFoundError(params.URI)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to subscribe: %w", err)
+	}
+	return nil
+}
+
+// ResourceUpdated implements watch.Notifier.
+func (c *serverConn) ResourceUpdated(ctx context.Context, uri string) error {
+	return c.notify(ctx, notificationResourceUpdated, &subscribeParams{URI: uri})
+}
+
+// ResourceListChanged implements watch.Notifier.
+func (c *serverConn) ResourceListChanged(ctx context.Context) error {
+	return c.notify(ctx, notificationResourceListChanged, &mcp.ResourceListChangedParams{})
+}
+
+func (c *serverConn) notify(ctx context.Context, method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.Write(ctx, &mcp.JSONRPCRequest{Method: method, Params: raw})
+}
+
+// subscribeCapabilityMiddleware advertises resource subscriptions in the
+// initialize result.
+func subscribeCapabilityMiddleware(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		result, err := next(ctx, session, method, params)
+		if res, ok := result.(*mcp.InitializeResult); ok && err == nil && res.Capabilities != nil && res.Capabilities.Resources != nil {
+			res.Capabilities.Resources.Subscribe = true
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,166 @@
+package officialmcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/takak2166/scrapbox-mcp/internal/completion"
+	"github.com/takak2166/scrapbox-mcp/internal/tenant"
+	"github.com/takak2166/scrapbox-mcp/internal/watch"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// The SDK does not dispatch resources/subscribe, resources/unsubscribe and
+// completion/complete to servers, so those requests are handled by wrapping
+// the transport: the connection answers them itself and they never reach the
+// session.
+
+// serverTransport adds the requests the SDK does not dispatch to a transport.
+type serverTransport struct {
+	mcp.Transport
+	server *Server
+	// conn is the connection made by Connect.
+	conn *serverConn
+}
+
+// connect connects a session over t, answering the requests the SDK does
+// not dispatch.
+func (s *Server) connect(ctx context.Context, t mcp.Transport) (*mcp.ServerSession, error) {
+	st := &serverTransport{Transport: t, server: s}
+	ss, err := s.mcpServer.Connect(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	st.conn.session.Store(ss)
+	return ss, nil
+}
+
+// Connect implements mcp.Transport.
+func (t *serverTransport) Connect(ctx context.Context) (mcp.Connection, error) {
+	conn, err := t.Transport.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := &serverConn{Connection: conn, client: t.server.projects.Primary(), watchOpts: t.server.watchOpts, completer: t.server.completer}
+	if tn, ok := tenant.FromContext(ctx); ok {
+		c.client, c.completer = tn.Projects.Primary(), tn.Completer
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	t.conn = c
+	return c, nil
+}
+
+// serverConn is a connection answering the requests the SDK does not
+// dispatch. Writes are serialized because the watcher and completions write
+// concurrently with the session.
+type serverConn struct {
+	mcp.Connection
+	// session is the session of the connection, once connected, which the
+	// records logged while answering requests are sent to.
+	session   atomic.Pointer[mcp.ServerSession]
+	client    *scrapbox.Client
+	watchOpts watch.Options
+	completer *completion.Completer
+
+	// watcher is started by the first subscription, so that sessions
+	// without any do not poll Scrapbox.
+	watchMu sync.Mutex
+	watcher *watch.Watcher
+
+	// ctx is canceled when the connection is closed.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// Read implements mcp.Connection.
+func (c *serverConn) Read(ctx context.Context) (mcp.JSONRPCMessage, error) {
+	for {
+		msg, err := c.Connection.Read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req, ok := msg.(*mcp.JSONRPCRequest)
+		if !ok {
+			return msg, nil
+		}
+		switch req.Method {
+		case methodSubscribe, methodUnsubscribe:
+			err := c.handleSubscription(withSessionLogger(ctx, c.session.Load()), req)
+			if err := c.respond(ctx, req, struct{}{}, err); err != nil {
+				return nil, err
+			}
+		case methodComplete:
+			// Completions may have to load the title list, so they are
+			// answered without holding up the session.
+			go func() {
+				result, err := c.handleComplete(withSessionLogger(c.ctx, c.session.Load()), req)
+				_ = c.respond(c.ctx, req, result, err)
+			}()
+		default:
+			return msg, nil
+		}
+	}
+}
+
+// respond answers req with result, or with err if it is not nil.
+// Notifications get no response.
+func (c *serverConn) respond(ctx context.Context, req *mcp.JSONRPCRequest, result any, err error) error {
+	if !req.ID.IsValid() {
+		return nil
+	}
+	resp := &mcp.JSONRPCResponse{ID: req.ID, Error: err}
+	if err == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = raw
+	}
+	return c.Write(ctx, resp)
+}
+
+// Write implements mcp.Connection.
+func (c *serverConn) Write(ctx context.Context, msg mcp.JSONRPCMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Connection.Write(ctx, msg)
+}
+
+// Close implements mcp.Connection. It stops the watcher and pending
+// completions before closing the underlying connection.
+func (c *serverConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.watchMu.Lock()
+		defer c.watchMu.Unlock()
+		c.cancel()
+		if c.watcher != nil {
+			c.watcher.Close()
+		}
+	})
+	return c.Connection.Close()
+}
+
+// startWatcher returns the watcher of the connection, starting it if need
+// be, or nil once the connection is closed.
+func (c *serverConn) startWatcher() *watch.Watcher {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	if c.watcher == nil && c.ctx.Err() == nil {
+		c.watcher = watch.New(c.client, c, c.watchOpts)
+	}
+	return c.watcher
+}
+
+// currentWatcher returns the watcher of the connection, or nil if none was
+// started.
+func (c *serverConn) currentWatcher() *watch.Watcher {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	return c.watcher
+}
@@ -0,0 +1,259 @@
+// Package pagestore keeps an on-disk copy of a Scrapbox project.
+//
+// The store holds one JSON file per page plus an index of page IDs, titles
+// and update times. Sync compares the index with the project's page list and
+// only fetches pages whose updated timestamp changed, so exporters and
+// mirrors can work off a local copy without re-downloading the project.
+package pagestore
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+const (
+	indexFile = "index.json"
+	pagesDir  = "pages"
+)
+
+// Source is the subset of scrapbox.Client used to sync a store.
+type Source interface {
+	ListAllPages(ctx context.Context) ([]scrapbox.Page, error)
+	GetPage(ctx context.Context, title string) (*scrapbox.Page, error)
+}
+
+// Entry describes a page held in the store.
+type Entry struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Updated int64  `json:"updated"`
+}
+
+// SyncResult reports what a Sync changed.
+type SyncResult struct {
+	// Updated lists pages that were added or changed.
+	Updated []Entry
+	// Deleted lists pages that no longer exist in the project.
+	Deleted []Entry
+}
+
+// Store is an on-disk copy of a project's pages.
+type Store struct {
+	dir   string
+	index map[string]Entry
+}
+
+// Open opens the store in dir, creating it if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, pagesDir), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	s := &Store{dir: dir, index: map[string]Entry{}}
+	b, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read store index: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode store index: %w", err)
+	}
+	for _, e := range entries {
+		s.index[e.ID] = e
+	}
+	return s, nil
+}
+
+// Dir returns the directory backing the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Sync brings the store up to date with src. Pages whose updated timestamp
+// is unchanged are not fetched again. Pages that src denies with
+// scrapbox.ErrPageDenied are deleted from the store like missing pages.
+//
+// A stored page missing from the page list is fetched again before it is
+// deleted, since the list may have changed while it was walked: it is only
+// deleted if it is not found, is denied or its title now names another page.
+func (s *Store) Sync(ctx context.Context, src Source) (*SyncResult, error) {
+	remote, err := src.ListAllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{}
+	seen := map[string]bool{}
+	for _, meta := range remote {
+		id := pageID(&meta)
+		if cur, ok := s.index[id]; ok && cur.Updated == meta.Updated && cur.Title == meta.Title {
+			seen[id] = true
+			continue
+		}
+		page, err := src.GetPage(ctx, meta.Title)
+		if errors.Is(err, scrapbox.ErrPageDenied) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		seen[id] = true
+		if page.ID == "" {
+			page.ID = meta.ID
+		}
+		if page.Updated == 0 {
+			page.Updated = meta.Updated
+		}
+		if err := s.writePage(id, page); err != nil {
+			return nil, err
+		}
+		entry := Entry{ID: id, Title: page.Title, Updated: page.Updated}
+		s.index[id] = entry
+		result.Updated = append(result.Updated, entry)
+	}
+
+	missing := map[string]bool{}
+	for id, e := range s.index {
+		if seen[id] {
+			continue
+		}
+		page, err := src.GetPage(ctx, e.Title)
+		switch {
+		case errors.Is(err, scrapbox.ErrPageDenied), scrapbox.IsNotFound(err):
+			missing[id] = true
+		case err != nil:
+			return nil, err
+		case page == nil || pageID(page) != id:
+			missing[id] = true
+		}
+	}
+	deleted, err := s.remove(func(id string) bool { return missing[id] })
+	if err != nil {
+		return nil, err
+	}
+	result.Deleted = deleted
+	return result, nil
+}
+
+// Prune deletes the pages for which deny reports true from the store, such
+// as pages hidden by access rules that changed since they were synced, and
+// returns them.
+func (s *Store) Prune(deny func(*scrapbox.Page) bool) ([]Entry, error) {
+	denied := map[string]bool{}
+	for id := range s.index {
+		page, err := s.Page(id)
+		if err != nil {
+			return nil, err
+		}
+		denied[id] = deny(page)
+	}
+	return s.remove(func(id string) bool { return denied[id] })
+}
+
+// remove deletes the pages whose IDs match from the store, writes the index
+// and returns them.
+func (s *Store) remove(match func(id string) bool) ([]Entry, error) {
+	var removed []Entry
+	for id, e := range s.index {
+		if !match(id) {
+			continue
+		}
+		if err := os.Remove(s.pagePath(id)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove page %q: %w", e.Title, err)
+		}
+		delete(s.index, id)
+		removed = append(removed, e)
+	}
+	if err := s.writeIndex(); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// Entries returns every page in the store ordered by title.
+func (s *Store) Entries() []Entry {
+	entries := make([]Entry, 0, len(s.index))
+	for _, e := range s.index {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Title < entries[j].Title })
+	return entries
+}
+
+// Page loads the page with the given store ID.
+func (s *Store) Page(id string) (*scrapbox.Page, error) {
+	if _, ok := s.index[id]; !ok {
+		return nil, fmt.Errorf("page %q is not in the store", id)
+	}
+	b, err := os.ReadFile(s.pagePath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+	var page scrapbox.Page
+	if err := json.Unmarshal(b, &page); err != nil {
+		return nil, fmt.Errorf("failed to decode page: %w", err)
+	}
+	return &page, nil
+}
+
+func (s *Store) pagePath(id string) string {
+	return filepath.Join(s.dir, pagesDir, id+".json")
+}
+
+func (s *Store) writePage(id string, page *scrapbox.Page) error {
+	b, err := json.MarshalIndent(page, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal page: %w", err)
+	}
+	return writeFileAtomic(s.pagePath(id), b)
+}
+
+func (s *Store) writeIndex() error {
+	b, err := json.MarshalIndent(s.Entries(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal store index: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(s.dir, indexFile), b)
+}
+
+// pageID returns a file-system safe identifier for a page. Page IDs from the
+// API are hex strings; pages without one fall back to a hash of the title.
+func pageID(p *scrapbox.Page) string {
+	if p.ID != "" && filepath.Base(p.ID) == p.ID {
+		return p.ID
+	}
+	sum := sha1.Sum([]byte(p.Title))
+	return "title-" + hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+// so that an interrupted sync never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
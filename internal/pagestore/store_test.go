@@ -0,0 +1,175 @@
+package pagestore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/errors"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+type fakeSource struct {
+	pages   map[string]*scrapbox.Page
+	fetched []string
+	// denied are the titles of pages denied by GetPage.
+	denied []string
+	// unlisted are the IDs of pages ListAllPages skips, as if the list
+	// changed while it was walked.
+	unlisted []string
+}
+
+func (f *fakeSource) ListAllPages(ctx context.Context) ([]scrapbox.Page, error) {
+	var list []scrapbox.Page
+	for _, p := range f.pages {
+		if slices.Contains(f.unlisted, p.ID) {
+			continue
+		}
+		list = append(list, scrapbox.Page{ID: p.ID, Title: p.Title, Updated: p.Updated})
+	}
+	return list, nil
+}
+
+func (f *fakeSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	f.fetched = append(f.fetched, title)
+	if slices.Contains(f.denied, title) {
+		return nil, fmt.Errorf("%w: %s", scrapbox.ErrPageDenied, title)
+	}
+	for _, p := range f.pages {
+		if p.Title == title {
+			return p, nil
+		}
+	}
+	return nil, &errors.ScrapboxError{Code: errors.ErrNotFound, Message: "unexpected status code"}
+}
+
+func TestStore_Sync(t *testing.T) {
+	dir := t.TempDir()
+	src := &fakeSource{pages: map[string]*scrapbox.Page{
+		"a": {ID: "a", Title: "A", Updated: 1, Lines: []scrapbox.Line{{Text: "A"}}},
+		"b": {ID: "b", Title: "B", Updated: 1, Lines: []scrapbox.Line{{Text: "B"}}},
+	}}
+
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	res, err := store.Sync(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(res.Updated) != 2 || len(res.Deleted) != 0 {
+		t.Errorf("first Sync() = %+v, want 2 updated", res)
+	}
+
+	// Change one page, delete the other and reopen the store from disk.
+	src.pages["a"] = &scrapbox.Page{ID: "a", Title: "A", Updated: 2, Lines: []scrapbox.Line{{Text: "A"}, {Text: "new"}}}
+	delete(src.pages, "b")
+	src.fetched = nil
+
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	res, err = store.Sync(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	want := &SyncResult{
+		Updated: []Entry{{ID: "a", Title: "A", Updated: 2}},
+		Deleted: []Entry{{ID: "b", Title: "B", Updated: 1}},
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("second Sync() mismatch (-want +got):\n%s", diff)
+	}
+	// B is fetched again to confirm that it was deleted.
+	if diff := cmp.Diff([]string{"A", "B"}, src.fetched); diff != "" {
+		t.Errorf("fetched pages mismatch (-want +got):\n%s", diff)
+	}
+
+	page, err := store.Page("a")
+	if err != nil {
+		t.Fatalf("Page() error = %v", err)
+	}
+	if len(page.Lines) != 2 {
+		t.Errorf("Page() lines = %d, want 2", len(page.Lines))
+	}
+	if _, err := store.Page("b"); err == nil {
+		t.Error("Page() for deleted page error = nil, want error")
+	}
+}
+
+func TestStore_Denied(t *testing.T) {
+	dir := t.TempDir()
+	src := &fakeSource{pages: map[string]*scrapbox.Page{
+		"a": {ID: "a", Title: "A", Updated: 1},
+		"b": {ID: "b", Title: "B", Updated: 1},
+		"c": {ID: "c", Title: "C", Updated: 1},
+	}}
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := store.Sync(context.Background(), src); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// A page changed since it was synced and is denied now.
+	src.pages["b"] = &scrapbox.Page{ID: "b", Title: "B", Updated: 2}
+	src.denied = []string{"B"}
+	res, err := store.Sync(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	want := &SyncResult{Deleted: []Entry{{ID: "b", Title: "B", Updated: 1}}}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("Sync() mismatch (-want +got):\n%s", diff)
+	}
+
+	pruned, err := store.Prune(func(p *scrapbox.Page) bool { return p.Title == "C" })
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if diff := cmp.Diff([]Entry{{ID: "c", Title: "C", Updated: 1}}, pruned); diff != "" {
+		t.Errorf("Prune() mismatch (-want +got):\n%s", diff)
+	}
+
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if diff := cmp.Diff([]Entry{{ID: "a", Title: "A", Updated: 1}}, store.Entries()); diff != "" {
+		t.Errorf("Entries() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStore_Unlisted(t *testing.T) {
+	src := &fakeSource{pages: map[string]*scrapbox.Page{
+		"a": {ID: "a", Title: "A", Updated: 1},
+		"b": {ID: "b", Title: "B", Updated: 1},
+	}}
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := store.Sync(context.Background(), src); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// A is skipped by the page list but still exists, B was renamed to C.
+	src.unlisted = []string{"a"}
+	src.pages["b"] = &scrapbox.Page{ID: "b", Title: "C", Updated: 2}
+	res, err := store.Sync(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	want := &SyncResult{Updated: []Entry{{ID: "b", Title: "C", Updated: 2}}}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("Sync() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]Entry{{ID: "a", Title: "A", Updated: 1}, {ID: "b", Title: "C", Updated: 2}}, store.Entries()); diff != "" {
+		t.Errorf("Entries() mismatch (-want +got):\n%s", diff)
+	}
+}
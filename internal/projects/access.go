@@ -0,0 +1,91 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	scrapboxerrors "github.com/takak2166/scrapbox-mcp/internal/errors"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+var (
+	// ErrPrivateProject is returned by DetectAccess for private projects
+	// configured without a cookie.
+	ErrPrivateProject = errors.New("private project configured without a cookie")
+	// ErrNotMember is returned by DetectAccess for private projects whose
+	// cookie belongs to a user who is not a member.
+	ErrNotMember = errors.New("not a member of the project")
+)
+
+// IsCredentialError reports whether err, as returned by DetectAccess, is due
+// to missing, expired or insufficient credentials rather than, say, network
+// trouble.
+func IsCredentialError(err error) bool {
+	return errors.Is(err, ErrPrivateProject) || errors.Is(err, scrapbox.ErrCookieExpired) || errors.Is(err, ErrNotMember)
+}
+
+// DetectAccess validates the credentials of every project at startup. For a
+// project with a cookie it asks /api/users/me who is logged in; for every
+// project it asks /api/projects/:project whether the project is public and
+// whether that user is a member. The findings are recorded for List.
+//
+// Projects accessed anonymously must be public, and cookies must belong to a
+// logged-in member of private projects: otherwise the returned error wraps
+// ErrPrivateProject, scrapbox.ErrCookieExpired or ErrNotMember and names the
+// variable to set. Other failures are returned as well, joined, so the
+// caller can tell them apart.
+func (s *Set) DetectAccess(ctx context.Context) error {
+	var errs []error
+	for _, c := range s.clients {
+		a, err := detect(ctx, c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.mu.Lock()
+		s.access[c.ProjectName()] = a
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// detect finds out how the project of c is accessed.
+func detect(ctx context.Context, c *scrapbox.Client) (access, error) {
+	var a access
+	keys := c.CookieSource()
+	if !c.Anonymous() {
+		me, err := c.GetMe(ctx)
+		if err != nil {
+			return a, fmt.Errorf("failed to get the user of project %s: %w", c.ProjectName(), err)
+		}
+		if me.IsGuest {
+			return a, fmt.Errorf("%w: the cookie of project %s is not logged in, refresh %s", scrapbox.ErrCookieExpired, c.ProjectName(), keys)
+		}
+		a.user = me.Name
+	}
+
+	p, err := c.GetProject(ctx)
+	if err != nil {
+		var se *scrapboxerrors.ScrapboxError
+		if errors.As(err, &se) && (se.Code == http.StatusUnauthorized || se.Code == http.StatusForbidden) {
+			if c.Anonymous() {
+				return a, fmt.Errorf("%w: %s is private, set %s", ErrPrivateProject, c.ProjectName(), keys)
+			}
+			if se.Code == http.StatusForbidden {
+				return a, fmt.Errorf("%w: %s is private and %s is not a member, check %s", ErrNotMember, c.ProjectName(), a.user, keys)
+			}
+		}
+		return a, fmt.Errorf("failed to get project %s: %w", c.ProjectName(), err)
+	}
+	if c.Anonymous() && !p.PublicVisible {
+		return a, fmt.Errorf("%w: %s is private, set %s", ErrPrivateProject, c.ProjectName(), keys)
+	}
+	a.public = p.PublicVisible
+	if !c.Anonymous() {
+		member := p.IsMember
+		a.member = &member
+	}
+	return a, nil
+}
@@ -0,0 +1,162 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// accessBackend serves /users/me and /projects/:project like Scrapbox.
+// Cookie "alice" is logged in and a member of "team", cookie "stale" is not
+// logged in, and any cookie is rejected with 401 by "revoked". Public
+// projects are served to everyone and private ones to members only. Project
+// "down" always fails.
+func accessBackend(t *testing.T) *httptest.Server {
+	public := map[string]bool{"open": true, "team": false, "secret": false}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie := strings.TrimPrefix(r.Header.Get("Cookie"), "connect.sid=")
+		if r.URL.Path == "/users/me" {
+			_ = json.NewEncoder(w).Encode(scrapbox.Me{Name: cookie, IsGuest: cookie != "alice"})
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/projects/")
+		member := cookie == "alice" && name == "team"
+		switch {
+		case name == "down":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case name == "revoked" && cookie != "":
+			http.Error(w, `{"name":"NotLoggedInError"}`, http.StatusUnauthorized)
+		case !public[name] && cookie == "":
+			http.Error(w, `{"name":"NotLoggedInError"}`, http.StatusUnauthorized)
+		case !public[name] && !member:
+			http.Error(w, `{"name":"NotMemberError"}`, http.StatusForbidden)
+		default:
+			_ = json.NewEncoder(w).Encode(scrapbox.Project{Name: name, PublicVisible: public[name], IsMember: member})
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestSet_DetectAccess(t *testing.T) {
+	ts := accessBackend(t)
+
+	tests := map[string]struct {
+		cfg         *config.Config
+		wantAccess  []string
+		wantErr     error
+		wantMessage string
+	}{
+		"ok: public project without cookie": {
+			cfg:        &config.Config{ProjectName: "open"},
+			wantAccess: []string{"public, anonymous"},
+		},
+		"ok: private project of a member": {
+			cfg:        &config.Config{ProjectName: "open", Projects: []config.Project{{Name: "team", SID: "alice"}}},
+			wantAccess: []string{"public, anonymous", "private, logged in as alice, member"},
+		},
+		"ok: public project of a non-member": {
+			cfg:        &config.Config{ScrapboxSID: "alice", ProjectName: "open"},
+			wantAccess: []string{"public, logged in as alice, not a member"},
+		},
+		"err: private primary project without cookie": {
+			cfg:         &config.Config{ProjectName: "secret"},
+			wantAccess:  []string{"anonymous"},
+			wantErr:     ErrPrivateProject,
+			wantMessage: "secret is private, set SCRAPBOX_SID",
+		},
+		"err: private additional project without cookie": {
+			cfg:         &config.Config{ProjectName: "open", Projects: []config.Project{{Name: "secret"}}},
+			wantAccess:  []string{"public, anonymous", "anonymous"},
+			wantErr:     ErrPrivateProject,
+			wantMessage: "secret is private, set SCRAPBOX_SID_SECRET or SCRAPBOX_SID",
+		},
+		"err: cookie not logged in": {
+			cfg:         &config.Config{ScrapboxSID: "stale", ProjectName: "team"},
+			wantAccess:  []string{"unknown"},
+			wantErr:     scrapbox.ErrCookieExpired,
+			wantMessage: "the cookie of project team is not logged in, refresh SCRAPBOX_SID",
+		},
+		"err: cookie rejected": {
+			cfg:         &config.Config{ScrapboxSID: "alice", ProjectName: "revoked"},
+			wantAccess:  []string{"cookie expired"},
+			wantErr:     scrapbox.ErrCookieExpired,
+			wantMessage: "cookie expired for project revoked, refresh SCRAPBOX_SID",
+		},
+		"err: private project of a non-member": {
+			cfg:         &config.Config{ScrapboxSID: "alice", ProjectName: "secret"},
+			wantAccess:  []string{"unknown"},
+			wantErr:     ErrNotMember,
+			wantMessage: "secret is private and alice is not a member, check SCRAPBOX_SID",
+		},
+		"err: unreachable project": {
+			cfg:         &config.Config{ProjectName: "open", Projects: []config.Project{{Name: "down"}}},
+			wantAccess:  []string{"public, anonymous", "anonymous"},
+			wantMessage: "failed to get project down",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := FromConfig(tt.cfg, scrapbox.WithBaseURL(ts.URL))
+			err := s.DetectAccess(context.Background())
+			if (err != nil) != (tt.wantMessage != "") {
+				t.Fatalf("DetectAccess() error = %v, want %q", err, tt.wantMessage)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("DetectAccess() error = %v, want %q", err, tt.wantMessage)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("DetectAccess() error = %v, want %v", err, tt.wantErr)
+			}
+			var access []string
+			for _, p := range s.List() {
+				access = append(access, p.Access())
+			}
+			if diff := cmp.Diff(tt.wantAccess, access); diff != "" {
+				t.Errorf("List() access mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSet_OnExpired(t *testing.T) {
+	ts := accessBackend(t)
+	s := FromConfig(&config.Config{
+		ProjectName: "open",
+		Projects:    []config.Project{{Name: "revoked", SID: "alice"}},
+	}, scrapbox.WithBaseURL(ts.URL))
+
+	var notified []error
+	remove := s.OnExpired(func(err error) { notified = append(notified, err) })
+
+	c, err := s.Client("revoked")
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetProject(context.Background()); !errors.Is(err, scrapbox.ErrCookieExpired) {
+			t.Fatalf("GetProject() error = %v, want %v", err, scrapbox.ErrCookieExpired)
+		}
+	}
+	if len(notified) != 1 || !strings.Contains(notified[0].Error(), "refresh SCRAPBOX_SID_REVOKED or SCRAPBOX_SID") {
+		t.Errorf("notified = %v, want one actionable error", notified)
+	}
+	if got := s.List()[1]; !got.Expired {
+		t.Errorf("List()[1].Expired = false, want true")
+	}
+
+	remove()
+	s.expired(errors.New("again"))
+	if len(notified) != 1 {
+		t.Errorf("notified after remove = %v, want no more calls", notified)
+	}
+}
@@ -0,0 +1,246 @@
+// Package projects keeps the Scrapbox projects served by one server: the
+// primary project, used when a request names none, and any number of
+// additional projects, each with its own scrapbox.Client and credentials.
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// ErrUnknownProject is returned for project names the server does not serve.
+var ErrUnknownProject = errors.New("unknown project")
+
+// Project describes a served project, as returned by the list_projects tool.
+type Project struct {
+	Name    string `json:"name"`
+	Primary bool   `json:"primary"`
+	URL     string `json:"url"`
+	// Public, User and Member are only set once DetectAccess has determined
+	// them.
+	Public    *bool  `json:"public,omitempty"`
+	Anonymous bool   `json:"anonymous,omitempty"`
+	User      string `json:"user,omitempty"`
+	Member    *bool  `json:"member,omitempty"`
+	// Expired reports that Scrapbox rejected the cookie of the project.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// Access describes how the project is accessed, e.g. "private, logged in as
+// alice, member".
+func (p Project) Access() string {
+	var parts []string
+	if p.Public != nil {
+		parts = append(parts, map[bool]string{true: "public", false: "private"}[*p.Public])
+	}
+	switch {
+	case p.Expired:
+		parts = append(parts, "cookie expired")
+	case p.Anonymous:
+		parts = append(parts, "anonymous")
+	case p.User != "":
+		parts = append(parts, "logged in as "+p.User)
+	}
+	if p.Member != nil {
+		parts = append(parts, map[bool]string{true: "member", false: "not a member"}[*p.Member])
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Set is the set of projects served by a server.
+type Set struct {
+	clients []*scrapbox.Client
+	byName  map[string]*scrapbox.Client
+
+	// mu guards access, recorded by DetectAccess, and the functions called
+	// when the cookie of a project expires.
+	mu        sync.Mutex
+	access    map[string]access
+	onExpired map[int]func(error)
+	nextID    int
+}
+
+// access is what DetectAccess found out about a project.
+type access struct {
+	public bool
+	user   string
+	member *bool
+}
+
+// New creates a Set serving the project of primary and those of others.
+// Later clients for an already served project are ignored. The clients must
+// not be used before New returns, since it sets their expired handlers.
+func New(primary *scrapbox.Client, others ...*scrapbox.Client) *Set {
+	s := &Set{
+		byName:    map[string]*scrapbox.Client{},
+		access:    map[string]access{},
+		onExpired: map[int]func(error){},
+	}
+	for _, c := range append([]*scrapbox.Client{primary}, others...) {
+		if _, ok := s.byName[c.ProjectName()]; ok {
+			continue
+		}
+		c.SetExpiredHandler(s.expired)
+		s.clients = append(s.clients, c)
+		s.byName[c.ProjectName()] = c
+	}
+	return s
+}
+
+// FromConfig creates the Set of the projects configured by cfg, applying
+// opts to every client. Clients whose SID was read from a file, a command or
+// a cookies.txt export re-read it when Scrapbox rejects it.
+func FromConfig(cfg *config.Config, opts ...scrapbox.Option) *Set {
+	others := make([]*scrapbox.Client, 0, len(cfg.Projects))
+	for _, p := range cfg.Projects {
+		others = append(others, newClient(p.Name, p.SID, p.SIDSource, false, opts))
+	}
+	return New(newClient(cfg.ProjectName, cfg.ScrapboxSID, cfg.SIDSource, true, opts), others...)
+}
+
+// newClient creates the client of a configured project.
+func newClient(project, sid string, src config.CredentialSource, primary bool, opts []scrapbox.Option) *scrapbox.Client {
+	source, reload := cookieSource(project, src, primary)
+	opts = append(opts[:len(opts):len(opts)], scrapbox.WithCookieSource(source))
+	if reload != nil {
+		opts = append(opts, scrapbox.WithCookieReload(reload))
+	}
+	return scrapbox.NewClient(project, sid, opts...)
+}
+
+// cookieSource describes where the SID of a configured project comes from
+// and returns the function re-reading it, if it is read from src.
+func cookieSource(project string, src config.CredentialSource, primary bool) (string, func(context.Context) (string, error)) {
+	if src.IsZero() {
+		return sidKeys(project, primary), nil
+	}
+	return src.String(), src.Read
+}
+
+// Reconfigure applies the credentials of cfg, typically reloaded from the
+// config file, to the projects it configures. The projects served do not
+// change.
+func (s *Set) Reconfigure(cfg *config.Config) {
+	if c, ok := s.byName[cfg.ProjectName]; ok {
+		source, reload := cookieSource(cfg.ProjectName, cfg.SIDSource, true)
+		c.SetCookie(cfg.ScrapboxSID, source, reload)
+	}
+	for _, p := range cfg.Projects {
+		if c, ok := s.byName[p.Name]; ok && c != s.Primary() {
+			source, reload := cookieSource(p.Name, p.SIDSource, false)
+			c.SetCookie(p.SID, source, reload)
+		}
+	}
+}
+
+// sidKeys names the environment variables holding the SID of a project.
+func sidKeys(project string, primary bool) string {
+	if primary {
+		return "SCRAPBOX_SID"
+	}
+	return config.ProjectSIDKey(project) + " or SCRAPBOX_SID"
+}
+
+// OnExpired registers f to be called with the actionable error returned to
+// the caller when Scrapbox first rejects the cookie of a project. The
+// returned function unregisters f.
+func (s *Set) OnExpired(f func(error)) (remove func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.onExpired[id] = f
+	return func() {
+		s.mu.Lock()
+		delete(s.onExpired, id)
+		s.mu.Unlock()
+	}
+}
+
+// expired calls the functions registered with OnExpired.
+func (s *Set) expired(err error) {
+	s.mu.Lock()
+	fs := make([]func(error), 0, len(s.onExpired))
+	for _, f := range s.onExpired {
+		fs = append(fs, f)
+	}
+	s.mu.Unlock()
+	for _, f := range fs {
+		f(err)
+	}
+}
+
+// Primary returns the client of the primary project.
+func (s *Set) Primary() *scrapbox.Client {
+	return s.clients[0]
+}
+
+// Client returns the client of the named project, or of the primary project
+// if name is empty.
+func (s *Set) Client(name string) (*scrapbox.Client, error) {
+	if name == "" {
+		return s.Primary(), nil
+	}
+	c, ok := s.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q: available projects are %s", ErrUnknownProject, name, strings.Join(s.Names(), ", "))
+	}
+	return c, nil
+}
+
+// ForURI returns the client of the project named by a page resource URI, or
+// the primary client if the URI names no served project, in which case
+// reading the resource fails with resources.ErrNotFound.
+func (s *Set) ForURI(uri string) *scrapbox.Client {
+	project, _, _, err := resources.ParseURI(uri)
+	if err != nil {
+		return s.Primary()
+	}
+	if c, ok := s.byName[project]; ok {
+		return c
+	}
+	return s.Primary()
+}
+
+// Names returns the names of the projects, the primary project first.
+func (s *Set) Names() []string {
+	names := make([]string, len(s.clients))
+	for i, c := range s.clients {
+		names[i] = c.ProjectName()
+	}
+	return names
+}
+
+// List describes the projects, the primary project first.
+func (s *Set) List() []Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Project, len(s.clients))
+	for i, c := range s.clients {
+		list[i] = Project{
+			Name:      c.ProjectName(),
+			Primary:   i == 0,
+			URL:       "https://scrapbox.io/" + url.PathEscape(c.ProjectName()),
+			Anonymous: c.Anonymous(),
+			Expired:   c.Expired(),
+		}
+		if a, ok := s.access[c.ProjectName()]; ok {
+			public := a.public
+			list[i].Public = &public
+			list[i].User = a.user
+			list[i].Member = a.member
+		}
+	}
+	return list
+}
@@ -0,0 +1,132 @@
+package projects
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+)
+
+func TestSet_Client(t *testing.T) {
+	s := FromConfig(&config.Config{
+		ScrapboxSID: "main_sid",
+		ProjectName: "main",
+		Projects: []config.Project{
+			{Name: "team", SID: "team_sid"},
+			{Name: "main", SID: "ignored"},
+		},
+	})
+
+	tests := map[string]struct {
+		name        string
+		wantProject string
+		wantErr     error
+	}{
+		"ok: primary by default": {
+			name:        "",
+			wantProject: "main",
+		},
+		"ok: primary by name": {
+			name:        "main",
+			wantProject: "main",
+		},
+		"ok: additional project": {
+			name:        "team",
+			wantProject: "team",
+		},
+		"err: unknown project": {
+			name:    "other",
+			wantErr: ErrUnknownProject,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := s.Client(tt.name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Client() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.ProjectName(); got != tt.wantProject {
+				t.Errorf("Client() project = %q, want %q", got, tt.wantProject)
+			}
+		})
+	}
+}
+
+func TestSet_ForURI(t *testing.T) {
+	s := FromConfig(&config.Config{ProjectName: "main", Projects: []config.Project{{Name: "team"}}})
+
+	tests := map[string]struct {
+		uri  string
+		want string
+	}{
+		"primary project":    {uri: "scrapbox://main/Page", want: "main"},
+		"additional project": {uri: "scrapbox://team/Page?format=json", want: "team"},
+		"unknown project":    {uri: "scrapbox://other/Page", want: "main"},
+		"invalid URI":        {uri: "https://scrapbox.io/team/Page", want: "main"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := s.ForURI(tt.uri).ProjectName(); got != tt.want {
+				t.Errorf("ForURI() project = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet_List(t *testing.T) {
+	s := FromConfig(&config.Config{ScrapboxSID: "main_sid", ProjectName: "main", Projects: []config.Project{{Name: "team wiki"}}})
+
+	want := []Project{
+		{Name: "main", Primary: true, URL: "https://scrapbox.io/main"},
+		{Name: "team wiki", URL: "https://scrapbox.io/team%20wiki", Anonymous: true},
+	}
+	if diff := cmp.Diff(want, s.List()); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"main", "team wiki"}, s.Names()); diff != "" {
+		t.Errorf("Names() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSet_Reconfigure(t *testing.T) {
+	s := FromConfig(&config.Config{ProjectName: "main", Projects: []config.Project{{Name: "team", SID: "team_sid"}}})
+
+	s.Reconfigure(&config.Config{
+		ProjectName: "main",
+		ScrapboxSID: "main_sid",
+		SIDSource:   config.CredentialSource{File: "/run/secrets/sid"},
+		Projects:    []config.Project{{Name: "team"}, {Name: "other", SID: "ignored"}},
+	})
+
+	tests := map[string]struct {
+		project       string
+		wantAnonymous bool
+		wantSource    string
+	}{
+		"primary project": {project: "main", wantAnonymous: false, wantSource: "the file /run/secrets/sid"},
+		"cookie removed":  {project: "team", wantAnonymous: true, wantSource: "SCRAPBOX_SID_TEAM or SCRAPBOX_SID"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := s.Client(tt.project)
+			if err != nil {
+				t.Fatalf("Client() error = %v", err)
+			}
+			if got := c.Anonymous(); got != tt.wantAnonymous {
+				t.Errorf("Anonymous() = %v, want %v", got, tt.wantAnonymous)
+			}
+			if got := c.CookieSource(); got != tt.wantSource {
+				t.Errorf("CookieSource() = %q, want %q", got, tt.wantSource)
+			}
+		})
+	}
+	if diff := cmp.Diff([]string{"main", "team"}, s.Names()); diff != "" {
+		t.Errorf("Names() mismatch (-want +got):\n%s", diff)
+	}
+}
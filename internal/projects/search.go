@@ -0,0 +1,77 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultSearchTimeout is the deadline SearchAll shares between the
+// searches of all projects when it is given a non-positive timeout.
+const DefaultSearchTimeout = 10 * time.Second
+
+// SearchHit is a page found by SearchAll.
+type SearchHit struct {
+	Project string   `json:"project"`
+	Title   string   `json:"title"`
+	Lines   []string `json:"lines"`
+}
+
+// SearchFailure is a project whose search failed.
+type SearchFailure struct {
+	Project string `json:"project"`
+	Error   string `json:"error"`
+}
+
+// SearchResult is the merged result of SearchAll.
+type SearchResult struct {
+	Pages    []SearchHit     `json:"pages"`
+	Failures []SearchFailure `json:"failures,omitempty"`
+}
+
+// SearchAll runs a full-text search for query in every project concurrently,
+// all within timeout. The hits are merged in project order, the primary
+// project first. A project whose search fails or does not finish in time is
+// reported in Failures without affecting the others; an error is returned
+// only if the search failed in every project.
+func (s *Set) SearchAll(ctx context.Context, query string, timeout time.Duration) (*SearchResult, error) {
+	if timeout <= 0 {
+		timeout = DefaultSearchTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	hits := make([][]SearchHit, len(s.clients))
+	errs := make([]error, len(s.clients))
+	var wg sync.WaitGroup
+	for i, c := range s.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list, err := c.SearchPages(ctx, query)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			for _, p := range list.Pages {
+				hits[i] = append(hits[i], SearchHit{Project: c.ProjectName(), Title: p.Title, Lines: p.Lines})
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := &SearchResult{Pages: []SearchHit{}}
+	for i, c := range s.clients {
+		if errs[i] != nil {
+			result.Failures = append(result.Failures, SearchFailure{Project: c.ProjectName(), Error: errs[i].Error()})
+			continue
+		}
+		result.Pages = append(result.Pages, hits[i]...)
+	}
+	if len(result.Failures) == len(s.clients) {
+		return nil, fmt.Errorf("search failed in every project: %w", errors.Join(errs...))
+	}
+	return result, nil
+}
@@ -0,0 +1,99 @@
+package projects
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// searchBackend answers searches with one hit per project, fails for
+// project "broken" and stalls for project "slow".
+func searchBackend(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		project := strings.Split(strings.TrimPrefix(r.URL.Path, "/pages/"), "/")[0]
+		switch project {
+		case "broken":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "slow":
+			<-r.Context().Done()
+		default:
+			fmt.Fprintf(w, `{"pages":[{"title":"%s doc","lines":["%s"]}]}`, project, r.URL.Query().Get("q"))
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestSet_SearchAll(t *testing.T) {
+	ts := searchBackend(t)
+
+	tests := map[string]struct {
+		projects     []string
+		wantPages    []SearchHit
+		wantFailures []string
+		wantErr      bool
+	}{
+		"ok: all projects": {
+			projects: []string{"main", "team"},
+			wantPages: []SearchHit{
+				{Project: "main", Title: "main doc", Lines: []string{"design"}},
+				{Project: "team", Title: "team doc", Lines: []string{"design"}},
+			},
+		},
+		"ok: partial failures": {
+			projects: []string{"main", "broken", "slow", "team"},
+			wantPages: []SearchHit{
+				{Project: "main", Title: "main doc", Lines: []string{"design"}},
+				{Project: "team", Title: "team doc", Lines: []string{"design"}},
+			},
+			wantFailures: []string{"broken", "slow"},
+		},
+		"err: every project fails": {
+			projects: []string{"broken", "slow"},
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &config.Config{ProjectName: tt.projects[0]}
+			for _, p := range tt.projects[1:] {
+				cfg.Projects = append(cfg.Projects, config.Project{Name: p})
+			}
+			s := FromConfig(cfg, scrapbox.WithBaseURL(ts.URL))
+
+			start := time.Now()
+			got, err := s.SearchAll(context.Background(), "design", 200*time.Millisecond)
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("SearchAll() took %v, want the deadline to stop it", elapsed)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SearchAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.wantPages, got.Pages); diff != "" {
+				t.Errorf("SearchAll() pages mismatch (-want +got):\n%s", diff)
+			}
+			var failures []string
+			for _, f := range got.Failures {
+				if f.Error == "" {
+					t.Errorf("failure of %s has no error", f.Project)
+				}
+				failures = append(failures, f.Project)
+			}
+			if diff := cmp.Diff(tt.wantFailures, failures); diff != "" {
+				t.Errorf("SearchAll() failures mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Package prompts builds the MCP prompts offered for common Scrapbox
+// workflows.
+//
+// Prompt results are made of a user message with the instructions followed by
+// one message per page the prompt refers to. Pages are embedded as resource
+// contents under their scrapbox:// URI, so clients can render them and fetch
+// them again through resources/read.
+package prompts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/history"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// Prompt names.
+const (
+	SummarizePage     = "summarize_page"
+	WeeklyDigest      = "weekly_digest"
+	DraftMeetingNotes = "draft_meeting_notes"
+	AnswerFromProject = "answer_from_project"
+)
+
+const (
+	// defaultDigestRange is the period covered by weekly_digest without since.
+	defaultDigestRange = 7 * 24 * time.Hour
+	// digestPageLimit and digestMaxChars bound the pages embedded in and the
+	// changed lines listed by weekly_digest.
+	digestPageLimit = 10
+	digestMaxChars  = 20000
+	// meetingPageLimit and answerPageLimit bound the search results embedded
+	// by draft_meeting_notes and answer_from_project.
+	meetingPageLimit = 3
+	answerPageLimit  = 5
+)
+
+// ErrUnknownPrompt is returned by Get for names not listed in Prompts.
+var ErrUnknownPrompt = errors.New("unknown prompt")
+
+// Source is the subset of scrapbox.Client used to build prompts.
+type Source interface {
+	resources.Source
+	history.RecentSource
+	SearchPages(ctx context.Context, query string) (*scrapbox.SearchPageList, error)
+}
+
+// Argument describes a prompt argument.
+type Argument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Prompt describes a prompt.
+type Prompt struct {
+	Name        string
+	Description string
+	Arguments   []Argument
+}
+
+// Prompts lists the prompts in the order they are offered.
+var Prompts = []Prompt{
+	{
+		Name:        SummarizePage,
+		Description: "Summarize a Scrapbox page",
+		Arguments: []Argument{
+			{Name: "page_title", Description: "Title of the page to summarize", Required: true},
+		},
+	},
+	{
+		Name:        WeeklyDigest,
+		Description: "Write a digest of the pages changed in the project",
+		Arguments: []Argument{
+			{Name: "since", Description: "Start of the period as an RFC 3339 timestamp or YYYY-MM-DD date or Unix seconds (defaults to 7 days ago)"},
+		},
+	},
+	{
+		Name:        DraftMeetingNotes,
+		Description: "Draft a meeting notes page in Scrapbox notation",
+		Arguments: []Argument{
+			{Name: "topic", Description: "Topic of the meeting", Required: true},
+		},
+	},
+	{
+		Name:        AnswerFromProject,
+		Description: "Answer a question using the pages of the project that match it",
+		Arguments: []Argument{
+			{Name: "question", Description: "Question to answer", Required: true},
+		},
+	},
+}
+
+// Message is a user message of a prompt result. Exactly one of Text and
+// Resource is set.
+type Message struct {
+	Text     string
+	Resource *resources.Contents
+}
+
+// Result is the result of a prompt.
+type Result struct {
+	Description string
+	Messages    []Message
+}
+
+// Get builds the prompt called name from args.
+func Get(ctx context.Context, src Source, name string, args map[string]string) (*Result, error) {
+	p, ok := lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+	for _, a := range p.Arguments {
+		if a.Required && strings.TrimSpace(args[a.Name]) == "" {
+			return nil, fmt.Errorf("missing required argument %q", a.Name)
+		}
+	}
+
+	switch name {
+	case SummarizePage:
+		return summarizePage(ctx, src, args["page_title"])
+	case WeeklyDigest:
+		return weeklyDigest(ctx, src, args["since"])
+	case DraftMeetingNotes:
+		return draftMeetingNotes(ctx, src, args["topic"])
+	default:
+		return answerFromProject(ctx, src, args["question"])
+	}
+}
+
+func lookup(name string) (Prompt, bool) {
+	for _, p := range Prompts {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Prompt{}, false
+}
+
+func summarizePage(ctx context.Context, src Source, title string) (*Result, error) {
+	page, err := readPage(ctx, src, title)
+	if err != nil {
+		return nil, err
+	}
+	text := fmt.Sprintf(`Summarize the Scrapbox page "%s" embedded below.
+
+Start with a one-sentence overview, then list the key points, decisions and open questions as bullets. Keep [bracket] links to other pages so they can be followed.`, title)
+	return &Result{
+		Description: fmt.Sprintf("Summary of %s", title),
+		Messages:    []Message{{Text: text}, {Resource: page}},
+	}, nil
+}
+
+func weeklyDigest(ctx context.Context, src Source, sinceArg string) (*Result, error) {
+	since := time.Now().Add(-defaultDigestRange)
+	if strings.TrimSpace(sinceArg) != "" {
+		t, err := history.ParseTime(sinceArg)
+		if err != nil {
+			return nil, err
+		}
+		since = t
+	}
+	activity, err := history.RecentChanges(ctx, src, since, digestMaxChars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent changes: %w", err)
+	}
+	b, err := json.Marshal(activity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal recent changes: %w", err)
+	}
+
+	text := fmt.Sprintf(`Write a digest of the changes in the Scrapbox project %s since %s.
+
+Group related changes by theme rather than by page, say who made the notable ones, and end with the open questions or follow-ups they raise. Refer to pages as [page title] links.
+
+The changed lines by page and author are:
+
+%s`, src.ProjectName(), activity.Since, b)
+	messages := []Message{{Text: text}}
+	for i, p := range activity.Pages {
+		if i == digestPageLimit {
+			break
+		}
+		page, err := readPage(ctx, src, p.Title)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, Message{Resource: page})
+	}
+	return &Result{
+		Description: fmt.Sprintf("Digest of changes since %s", activity.Since),
+		Messages:    messages,
+	}, nil
+}
+
+func draftMeetingNotes(ctx context.Context, src Source, topic string) (*Result, error) {
+	related, err := searchPages(ctx, src, topic, meetingPageLimit)
+	if err != nil {
+		return nil, err
+	}
+	text := fmt.Sprintf(`Draft a Scrapbox page for meeting notes about "%s".
+
+Write Scrapbox notation, not Markdown:
+- The first line is the page title, e.g. "%s %s".
+- Use [* Heading] for the sections Attendees, Agenda, Notes, Decisions and Action items.
+- Indent bullets with one space per level.
+- Link pages as [page title] and add tags as #tag.
+- Write action items as "[owner] task (due date)".
+
+Leave the content as placeholders where it is not known yet.`, topic, time.Now().Format("2006-01-02"), topic)
+	if len(related) > 0 {
+		text += " Link the related pages embedded below where they are relevant."
+	}
+	return &Result{
+		Description: fmt.Sprintf("Meeting notes about %s", topic),
+		Messages:    append([]Message{{Text: text}}, related...),
+	}, nil
+}
+
+func answerFromProject(ctx context.Context, src Source, question string) (*Result, error) {
+	found, err := searchPages(ctx, src, question, answerPageLimit)
+	if err != nil {
+		return nil, err
+	}
+	var text string
+	if len(found) == 0 {
+		text = fmt.Sprintf(`Answer the question below. No page of the Scrapbox project %s matched it, so say that the project does not cover it before answering from general knowledge.
+
+Question: %s`, src.ProjectName(), question)
+	} else {
+		text = fmt.Sprintf(`Answer the question below using only the pages of the Scrapbox project %s embedded after it. Cite the pages you rely on as [page title] links, and say so if they do not contain the answer.
+
+Question: %s`, src.ProjectName(), question)
+	}
+	return &Result{
+		Description: fmt.Sprintf("Answer to %s", question),
+		Messages:    append([]Message{{Text: text}}, found...),
+	}, nil
+}
+
+// searchPages embeds up to limit pages matching query.
+func searchPages(ctx context.Context, src Source, query string, limit int) ([]Message, error) {
+	results, err := src.SearchPages(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search pages: %w", err)
+	}
+	var messages []Message
+	for _, p := range results.Pages {
+		if len(messages) == limit {
+			break
+		}
+		page, err := readPage(ctx, src, p.Title)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, Message{Resource: page})
+	}
+	return messages, nil
+}
+
+// readPage reads a page as a Markdown resource.
+func readPage(ctx context.Context, src Source, title string) (*resources.Contents, error) {
+	c, err := resources.Read(ctx, src, resources.URI(src.ProjectName(), title))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page %q: %w", title, err)
+	}
+	return c, nil
+}
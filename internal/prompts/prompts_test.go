@@ -0,0 +1,186 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+type fakeSource struct {
+	pages []scrapbox.Page
+}
+
+func (f *fakeSource) ProjectName() string { return "proj" }
+
+func (f *fakeSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	for _, p := range f.pages {
+		if p.Title == title {
+			return &p, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeSource) ListPagesWithOptions(ctx context.Context, opts scrapbox.ListPagesOptions) (*scrapbox.PageList, error) {
+	return &scrapbox.PageList{Count: len(f.pages), Pages: f.pages}, nil
+}
+
+func (f *fakeSource) ListPagesUpdatedSince(ctx context.Context, since time.Time) ([]scrapbox.Page, error) {
+	var pages []scrapbox.Page
+	for _, p := range f.pages {
+		if p.Updated > since.Unix() {
+			pages = append(pages, p)
+		}
+	}
+	return pages, nil
+}
+
+func (f *fakeSource) GetProject(ctx context.Context) (*scrapbox.Project, error) {
+	return &scrapbox.Project{Name: "proj"}, nil
+}
+
+func (f *fakeSource) SearchPages(ctx context.Context, query string) (*scrapbox.SearchPageList, error) {
+	list := &scrapbox.SearchPageList{}
+	for _, p := range f.pages {
+		for _, l := range p.Lines {
+			if strings.Contains(l.Text, query) {
+				list.Pages = append(list.Pages, scrapbox.SearchPage{Title: p.Title})
+				break
+			}
+		}
+	}
+	return list, nil
+}
+
+func page(title string, updated int64, lines ...string) scrapbox.Page {
+	p := scrapbox.Page{Title: title, Updated: updated}
+	for _, text := range append([]string{title}, lines...) {
+		p.Lines = append(p.Lines, scrapbox.Line{Text: text, Updated: updated, UserID: "u1"})
+	}
+	return p
+}
+
+func TestGet(t *testing.T) {
+	src := &fakeSource{pages: []scrapbox.Page{
+		page("Design", 1700000000, "cache layer", "[Roadmap]"),
+		page("Roadmap", 1600000000, "release plan"),
+	}}
+
+	tests := map[string]struct {
+		name         string
+		args         map[string]string
+		wantURIs     []string
+		wantInText   []string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		"summarize_page": {
+			name:       SummarizePage,
+			args:       map[string]string{"page_title": "Design"},
+			wantURIs:   []string{"scrapbox://proj/Design"},
+			wantInText: []string{`"Design"`},
+		},
+		"weekly_digest": {
+			name:       WeeklyDigest,
+			args:       map[string]string{"since": "2023-01-01"},
+			wantURIs:   []string{"scrapbox://proj/Design"},
+			wantInText: []string{"since 2023-01-01T00:00:00Z", `"cache layer"`},
+		},
+		"draft_meeting_notes": {
+			name:       DraftMeetingNotes,
+			args:       map[string]string{"topic": "release"},
+			wantURIs:   []string{"scrapbox://proj/Roadmap"},
+			wantInText: []string{"[* Heading]", "release"},
+		},
+		"answer_from_project": {
+			name:       AnswerFromProject,
+			args:       map[string]string{"question": "cache"},
+			wantURIs:   []string{"scrapbox://proj/Design"},
+			wantInText: []string{"Question: cache", "[page title]"},
+		},
+		"answer_from_project without matches": {
+			name:       AnswerFromProject,
+			args:       map[string]string{"question": "nothing"},
+			wantInText: []string{"No page of the Scrapbox project proj matched"},
+		},
+		"err: missing argument": {
+			name:    SummarizePage,
+			args:    map[string]string{},
+			wantErr: true,
+		},
+		"err: missing page": {
+			name:    SummarizePage,
+			args:    map[string]string{"page_title": "Nope"},
+			wantErr: true,
+		},
+		"err: invalid since": {
+			name:    WeeklyDigest,
+			args:    map[string]string{"since": "last week"},
+			wantErr: true,
+		},
+		"err: unknown prompt": {
+			name:         "nope",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Get(context.Background(), src, tt.name, tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Get() error = nil, wantErr true")
+				}
+				if tt.wantNotFound && !errors.Is(err, ErrUnknownPrompt) {
+					t.Errorf("Get() error = %v, want ErrUnknownPrompt", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if len(got.Messages) == 0 || got.Messages[0].Text == "" {
+				t.Fatalf("Get() first message is not the instructions: %+v", got.Messages)
+			}
+			for _, s := range tt.wantInText {
+				if !strings.Contains(got.Messages[0].Text, s) {
+					t.Errorf("instructions do not contain %q:\n%s", s, got.Messages[0].Text)
+				}
+			}
+
+			var uris []string
+			for _, m := range got.Messages[1:] {
+				if m.Resource == nil {
+					t.Fatalf("message after the instructions is not a resource: %+v", m)
+				}
+				if m.Resource.Text == "" {
+					t.Errorf("resource %s has no content", m.Resource.URI)
+				}
+				uris = append(uris, m.Resource.URI)
+			}
+			if diff := cmp.Diff(tt.wantURIs, uris); diff != "" {
+				t.Errorf("embedded resources mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrompts_Definitions(t *testing.T) {
+	for _, p := range Prompts {
+		for _, a := range p.Arguments {
+			if a.Description == "" {
+				t.Errorf("%s: argument %s has no description", p.Name, a.Name)
+			}
+		}
+		if _, ok := lookup(p.Name); !ok {
+			t.Errorf("lookup(%q) failed", p.Name)
+		}
+	}
+}
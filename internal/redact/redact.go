@@ -0,0 +1,234 @@
+// Package redact removes secrets and personal data, such as the tokens and
+// phone numbers pasted into pages, from the outputs of the MCP servers
+// before they leave the server. Every adapter passes the text of its tool
+// results, resources and prompts through a Redactor, which replaces each
+// match of its detectors with a placeholder naming the detector, such as
+// [REDACTED:email], and counts it in the "redactions" expvar map.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Detectors are the names of the built-in detectors, in the order they are
+// applied.
+var Detectors = []string{"jwt", "aws_key", "api_key", "email", "phone"}
+
+// builtin are the built-in detectors by name.
+var builtin = map[string]*regexp.Regexp{
+	// JSON Web Tokens: a base64url-encoded JSON header and payload, and a
+	// signature.
+	"jwt": regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	// AWS access key IDs, and secret access keys assigned to their usual
+	// names.
+	"aws_key": regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b|(?i)\baws_secret_access_key\b["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`),
+	// API keys with the prefixes of well-known services: OpenAI, Anthropic,
+	// Stripe, GitHub, GitLab, Slack and Google.
+	"api_key": regexp.MustCompile(`\b(?:sk-(?:proj-|ant-)?[A-Za-z0-9_-]{20,}|[sr]k_(?:live|test)_[A-Za-z0-9]{16,}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|glpat-[A-Za-z0-9_-]{20,}|xox[abposr]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`),
+	"email":   regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+	// International numbers, and national ones written with separators
+	// such as 03-1234-5678 or (555) 123-4567, but not dates.
+	"phone": regexp.MustCompile(`\+\d{1,3}[ -]?\(?\d{1,4}\)?[ -]?\d{1,4}[ -]?\d{3,4}\b|\b0\d{1,4}-\d{1,4}-\d{3,4}\b|\(\d{3}\) ?\d{3}-\d{4}\b`),
+}
+
+// Known reports whether name is one of Detectors.
+func Known(name string) bool {
+	return slices.Contains(Detectors, name)
+}
+
+// Placeholder returns the text replacing the matches of the named detector.
+func Placeholder(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// counts are the redactions made by detector name.
+var counts = expvar.NewMap("redactions")
+
+// Count returns the number of matches of the named detector redacted since
+// the server started.
+func Count(name string) int64 {
+	if v, ok := counts.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// Pattern is a configured detector: a regular expression whose matches are
+// replaced by the placeholder of Name.
+type Pattern struct {
+	Name   string
+	Regexp string
+}
+
+// Rules select the detectors of a Redactor.
+type Rules struct {
+	// Detectors are the built-in detectors applied: all of them if nil, and
+	// none if empty.
+	Detectors []string
+	// Patterns are applied after the built-in detectors.
+	Patterns []Pattern
+}
+
+// detector is a regular expression and the name of its placeholder.
+type detector struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor redacts text with the detectors of its rules, which can be
+// replaced while it is in use. A nil Redactor redacts nothing.
+type Redactor struct {
+	mu        sync.RWMutex
+	detectors []detector
+}
+
+// New creates a Redactor applying rules.
+func New(rules Rules) (*Redactor, error) {
+	r := &Redactor{}
+	if err := r.Set(rules); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Set replaces the rules of the Redactor, unless one of them is invalid.
+func (r *Redactor) Set(rules Rules) error {
+	names := rules.Detectors
+	if names == nil {
+		names = Detectors
+	}
+	for _, name := range names {
+		if !Known(name) {
+			return fmt.Errorf("unknown detector %s", name)
+		}
+	}
+	var detectors []detector
+	for _, name := range Detectors {
+		if slices.Contains(names, name) {
+			detectors = append(detectors, detector{name: name, re: builtin[name]})
+		}
+	}
+	for _, p := range rules.Patterns {
+		re, err := regexp.Compile(p.Regexp)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s: %w", p.Name, err)
+		}
+		detectors = append(detectors, detector{name: p.Name, re: re})
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.detectors = detectors
+	return nil
+}
+
+// Redact replaces the matches of every detector in s with their
+// placeholders.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	detectors := r.detectors
+	r.mu.RUnlock()
+	for _, d := range detectors {
+		n := 0
+		s = d.re.ReplaceAllStringFunc(s, func(string) string {
+			n++
+			return Placeholder(d.name)
+		})
+		if n > 0 {
+			counts.Add(d.name, int64(n))
+		}
+	}
+	return s
+}
+
+// Text redacts s, the text of a tool result, a resource or a prompt. JSON
+// objects and arrays are redacted key by key and value by value, so that a
+// match spanning an escape sequence cannot break them; they are only
+// re-encoded if anything was redacted.
+func (r *Redactor) Text(s string) string {
+	if r == nil {
+		return s
+	}
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") || !json.Valid([]byte(trimmed)) {
+		return r.Redact(s)
+	}
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return r.Redact(s)
+	}
+	v, changed := r.value(v)
+	if !changed {
+		return s
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return r.Redact(s)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// value redacts the strings of v, a decoded JSON value, including the keys
+// of its objects, and reports whether any of them changed. Keys redacted to
+// the same placeholder are numbered so that no value is lost.
+func (r *Redactor) value(v any) (any, bool) {
+	switch v := v.(type) {
+	case string:
+		s := r.Redact(v)
+		return s, s != v
+	case []any:
+		changed := false
+		for i, e := range v {
+			var c bool
+			v[i], c = r.value(e)
+			changed = changed || c
+		}
+		return v, changed
+	case map[string]any:
+		changed := false
+		keys := make(map[string]string, len(v))
+		for k, e := range v {
+			var c bool
+			v[k], c = r.value(e)
+			changed = changed || c
+			if rk := r.Redact(k); rk != k {
+				keys[k] = rk
+			}
+		}
+		if len(keys) == 0 {
+			return v, changed
+		}
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			if _, ok := keys[k]; !ok {
+				m[k] = e
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(keys)) {
+			rk := keys[k]
+			for n := 2; ; n++ {
+				if _, ok := m[rk]; !ok {
+					break
+				}
+				rk = fmt.Sprintf("%s#%d", keys[k], n)
+			}
+			m[rk] = v[k]
+		}
+		return m, true
+	}
+	return v, false
+}
@@ -0,0 +1,181 @@
+// Package resources exposes Scrapbox pages as MCP resources addressed by
+// scrapbox://{project}/{title} URIs.
+//
+// A page can be read as Markdown (the default) or, by appending
+// "?format=json" to its URI, as the page JSON returned by the Scrapbox API.
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/export"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+const (
+	// Scheme is the URI scheme of page resources.
+	Scheme = "scrapbox"
+	// URITemplate is the RFC 6570 template matching every page resource.
+	URITemplate = Scheme + "://{project}/{title}"
+
+	MIMEMarkdown = "text/markdown"
+	MIMEJSON     = "application/json"
+
+	// DefaultLimit is the number of pages List returns when limit is not positive.
+	DefaultLimit = 50
+)
+
+// ErrNotFound is returned for URIs that do not name a page of the project.
+var ErrNotFound = errors.New("resource not found")
+
+// Source is the subset of scrapbox.Client used to list and read resources.
+type Source interface {
+	ProjectName() string
+	GetPage(ctx context.Context, title string) (*scrapbox.Page, error)
+	ListPagesWithOptions(ctx context.Context, opts scrapbox.ListPagesOptions) (*scrapbox.PageList, error)
+}
+
+// Resource describes a page resource.
+type Resource struct {
+	URI         string
+	Name        string
+	Description string
+	MIMEType    string
+	Pinned      bool
+	// LastModified is the page's updated time, or zero if unknown.
+	LastModified time.Time
+}
+
+// Contents is the content of a page resource.
+type Contents struct {
+	URI          string
+	MIMEType     string
+	Text         string
+	LastModified time.Time
+}
+
+// FormatTime formats t as the ISO 8601 string used for lastModified
+// annotations, or returns "" for the zero time.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// URI returns the resource URI of the page titled title.
+func URI(project, title string) string {
+	return Scheme + "://" + url.PathEscape(project) + "/" + url.PathEscape(title)
+}
+
+// ParseURI splits a page resource URI into its project, page title and the
+// MIME type requested through the format query parameter.
+func ParseURI(uri string) (project, title, mimeType string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	if u.Scheme != Scheme || u.Host == "" {
+		return "", "", "", fmt.Errorf("%w: %s is not a %s:// URI", ErrNotFound, uri, Scheme)
+	}
+	title = strings.TrimPrefix(u.Path, "/")
+	if title == "" {
+		return "", "", "", fmt.Errorf("%w: %s has no page title", ErrNotFound, uri)
+	}
+	switch format := u.Query().Get("format"); format {
+	case "", "markdown", "md":
+		mimeType = MIMEMarkdown
+	case "json":
+		mimeType = MIMEJSON
+	default:
+		return "", "", "", fmt.Errorf("unsupported format %q: use markdown or json", format)
+	}
+	return u.Host, title, mimeType, nil
+}
+
+// List returns the pinned pages followed by the most recently updated pages,
+// at most limit in total.
+func List(ctx context.Context, src Source, limit int) ([]Resource, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	list, err := src.ListPagesWithOptions(ctx, scrapbox.ListPagesOptions{Limit: limit, Sort: "updated"})
+	if err != nil {
+		return nil, err
+	}
+	pages := append([]scrapbox.Page(nil), list.Pages...)
+	sort.SliceStable(pages, func(i, j int) bool {
+		if (pages[i].Pin != 0) != (pages[j].Pin != 0) {
+			return pages[i].Pin != 0
+		}
+		return pages[i].Updated > pages[j].Updated
+	})
+	if len(pages) > limit {
+		pages = pages[:limit]
+	}
+
+	resources := make([]Resource, 0, len(pages))
+	for _, p := range pages {
+		r := Resource{
+			URI:      URI(src.ProjectName(), p.Title),
+			Name:     p.Title,
+			MIMEType: MIMEMarkdown,
+			Pinned:   p.Pin != 0,
+		}
+		if p.Updated != 0 {
+			r.LastModified = time.Unix(p.Updated, 0).UTC()
+		}
+		r.Description = describe(r)
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+func describe(r Resource) string {
+	kind := "Scrapbox page"
+	if r.Pinned {
+		kind = "Pinned Scrapbox page"
+	}
+	if r.LastModified.IsZero() {
+		return kind
+	}
+	return kind + ", last modified " + FormatTime(r.LastModified)
+}
+
+// Read returns the content of the page named by uri.
+func Read(ctx context.Context, src Source, uri string) (*Contents, error) {
+	project, title, mimeType, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if project != src.ProjectName() {
+		return nil, fmt.Errorf("%w: project %q is not served", ErrNotFound, project)
+	}
+	page, err := src.GetPage(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Contents{URI: uri, MIMEType: mimeType}
+	if page.Updated != 0 {
+		c.LastModified = time.Unix(page.Updated, 0).UTC()
+	}
+	switch mimeType {
+	case MIMEJSON:
+		b, err := json.Marshal(page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal page: %w", err)
+		}
+		c.Text = string(b)
+	default:
+		c.Text = export.Markdown(page, page.Title, func(title string) string { return title })
+	}
+	return c, nil
+}
@@ -0,0 +1,162 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+type fakeSource struct {
+	pages []scrapbox.Page
+	opts  scrapbox.ListPagesOptions
+}
+
+func (f *fakeSource) ProjectName() string { return "proj" }
+
+func (f *fakeSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	for _, p := range f.pages {
+		if p.Title == title {
+			return &p, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeSource) ListPagesWithOptions(ctx context.Context, opts scrapbox.ListPagesOptions) (*scrapbox.PageList, error) {
+	f.opts = opts
+	return &scrapbox.PageList{Count: len(f.pages), Pages: f.pages}, nil
+}
+
+func TestParseURI(t *testing.T) {
+	tests := map[string]struct {
+		uri         string
+		wantProject string
+		wantTitle   string
+		wantMIME    string
+		wantErr     bool
+	}{
+		"markdown by default": {
+			uri:         "scrapbox://proj/Hello%20World",
+			wantProject: "proj",
+			wantTitle:   "Hello World",
+			wantMIME:    MIMEMarkdown,
+		},
+		"json format": {
+			uri:         "scrapbox://proj/Page?format=json",
+			wantProject: "proj",
+			wantTitle:   "Page",
+			wantMIME:    MIMEJSON,
+		},
+		"escaped slash in title": {
+			uri:         URI("proj", "a/b"),
+			wantProject: "proj",
+			wantTitle:   "a/b",
+			wantMIME:    MIMEMarkdown,
+		},
+		"other scheme": {
+			uri:     "https://scrapbox.io/proj/Page",
+			wantErr: true,
+		},
+		"missing title": {
+			uri:     "scrapbox://proj/",
+			wantErr: true,
+		},
+		"unknown format": {
+			uri:     "scrapbox://proj/Page?format=html",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			project, title, mimeType, err := ParseURI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if project != tt.wantProject || title != tt.wantTitle || mimeType != tt.wantMIME {
+				t.Errorf("ParseURI() = %q, %q, %q, want %q, %q, %q", project, title, mimeType, tt.wantProject, tt.wantTitle, tt.wantMIME)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	src := &fakeSource{pages: []scrapbox.Page{
+		{Title: "Recent", Updated: 300},
+		{Title: "Pinned", Pin: 1, Updated: 100},
+		{Title: "Unknown"},
+	}}
+
+	got, err := List(context.Background(), src, 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []Resource{
+		{URI: "scrapbox://proj/Pinned", Name: "Pinned", Description: "Pinned Scrapbox page, last modified 1970-01-01T00:01:40Z", MIMEType: MIMEMarkdown, Pinned: true, LastModified: time.Unix(100, 0).UTC()},
+		{URI: "scrapbox://proj/Recent", Name: "Recent", Description: "Scrapbox page, last modified 1970-01-01T00:05:00Z", MIMEType: MIMEMarkdown, LastModified: time.Unix(300, 0).UTC()},
+		{URI: "scrapbox://proj/Unknown", Name: "Unknown", Description: "Scrapbox page", MIMEType: MIMEMarkdown},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(scrapbox.ListPagesOptions{Limit: DefaultLimit, Sort: "updated"}, src.opts); diff != "" {
+		t.Errorf("List() options mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRead(t *testing.T) {
+	src := &fakeSource{pages: []scrapbox.Page{
+		{Title: "Page", Updated: 100, Lines: []scrapbox.Line{{Text: "Page"}, {Text: "see [Other]"}}},
+	}}
+
+	tests := map[string]struct {
+		uri          string
+		wantMIME     string
+		wantContains string
+		wantNotFound bool
+	}{
+		"markdown": {
+			uri:          "scrapbox://proj/Page",
+			wantMIME:     MIMEMarkdown,
+			wantContains: "see [[Other]]",
+		},
+		"json": {
+			uri:          "scrapbox://proj/Page?format=json",
+			wantMIME:     MIMEJSON,
+			wantContains: `"text":"see [Other]"`,
+		},
+		"other project": {
+			uri:          "scrapbox://other/Page",
+			wantNotFound: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Read(context.Background(), src, tt.uri)
+			if tt.wantNotFound {
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("Read() error = %v, want ErrNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if got.URI != tt.uri || got.MIMEType != tt.wantMIME {
+				t.Errorf("Read() = %q %q, want %q %q", got.URI, got.MIMEType, tt.uri, tt.wantMIME)
+			}
+			if !strings.Contains(got.Text, tt.wantContains) {
+				t.Errorf("Read() text does not contain %q:\n%s", tt.wantContains, got.Text)
+			}
+			if !got.LastModified.Equal(time.Unix(100, 0)) {
+				t.Errorf("Read() LastModified = %v", got.LastModified)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Client-side search over window.SEARCH_INDEX, which is loaded from
+// search-index.js so that the site also works when opened from disk.
+(function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var index = window.SEARCH_INDEX || [];
+  if (!input || !results) {
+    return;
+  }
+
+  function search(query) {
+    var terms = query.toLowerCase().split(/\s+/).filter(Boolean);
+    if (terms.length === 0) {
+      return [];
+    }
+    var hits = [];
+    index.forEach(function (page) {
+      var title = page.title.toLowerCase();
+      var text = page.text.toLowerCase();
+      var score = 0;
+      for (var i = 0; i < terms.length; i++) {
+        var inTitle = title.indexOf(terms[i]) >= 0;
+        var inText = text.indexOf(terms[i]) >= 0;
+        if (!inTitle && !inText) {
+          return;
+        }
+        score += inTitle ? 10 : 1;
+      }
+      hits.push({ page: page, score: score });
+    });
+    hits.sort(function (a, b) {
+      return b.score - a.score || (b.page.updated || 0) - (a.page.updated || 0);
+    });
+    return hits.slice(0, 50);
+  }
+
+  input.addEventListener("input", function () {
+    results.textContent = "";
+    search(input.value).forEach(function (hit) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = encodeURI(hit.page.url);
+      a.textContent = hit.page.title;
+      li.appendChild(a);
+      results.appendChild(li);
+    });
+  });
+})();
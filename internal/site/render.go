@@ -0,0 +1,131 @@
+package site
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox/notation"
+)
+
+// renderer turns notation blocks into HTML. Links are resolved through the
+// site so that they point at generated files.
+type renderer struct {
+	site *Site
+	// root is the relative path from the page being rendered to the site root.
+	root string
+}
+
+func (r *renderer) blocks(blocks []notation.Block) string {
+	var b strings.Builder
+	for _, block := range blocks {
+		switch block := block.(type) {
+		case *notation.Line:
+			class := "line"
+			if block.Quote {
+				class += " quote"
+			}
+			fmt.Fprintf(&b, `<div class="%s" style="--indent:%d">%s</div>`+"\n", class, block.Indent, r.nodes(block.Nodes))
+		case *notation.CodeBlock:
+			fmt.Fprintf(&b, `<div class="code" style="--indent:%d"><div class="code-name">%s</div><pre><code>%s</code></pre></div>`+"\n",
+				block.Indent, html.EscapeString(block.FileName), html.EscapeString(strings.Join(block.Lines, "\n")))
+		case *notation.Table:
+			fmt.Fprintf(&b, `<div class="table" style="--indent:%d"><div class="table-name">%s</div><table>`, block.Indent, html.EscapeString(block.Name))
+			for _, row := range block.Rows {
+				b.WriteString("<tr>")
+				for _, cell := range row {
+					b.WriteString("<td>" + r.nodes(notation.ParseInline(cell)) + "</td>")
+				}
+				b.WriteString("</tr>")
+			}
+			b.WriteString("</table></div>\n")
+		}
+	}
+	return b.String()
+}
+
+func (r *renderer) nodes(nodes []notation.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *notation.Text:
+			b.WriteString(html.EscapeString(n.Text))
+		case *notation.Link:
+			b.WriteString(r.pageLink(n.Title, n.Title, "link"))
+		case *notation.Hashtag:
+			b.WriteString(r.tagLink(n.Tag))
+		case *notation.Icon:
+			b.WriteString(r.pageLink(n.Title, "["+n.Title+"]", "icon"))
+		case *notation.ExternalLink:
+			label := n.Label
+			if label == "" {
+				label = n.URL
+			}
+			fmt.Fprintf(&b, `<a class="external" href="%s" rel="noopener">%s</a>`, html.EscapeString(safeURL(n.URL)), html.EscapeString(label))
+		case *notation.Image:
+			fmt.Fprintf(&b, `<img src="%s" alt="" loading="lazy">`, html.EscapeString(safeURL(n.URL)))
+		case *notation.Strong:
+			b.WriteString("<strong>" + r.nodes(n.Nodes) + "</strong>")
+		case *notation.Decoration:
+			b.WriteString(decorate(n.Marks, r.nodes(n.Nodes)))
+		case *notation.Code:
+			b.WriteString("<code>" + html.EscapeString(n.Text) + "</code>")
+		case *notation.Formula:
+			b.WriteString(`<span class="formula">` + html.EscapeString(n.Formula) + "</span>")
+		}
+	}
+	return b.String()
+}
+
+// pageLink links to the generated file of title, or renders a dangling link
+// when the page does not exist.
+func (r *renderer) pageLink(title, label, class string) string {
+	if file, ok := r.site.pageFile(title); ok {
+		return fmt.Sprintf(`<a class="%s" href="%s">%s</a>`, class, html.EscapeString(r.href(file)), html.EscapeString(label))
+	}
+	return fmt.Sprintf(`<span class="%s missing">%s</span>`, class, html.EscapeString(label))
+}
+
+// tagLink links a hashtag to its tag index page.
+func (r *renderer) tagLink(tag string) string {
+	return fmt.Sprintf(`<a class="tag" href="%s">#%s</a>`, html.EscapeString(r.href(r.site.tagFile(tag))), html.EscapeString(tag))
+}
+
+// href returns an escaped relative URL for a file below the site root.
+func (r *renderer) href(file string) string {
+	return r.root + (&url.URL{Path: file}).EscapedPath()
+}
+
+// decorate wraps rendered text in the elements matching Scrapbox marks.
+func decorate(marks, text string) string {
+	if strings.Contains(marks, "_") {
+		text = "<u>" + text + "</u>"
+	}
+	if strings.Contains(marks, "-") {
+		text = "<s>" + text + "</s>"
+	}
+	if strings.Contains(marks, "/") {
+		text = "<em>" + text + "</em>"
+	}
+	if n := strings.Count(marks, "*"); n > 0 {
+		text = fmt.Sprintf(`<strong class="level-%d">%s</strong>`, min(n, 5), text)
+	}
+	return text
+}
+
+// safeURL only lets http(s) URLs through, so page text cannot inject
+// javascript: links into the generated site.
+func safeURL(u string) string {
+	if strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://") {
+		return u
+	}
+	return "#"
+}
+
+// relRoot returns the path from a file below the site root back to the root.
+func relRoot(file string) string {
+	depth := strings.Count(path.Clean(file), "/")
+	return strings.Repeat("../", depth)
+}
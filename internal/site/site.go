@@ -0,0 +1,304 @@
+// Package site renders a page store as a self-contained static HTML site
+// with backlinks, tag index pages and a client-side search index.
+package site
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/export"
+	"github.com/takak2166/scrapbox-mcp/internal/pagestore"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox/notation"
+)
+
+//go:embed assets
+var assets embed.FS
+
+var layout = template.Must(template.ParseFS(assets, "assets/layout.html"))
+
+// snippetLength is the number of characters of page text kept per page in
+// the search index.
+const snippetLength = 2000
+
+// searchEntry is one record of search-index.json.
+type searchEntry struct {
+	Title   string   `json:"title"`
+	URL     string   `json:"url"`
+	Tags    []string `json:"tags,omitempty"`
+	Updated int64    `json:"updated,omitempty"`
+	Text    string   `json:"text"`
+}
+
+// pageView is the data passed to the layout template.
+type pageView struct {
+	Project string
+	Title   string
+	Root    string
+	Updated string
+	Body    template.HTML
+	Links   []linkView
+	// LinksTitle is the heading of the Links section.
+	LinksTitle string
+	Search     bool
+}
+
+type linkView struct {
+	Title string
+	Href  string
+}
+
+// Site generates a static site from a page store.
+type Site struct {
+	Store   *pagestore.Store
+	Dir     string
+	Project string
+
+	pages map[string]*page
+	files map[string]string
+	tags  map[string][]string
+}
+
+type page struct {
+	entry  pagestore.Entry
+	file   string
+	data   *scrapbox.Page
+	blocks []notation.Block
+}
+
+// Generate writes the site into Dir, replacing any previous output.
+func (s *Site) Generate() error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	for _, dir := range []string{"pages", "tags"} {
+		if err := os.RemoveAll(filepath.Join(s.Dir, dir)); err != nil {
+			return fmt.Errorf("failed to clean %s: %w", dir, err)
+		}
+		if err := os.MkdirAll(filepath.Join(s.Dir, dir), 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	backlinks := s.backlinks()
+	var index []searchEntry
+	for _, key := range s.sortedKeys() {
+		p := s.pages[key]
+		r := &renderer{site: s, root: relRoot(p.file)}
+		view := pageView{
+			Project:    s.Project,
+			Title:      p.entry.Title,
+			Root:       r.root,
+			Body:       template.HTML(r.blocks(p.blocks)),
+			LinksTitle: "Backlinks",
+		}
+		if p.entry.Updated != 0 {
+			view.Updated = time.Unix(p.entry.Updated, 0).UTC().Format("2006-01-02 15:04 MST")
+		}
+		for _, from := range backlinks[key] {
+			view.Links = append(view.Links, linkView{Title: from, Href: r.href(s.files[notation.NormalizeTitle(from)])})
+		}
+		if err := s.write(p.file, view); err != nil {
+			return err
+		}
+		index = append(index, searchEntry{
+			Title:   p.entry.Title,
+			URL:     p.file,
+			Tags:    notation.Tags(p.blocks),
+			Updated: p.entry.Updated,
+			Text:    searchText(p.data),
+		})
+	}
+
+	if err := s.writeTags(); err != nil {
+		return err
+	}
+	if err := s.writeIndex(index); err != nil {
+		return err
+	}
+	return s.writeAssets(index)
+}
+
+// load reads and parses every page of the store and assigns output files.
+func (s *Site) load() error {
+	s.pages = map[string]*page{}
+	s.files = map[string]string{}
+	s.tags = map[string][]string{}
+	used := map[string]bool{}
+	for _, e := range s.Store.Entries() {
+		data, err := s.Store.Page(e.ID)
+		if err != nil {
+			return err
+		}
+		var lines []string
+		for i, l := range data.Lines {
+			if i > 0 {
+				lines = append(lines, l.Text)
+			}
+		}
+		key := notation.NormalizeTitle(e.Title)
+		file := uniqueFile("pages", e.Title, used)
+		p := &page{entry: e, file: file, data: data, blocks: notation.Parse(lines)}
+		s.pages[key] = p
+		s.files[key] = file
+		for _, tag := range notation.Tags(p.blocks) {
+			tkey := notation.NormalizeTitle(tag)
+			s.tags[tkey] = append(s.tags[tkey], e.Title)
+		}
+	}
+	return nil
+}
+
+// backlinks maps each page key to the titles of pages linking to it.
+func (s *Site) backlinks() map[string][]string {
+	links := map[string][]string{}
+	for _, key := range s.sortedKeys() {
+		p := s.pages[key]
+		for _, title := range notation.Titles(p.blocks) {
+			target := notation.NormalizeTitle(title)
+			if target == key {
+				continue
+			}
+			links[target] = append(links[target], p.entry.Title)
+		}
+	}
+	return links
+}
+
+func (s *Site) sortedKeys() []string {
+	keys := make([]string, 0, len(s.pages))
+	for k := range s.pages {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return s.pages[keys[i]].entry.Title < s.pages[keys[j]].entry.Title })
+	return keys
+}
+
+func (s *Site) pageFile(title string) (string, bool) {
+	file, ok := s.files[notation.NormalizeTitle(title)]
+	return file, ok
+}
+
+func (s *Site) tagFile(tag string) string {
+	return "tags/" + export.SanitizeFileName(notation.NormalizeTitle(tag)) + ".html"
+}
+
+// writeTags writes one index page per hashtag plus tags.html listing them all.
+func (s *Site) writeTags() error {
+	var all []linkView
+	var keys []string
+	for k := range s.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		file := s.tagFile(key)
+		r := &renderer{site: s, root: relRoot(file)}
+		view := pageView{Project: s.Project, Title: "#" + key, Root: r.root, LinksTitle: "Pages"}
+		if p, ok := s.pages[key]; ok {
+			view.Body = template.HTML(r.pageLink(p.entry.Title, p.entry.Title, "link"))
+		}
+		for _, title := range s.tags[key] {
+			view.Links = append(view.Links, linkView{Title: title, Href: r.href(s.files[notation.NormalizeTitle(title)])})
+		}
+		if err := s.write(file, view); err != nil {
+			return err
+		}
+		all = append(all, linkView{Title: fmt.Sprintf("#%s (%d)", key, len(s.tags[key])), Href: file})
+	}
+	return s.write("tags.html", pageView{Project: s.Project, Title: "Tags", LinksTitle: "Tags", Links: all})
+}
+
+// writeIndex writes index.html listing pages by most recent update.
+func (s *Site) writeIndex(index []searchEntry) error {
+	recent := append([]searchEntry(nil), index...)
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].Updated > recent[j].Updated })
+	view := pageView{Project: s.Project, Title: s.Project, LinksTitle: "Pages", Search: true}
+	for _, e := range recent {
+		view.Links = append(view.Links, linkView{Title: e.Title, Href: (&renderer{}).href(e.URL)})
+	}
+	return s.write("index.html", view)
+}
+
+// writeAssets copies the static assets and writes the search index both as
+// JSON and as a script, since browsers refuse fetch() on file:// URLs.
+func (s *Site) writeAssets(index []searchEntry) error {
+	for _, name := range []string{"style.css", "search.js"} {
+		b, err := assets.ReadFile("assets/" + name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(s.Dir, name), b, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	b, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, "search-index.json"), b, 0o644); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	js := append([]byte("window.SEARCH_INDEX = "), b...)
+	js = append(js, ";\n"...)
+	if err := os.WriteFile(filepath.Join(s.Dir, "search-index.js"), js, 0o644); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	return nil
+}
+
+func (s *Site) write(file string, view pageView) error {
+	if view.Root == "" {
+		view.Root = relRoot(file)
+	}
+	f, err := os.Create(filepath.Join(s.Dir, filepath.FromSlash(file)))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", file, err)
+	}
+	defer f.Close()
+	if err := layout.Execute(f, view); err != nil {
+		return fmt.Errorf("failed to render %s: %w", file, err)
+	}
+	return nil
+}
+
+// uniqueFile returns an output path below dir for title that does not
+// collide with previously assigned paths.
+func uniqueFile(dir, title string, used map[string]bool) string {
+	base := export.SanitizeFileName(title)
+	name := base
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = base + " (" + strconv.Itoa(i) + ")"
+	}
+	used[strings.ToLower(name)] = true
+	return path.Join(dir, name+".html")
+}
+
+// searchText returns the body text of a page for the search index.
+func searchText(p *scrapbox.Page) string {
+	var b strings.Builder
+	for i, l := range p.Lines {
+		if i == 0 {
+			continue
+		}
+		b.WriteString(strings.TrimSpace(l.Text))
+		b.WriteString("\n")
+		if b.Len() >= snippetLength {
+			break
+		}
+	}
+	text := []rune(b.String())
+	if len(text) > snippetLength {
+		text = text[:snippetLength]
+	}
+	return string(text)
+}
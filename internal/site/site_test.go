@@ -0,0 +1,117 @@
+package site
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/pagestore"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+type fakeSource struct {
+	pages []*scrapbox.Page
+}
+
+func (f *fakeSource) ListAllPages(ctx context.Context) ([]scrapbox.Page, error) {
+	var list []scrapbox.Page
+	for _, p := range f.pages {
+		list = append(list, scrapbox.Page{ID: p.ID, Title: p.Title, Updated: p.Updated})
+	}
+	return list, nil
+}
+
+func (f *fakeSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	for _, p := range f.pages {
+		if p.Title == title {
+			return p, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func lines(texts ...string) []scrapbox.Line {
+	ls := make([]scrapbox.Line, len(texts))
+	for i, t := range texts {
+		ls[i] = scrapbox.Line{Text: t}
+	}
+	return ls
+}
+
+func TestSite_Generate(t *testing.T) {
+	store, err := pagestore.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	src := &fakeSource{pages: []*scrapbox.Page{
+		{ID: "1", Title: "Home Page", Updated: 2, Lines: lines("Home Page", "see [Guide] and [Nowhere] #docs", "<script>x</script> [bad javascript:alert(1)]")},
+		{ID: "2", Title: "Guide", Updated: 1, Lines: lines("Guide", "back to [home page] #docs")},
+	}}
+	if _, err := store.Sync(context.Background(), src); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	out := t.TempDir()
+	s := &Site{Store: store, Dir: out, Project: "demo"}
+	if err := s.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error = %v", name, err)
+		}
+		return string(b)
+	}
+
+	home := read("pages/Home Page.html")
+	for _, want := range []string{
+		`<a class="link" href="../pages/Guide.html">Guide</a>`,
+		`<span class="link missing">Nowhere</span>`,
+		`<a class="tag" href="../tags/docs.html">#docs</a>`,
+		`&lt;script&gt;x&lt;/script&gt;`,
+		`<a href="../pages/Guide.html">Guide</a>`, // backlink
+		`href="../style.css"`,
+	} {
+		if !strings.Contains(home, want) {
+			t.Errorf("Home Page.html does not contain %q", want)
+		}
+	}
+	if strings.Contains(home, `href="javascript:`) {
+		t.Errorf("Home Page.html contains a javascript: link")
+	}
+
+	tag := read("tags/docs.html")
+	for _, want := range []string{`href="../pages/Guide.html"`, `href="../pages/Home%20Page.html"`} {
+		if !strings.Contains(tag, want) {
+			t.Errorf("tags/docs.html does not contain %q", want)
+		}
+	}
+	if !strings.Contains(read("tags.html"), `href="tags/docs.html"`) {
+		t.Errorf("tags.html does not link to the docs tag")
+	}
+	if !strings.Contains(read("index.html"), `<script src="search-index.js"></script>`) {
+		t.Errorf("index.html does not load the search index")
+	}
+
+	var index []searchEntry
+	if err := json.Unmarshal([]byte(read("search-index.json")), &index); err != nil {
+		t.Fatalf("search-index.json: %v", err)
+	}
+	var titles []string
+	for _, e := range index {
+		titles = append(titles, e.Title+"|"+e.URL)
+	}
+	if diff := cmp.Diff([]string{"Guide|pages/Guide.html", "Home Page|pages/Home Page.html"}, titles); diff != "" {
+		t.Errorf("search index mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.HasPrefix(read("search-index.js"), "window.SEARCH_INDEX = [") {
+		t.Errorf("search-index.js does not define window.SEARCH_INDEX")
+	}
+}
@@ -0,0 +1,257 @@
+// Package tenant implements the multi-tenant mode of the HTTP servers, in
+// which every user acts as themselves instead of sharing one service cookie.
+//
+// The Scrapbox credentials of a request are resolved from the authenticated
+// identity through a mapping file, or from headers set by a trusted proxy.
+// Every MCP session then gets its own Tenant with isolated scrapbox.Clients
+// and completion cache, so nothing fetched for one session is served to
+// another.
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/auth"
+	"github.com/takak2166/scrapbox-mcp/internal/completion"
+	"github.com/takak2166/scrapbox-mcp/internal/config"
+	"github.com/takak2166/scrapbox-mcp/internal/projects"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// DefaultIdleTimeout is how long an unused session keeps its Tenant when
+// IdleTimeout is not set.
+const DefaultIdleTimeout = time.Hour
+
+var (
+	// ErrNoCredentials is returned for requests whose caller has no
+	// Scrapbox credentials.
+	ErrNoCredentials = errors.New("no Scrapbox credentials for this caller")
+	// ErrSessionMismatch is returned when a session is used with other
+	// credentials than the ones it was created with.
+	ErrSessionMismatch = errors.New("session belongs to another tenant")
+)
+
+// Credentials are the Scrapbox credentials of a caller.
+type Credentials struct {
+	SID     string `json:"sid"`
+	Project string `json:"project,omitempty"`
+}
+
+// LoadMap reads a JSON file mapping identity subjects to credentials, e.g.
+// {"alice": {"sid": "s%3A...", "project": "alice-notes"}}.
+func LoadMap(path string) (map[string]Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant map: %w", err)
+	}
+	var m map[string]Credentials
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse tenant map: %w", err)
+	}
+	for subject, c := range m {
+		if c.SID == "" {
+			return nil, fmt.Errorf("tenant map entry %q has no sid", subject)
+		}
+	}
+	return m, nil
+}
+
+// Options configures a Manager.
+type Options struct {
+	// Identities maps the subjects of authenticated callers to their
+	// credentials.
+	Identities map[string]Credentials
+	// SIDHeader and ProjectHeader name the headers carrying the credentials
+	// of callers without a mapping. Empty names disable them.
+	SIDHeader     string
+	ProjectHeader string
+	// DefaultProject is the project of callers whose credentials name none.
+	DefaultProject string
+	// Projects are the additional projects every Tenant serves next to the
+	// project of its credentials, accessed with the same SID.
+	Projects []string
+	// IdleTimeout drops the Tenant of a session unused for that long.
+	IdleTimeout time.Duration
+	// CompletionTTL is how long the Completer of every Tenant caches page
+	// titles; zero selects the default.
+	CompletionTTL time.Duration
+	// ClientOptions are applied to the clients of every Tenant.
+	ClientOptions []scrapbox.Option
+}
+
+// Tenant is the Scrapbox state of one MCP session.
+type Tenant struct {
+	Credentials Credentials
+	// Projects has the project of Credentials as its primary project.
+	Projects  *projects.Set
+	Completer *completion.Completer
+
+	lastUsed time.Time
+}
+
+// Manager resolves credentials and keeps the Tenant of every session.
+type Manager struct {
+	opts Options
+	now  func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*Tenant
+}
+
+// New creates a Manager.
+func New(opts Options) *Manager {
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	opts.ClientOptions = append([]scrapbox.Option{scrapbox.WithCookieSource("the Scrapbox SID of your credentials")}, opts.ClientOptions...)
+	return &Manager{opts: opts, now: time.Now, sessions: map[string]*Tenant{}}
+}
+
+// FromConfig creates the Manager configured by cfg, applying opts to the
+// clients of every Tenant. It returns nil if cfg does not enable
+// multi-tenant mode.
+func FromConfig(cfg *config.Config, opts ...scrapbox.Option) (*Manager, error) {
+	if !cfg.MultiTenant() {
+		return nil, nil
+	}
+	o := Options{
+		SIDHeader:      cfg.TenantSIDHeader,
+		ProjectHeader:  cfg.TenantProjectHeader,
+		DefaultProject: cfg.ProjectName,
+		CompletionTTL:  cfg.CompletionTTL,
+		ClientOptions:  opts,
+	}
+	for _, p := range cfg.Projects {
+		o.Projects = append(o.Projects, p.Name)
+	}
+	if cfg.TenantMapFile != "" {
+		m, err := LoadMap(cfg.TenantMapFile)
+		if err != nil {
+			return nil, err
+		}
+		o.Identities = m
+	}
+	return New(o), nil
+}
+
+// Resolve returns the credentials of the caller of r: the mapping of its
+// authenticated identity if there is one, or else the forwarded headers.
+func (m *Manager) Resolve(r *http.Request) (Credentials, error) {
+	var c Credentials
+	if id, ok := auth.FromContext(r.Context()); ok {
+		c = m.opts.Identities[id.Subject]
+	}
+	if c.SID == "" && m.opts.SIDHeader != "" {
+		c.SID = r.Header.Get(m.opts.SIDHeader)
+		if m.opts.ProjectHeader != "" {
+			c.Project = r.Header.Get(m.opts.ProjectHeader)
+		}
+	}
+	if c.SID == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	if c.Project == "" {
+		c.Project = m.opts.DefaultProject
+	}
+	return c, nil
+}
+
+// Middleware resolves the credentials of every request and rejects the
+// requests of callers without any. It must run after authentication. The
+// Tenant of a Streamable HTTP session is dropped when a DELETE ends the
+// session.
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := m.Resolve(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		ctx := context.WithValue(r.Context(), credentialsKey{}, c)
+		if id := r.Header.Get("Mcp-Session-Id"); r.Method == http.MethodDelete && id != "" {
+			// Only the owner of the session may drop its Tenant.
+			if _, err := m.Session(ctx, id); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			defer m.CloseSession(id)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type credentialsKey struct{}
+
+// Session returns the Tenant of session id, creating it with the
+// credentials resolved by Middleware for the request of ctx. Using a
+// session with other credentials fails with ErrSessionMismatch.
+func (m *Manager) Session(ctx context.Context, id string) (*Tenant, error) {
+	c, ok := ctx.Value(credentialsKey{}).(Credentials)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for sid, t := range m.sessions {
+		if now.Sub(t.lastUsed) > m.opts.IdleTimeout {
+			delete(m.sessions, sid)
+		}
+	}
+	t, ok := m.sessions[id]
+	if !ok {
+		others := make([]*scrapbox.Client, 0, len(m.opts.Projects))
+		for _, p := range m.opts.Projects {
+			others = append(others, scrapbox.NewClient(p, c.SID, m.opts.ClientOptions...))
+		}
+		set := projects.New(scrapbox.NewClient(c.Project, c.SID, m.opts.ClientOptions...), others...)
+		t = &Tenant{
+			Credentials: c,
+			Projects:    set,
+			Completer:   completion.New(set.Primary(), m.opts.CompletionTTL),
+		}
+		m.sessions[id] = t
+	} else if t.Credentials != c {
+		return nil, ErrSessionMismatch
+	}
+	t.lastUsed = now
+	return t, nil
+}
+
+// SetCompletionTTL changes the CompletionTTL of the Manager, including that
+// of the Tenants of current sessions.
+func (m *Manager) SetCompletionTTL(ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opts.CompletionTTL = ttl
+	for _, t := range m.sessions {
+		t.Completer.SetTTL(ttl)
+	}
+}
+
+// CloseSession drops the Tenant of session id.
+func (m *Manager) CloseSession(id string) {
+	m.mu.Lock()
+	delete(m.sessions, id)
+	m.mu.Unlock()
+}
+
+type tenantKey struct{}
+
+// NewContext returns a copy of ctx carrying t.
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// FromContext returns the Tenant carried by ctx, if any.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(tenantKey{}).(*Tenant)
+	return t, ok
+}
@@ -0,0 +1,345 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takak2166/scrapbox-mcp/internal/auth"
+	"github.com/takak2166/scrapbox-mcp/internal/completion"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+// resolve runs r through the middleware of m and returns the context the
+// next handler got, or the status of the rejection.
+func resolve(m *Manager, r *http.Request) (context.Context, int) {
+	var ctx context.Context
+	rec := httptest.NewRecorder()
+	m.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(rec, r)
+	return ctx, rec.Code
+}
+
+func newRequest(subject string, header map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	if subject != "" {
+		r = r.WithContext(auth.NewContext(r.Context(), &auth.Identity{Subject: subject}))
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func TestManager_Resolve(t *testing.T) {
+	m := New(Options{
+		Identities: map[string]Credentials{
+			"alice": {SID: "sid-alice", Project: "alice-notes"},
+			"bob":   {SID: "sid-bob"},
+		},
+		SIDHeader:      "X-Scrapbox-Sid",
+		ProjectHeader:  "X-Scrapbox-Project",
+		DefaultProject: "team",
+	})
+
+	tests := map[string]struct {
+		subject string
+		header  map[string]string
+		want    Credentials
+		wantErr error
+	}{
+		"ok: mapped identity": {
+			subject: "alice",
+			want:    Credentials{SID: "sid-alice", Project: "alice-notes"},
+		},
+		"ok: mapped identity without project": {
+			subject: "bob",
+			want:    Credentials{SID: "sid-bob", Project: "team"},
+		},
+		"ok: mapping wins over headers": {
+			subject: "alice",
+			header:  map[string]string{"X-Scrapbox-Sid": "sid-forged", "X-Scrapbox-Project": "other"},
+			want:    Credentials{SID: "sid-alice", Project: "alice-notes"},
+		},
+		"ok: forwarded headers": {
+			subject: "carol",
+			header:  map[string]string{"X-Scrapbox-Sid": "sid-carol", "X-Scrapbox-Project": "carol-notes"},
+			want:    Credentials{SID: "sid-carol", Project: "carol-notes"},
+		},
+		"ok: forwarded SID without identity": {
+			header: map[string]string{"X-Scrapbox-Sid": "sid-dave"},
+			want:   Credentials{SID: "sid-dave", Project: "team"},
+		},
+		"err: unmapped identity without headers": {
+			subject: "carol",
+			wantErr: ErrNoCredentials,
+		},
+		"err: project header alone": {
+			header:  map[string]string{"X-Scrapbox-Project": "team"},
+			wantErr: ErrNoCredentials,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := m.Resolve(newRequest(tt.subject, tt.header))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Resolve() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestManager_Middleware(t *testing.T) {
+	m := New(Options{SIDHeader: "X-Scrapbox-Sid", DefaultProject: "team", Projects: []string{"shared"}})
+
+	if _, code := resolve(m, newRequest("", nil)); code != http.StatusForbidden {
+		t.Errorf("status without credentials = %d, want %d", code, http.StatusForbidden)
+	}
+	ctx, code := resolve(m, newRequest("", map[string]string{"X-Scrapbox-Sid": "sid-a"}))
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	tenant, err := m.Session(ctx, "s1")
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"team", "shared"}, tenant.Projects.Names()); diff != "" {
+		t.Errorf("projects mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := m.Session(context.Background(), "s1"); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Session() without credentials error = %v, want %v", err, ErrNoCredentials)
+	}
+}
+
+// backend is a Scrapbox API whose page titles depend on the session cookie.
+type backend struct {
+	titles map[string][]string
+
+	mu       sync.Mutex
+	requests map[string][]string
+}
+
+func (b *backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cookie, _ := r.Cookie("connect.sid")
+	sid := ""
+	if cookie != nil {
+		sid = cookie.Value
+	}
+	b.mu.Lock()
+	b.requests[sid] = append(b.requests[sid], r.URL.Path)
+	b.mu.Unlock()
+
+	titles := b.titles[sid]
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/search/titles"):
+		var list []scrapbox.PageTitle
+		for _, title := range titles {
+			list = append(list, scrapbox.PageTitle{ID: sid + title, Title: title})
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	case r.URL.Path == "/pages/team":
+		list := scrapbox.PageList{Count: len(titles)}
+		for _, title := range titles {
+			list.Pages = append(list.Pages, scrapbox.Page{Title: title})
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	default:
+		title := strings.TrimPrefix(r.URL.Path, "/pages/team/")
+		for _, t := range titles {
+			if t == title {
+				_ = json.NewEncoder(w).Encode(scrapbox.Page{Title: title})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestManager_SessionIsolation(t *testing.T) {
+	b := &backend{
+		titles: map[string][]string{
+			"sid-alice": {"Alice diary", "Shared plan"},
+			"sid-bob":   {"Bob secrets"},
+		},
+		requests: map[string][]string{},
+	}
+	ts := httptest.NewServer(b)
+	defer ts.Close()
+
+	m := New(Options{
+		Identities: map[string]Credentials{
+			"alice": {SID: "sid-alice"},
+			"bob":   {SID: "sid-bob"},
+		},
+		DefaultProject: "team",
+		ClientOptions:  []scrapbox.Option{scrapbox.WithBaseURL(ts.URL)},
+	})
+	aliceCtx, _ := resolve(m, newRequest("alice", nil))
+	bobCtx, _ := resolve(m, newRequest("bob", nil))
+
+	alice, err := m.Session(aliceCtx, "session-alice")
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+	bob, err := m.Session(bobCtx, "session-bob")
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+	alice2, err := m.Session(aliceCtx, "session-alice-2")
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+	if alice.Projects.Primary() == bob.Projects.Primary() || alice.Completer == bob.Completer {
+		t.Error("sessions of different tenants share a client or completer")
+	}
+	if alice.Projects.Primary() == alice2.Projects.Primary() || alice.Completer == alice2.Completer {
+		t.Error("sessions of the same tenant share a client or completer")
+	}
+	if again, _ := m.Session(aliceCtx, "session-alice"); again != alice {
+		t.Error("Session() returned a new Tenant for a known session")
+	}
+
+	ctx := context.Background()
+	complete := func(tn *Tenant) []string {
+		t.Helper()
+		r, err := tn.Completer.Complete(ctx, completion.RefPrompt, "summarize_page", completion.PageTitleArgument, "")
+		if err != nil {
+			t.Fatalf("Complete() error = %v", err)
+		}
+		return r.Values
+	}
+	// Alice fills her title cache first; Bob must not be served from it.
+	if diff := cmp.Diff([]string{"Alice diary", "Shared plan"}, complete(alice)); diff != "" {
+		t.Errorf("alice completions mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Bob secrets"}, complete(bob)); diff != "" {
+		t.Errorf("bob completions mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := bob.Projects.Primary().GetPage(ctx, "Alice diary"); err == nil {
+		t.Error("bob read a page of alice")
+	}
+	if _, err := alice.Projects.Primary().GetPage(ctx, "Alice diary"); err != nil {
+		t.Errorf("alice GetPage() error = %v", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if got := b.requests[""]; len(got) != 0 {
+		t.Errorf("requests without a cookie: %v", got)
+	}
+	// Each tenant loaded its title list with its own cookie.
+	for _, sid := range []string{"sid-alice", "sid-bob"} {
+		n := 0
+		for _, path := range b.requests[sid] {
+			if strings.HasSuffix(path, "/search/titles") {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("title list requests with %s = %d, want 1", sid, n)
+		}
+	}
+}
+
+func TestManager_SessionMismatch(t *testing.T) {
+	m := New(Options{
+		Identities: map[string]Credentials{
+			"alice": {SID: "sid-alice"},
+			"bob":   {SID: "sid-bob"},
+		},
+		DefaultProject: "team",
+	})
+	aliceCtx, _ := resolve(m, newRequest("alice", nil))
+	bobCtx, _ := resolve(m, newRequest("bob", nil))
+
+	if _, err := m.Session(aliceCtx, "s1"); err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+	if _, err := m.Session(bobCtx, "s1"); !errors.Is(err, ErrSessionMismatch) {
+		t.Errorf("Session() with another tenant's session error = %v, want %v", err, ErrSessionMismatch)
+	}
+
+	m.CloseSession("s1")
+	tenant, err := m.Session(bobCtx, "s1")
+	if err != nil {
+		t.Fatalf("Session() after CloseSession() error = %v", err)
+	}
+	if tenant.Credentials.SID != "sid-bob" {
+		t.Errorf("SID = %q, want sid-bob", tenant.Credentials.SID)
+	}
+}
+
+func TestManager_SessionIdleTimeout(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	m := New(Options{SIDHeader: "X-Scrapbox-Sid", DefaultProject: "team", IdleTimeout: time.Minute})
+	m.now = func() time.Time { return now }
+	ctx, _ := resolve(m, newRequest("", map[string]string{"X-Scrapbox-Sid": "sid-a"}))
+
+	first, _ := m.Session(ctx, "s1")
+	now = now.Add(30 * time.Second)
+	if got, _ := m.Session(ctx, "s1"); got != first {
+		t.Error("Tenant was dropped before the idle timeout")
+	}
+	now = now.Add(2 * time.Minute)
+	if got, _ := m.Session(ctx, "s1"); got == first {
+		t.Error("Tenant was kept after the idle timeout")
+	}
+}
+
+func TestLoadMap(t *testing.T) {
+	tests := map[string]struct {
+		data    string
+		want    map[string]Credentials
+		wantErr bool
+	}{
+		"ok": {
+			data: `{"alice": {"sid": "sid-alice", "project": "notes"}, "token:1": {"sid": "sid-ci"}}`,
+			want: map[string]Credentials{
+				"alice":   {SID: "sid-alice", Project: "notes"},
+				"token:1": {SID: "sid-ci"},
+			},
+		},
+		"err: entry without sid": {
+			data:    `{"alice": {"project": "notes"}}`,
+			wantErr: true,
+		},
+		"err: not JSON": {
+			data:    `alice = sid`,
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tenants.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadMap(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("LoadMap() error = nil, wantErr true")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadMap() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("LoadMap() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
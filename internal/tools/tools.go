@@ -0,0 +1,225 @@
+// Package tools keeps the MCP tools the servers expose. Every adapter
+// registers its tools through a Registry, which decides which of them are
+// enabled and tells the adapter when that changes, so that it can update its
+// tool list and send notifications/tools/list_changed. Adapters register
+// every tool handler wrapped by Wrap, so that a tool disabled for the project
+// it is called on cannot run whatever the adapter lists.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Names are the tools implemented by every server, in the order they are
+// listed.
+var Names = []string{
+	"get_page",
+	"list_pages",
+	"search_pages",
+	"create_page_url",
+	"get_page_history",
+	"get_page_at",
+	"diff_page_versions",
+	"blame_page",
+	"recent_changes",
+	"search_all",
+	"list_projects",
+}
+
+// writes are the tools that create or modify pages.
+var writes = map[string]bool{
+	"create_page_url": true,
+}
+
+// ErrDisabled is returned for calls to tools that are not enabled.
+var ErrDisabled = errors.New("tool is disabled")
+
+// Known reports whether name is one of Names.
+func Known(name string) bool {
+	return slices.Contains(Names, name)
+}
+
+// Write reports whether the named tool creates or modifies pages. Write
+// tools are disabled in read-only mode and need the write scope in HTTP mode.
+func Write(name string) bool {
+	return writes[name]
+}
+
+// ProjectArgument returns the project argument of a tool call with the JSON
+// arguments args, or "" if there is none, which selects the primary project.
+func ProjectArgument(args json.RawMessage) string {
+	var a struct {
+		Project string `json:"project"`
+	}
+	_ = json.Unmarshal(args, &a)
+	return a.Project
+}
+
+// Policy selects the enabled tools.
+type Policy struct {
+	// ReadOnly disables every write tool.
+	ReadOnly bool
+	// Allow, unless empty, lists the only tools enabled.
+	Allow []string
+	// Deny lists tools disabled.
+	Deny []string
+}
+
+// Enables reports whether the policy enables the named tool.
+func (p Policy) Enables(name string) bool {
+	switch {
+	case p.ReadOnly && Write(name):
+		return false
+	case len(p.Allow) > 0 && !slices.Contains(p.Allow, name):
+		return false
+	}
+	return !slices.Contains(p.Deny, name)
+}
+
+// equal reports whether p and q enable the same tools.
+func (p Policy) equal(q Policy) bool {
+	for _, name := range Names {
+		if p.Enables(name) != q.Enables(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Registry tracks which tools are enabled, by default and in the projects
+// with a policy of their own.
+type Registry struct {
+	mu       sync.Mutex
+	policy   Policy
+	projects map[string]Policy
+	onChange map[int]func()
+	nextID   int
+}
+
+// NewRegistry creates a Registry enabling the tools of policy, and of
+// projects in the projects they name.
+func NewRegistry(policy Policy, projects map[string]Policy) *Registry {
+	return &Registry{policy: policy, projects: projects, onChange: map[int]func(){}}
+}
+
+// Enabled reports whether the named tool is enabled in any project, and so
+// is listed.
+func (r *Registry) Enabled(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.policy.Enables(name) {
+		return true
+	}
+	for _, p := range r.projects {
+		if p.Enables(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Call is the project a tool call is made on.
+type Call struct {
+	// Project is the project argument of the call, "" if it has none.
+	Project string
+	// Primary names the primary project of the session making the call,
+	// which calls without a project argument are made on.
+	Primary string
+}
+
+// project returns the name of the project the call is made on.
+func (c Call) project() string {
+	if c.Project == "" {
+		return c.Primary
+	}
+	return c.Project
+}
+
+// Check returns an error wrapping ErrDisabled unless the named tool is
+// enabled in the project call is made on.
+func (r *Registry) Check(name string, call Call) error {
+	project := call.project()
+	r.mu.Lock()
+	p, ok := r.projects[project]
+	if !ok {
+		p = r.policy
+	}
+	r.mu.Unlock()
+	switch {
+	case p.Enables(name):
+		return nil
+	case ok:
+		return fmt.Errorf("%w in project %s: %s", ErrDisabled, project, name)
+	}
+	return fmt.Errorf("%w: %s", ErrDisabled, name)
+}
+
+// Wrap returns handler, the handler of the named tool, wrapped to fail with
+// an error wrapping ErrDisabled instead of calling handler when r disables
+// the tool in the project a call is made on, which call returns. Adapters
+// register every tool handler wrapped.
+func Wrap[Req, Res any](r *Registry, name string, call func(context.Context, Req) (Call, error), handler func(context.Context, Req) (Res, error)) func(context.Context, Req) (Res, error) {
+	return func(ctx context.Context, req Req) (Res, error) {
+		c, err := call(ctx, req)
+		if err == nil {
+			err = r.Check(name, c)
+		}
+		if err != nil {
+			var zero Res
+			return zero, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Filter returns the enabled tools of names, in order.
+func (r *Registry) Filter(names []string) []string {
+	var enabled []string
+	for _, name := range names {
+		if r.Enabled(name) {
+			enabled = append(enabled, name)
+		}
+	}
+	return enabled
+}
+
+// SetPolicy replaces the policies of the Registry and calls the functions
+// registered with OnChange if that changes the enabled tools.
+func (r *Registry) SetPolicy(policy Policy, projects map[string]Policy) {
+	r.mu.Lock()
+	changed := !r.policy.equal(policy) || !maps.EqualFunc(r.projects, projects, Policy.equal)
+	r.policy, r.projects = policy, projects
+	if !changed {
+		r.mu.Unlock()
+		return
+	}
+	fs := make([]func(), 0, len(r.onChange))
+	for _, f := range r.onChange {
+		fs = append(fs, f)
+	}
+	r.mu.Unlock()
+	for _, f := range fs {
+		f()
+	}
+}
+
+// OnChange registers f to be called whenever the enabled tools change. The
+// returned function unregisters f.
+func (r *Registry) OnChange(f func()) (remove func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.nextID
+	r.nextID++
+	r.onChange[id] = f
+	return func() {
+		r.mu.Lock()
+		delete(r.onChange, id)
+		r.mu.Unlock()
+	}
+}
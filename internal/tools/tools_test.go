@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRegistry_SetPolicy(t *testing.T) {
+	r := NewRegistry(Policy{}, nil)
+	changes := 0
+	remove := r.OnChange(func() { changes++ })
+
+	tests := []struct {
+		name        string
+		policy      Policy
+		projects    map[string]Policy
+		want        []string
+		wantChanges int
+	}{
+		{name: "every tool by default", want: Names, wantChanges: 0},
+		{name: "allow list", policy: Policy{Allow: []string{"search_pages", "get_page"}}, want: []string{"get_page", "search_pages"}, wantChanges: 1},
+		{name: "same list in another order", policy: Policy{Allow: []string{"get_page", "search_pages"}}, want: []string{"get_page", "search_pages"}, wantChanges: 1},
+		{name: "same tools by deny list", policy: Policy{Deny: []string{"list_pages", "create_page_url", "get_page_history", "get_page_at", "diff_page_versions", "blame_page", "recent_changes", "search_all", "list_projects"}}, want: []string{"get_page", "search_pages"}, wantChanges: 1},
+		{
+			name:        "project override listed",
+			policy:      Policy{Allow: []string{"get_page"}},
+			projects:    map[string]Policy{"wiki": {Allow: []string{"list_pages"}}},
+			want:        []string{"get_page", "list_pages"},
+			wantChanges: 2,
+		},
+		{name: "every tool again", want: Names, wantChanges: 3},
+	}
+	for _, tt := range tests {
+		r.SetPolicy(tt.policy, tt.projects)
+		if diff := cmp.Diff(tt.want, r.Filter(Names)); diff != "" {
+			t.Errorf("%s: Filter() mismatch (-want +got):\n%s", tt.name, diff)
+		}
+		if changes != tt.wantChanges {
+			t.Errorf("%s: changes = %d, want %d", tt.name, changes, tt.wantChanges)
+		}
+	}
+
+	remove()
+	r.SetPolicy(Policy{ReadOnly: true}, nil)
+	if changes != 3 {
+		t.Errorf("changes after remove = %d, want 3", changes)
+	}
+}
+
+func TestRegistry_Check(t *testing.T) {
+	r := NewRegistry(Policy{ReadOnly: true, Deny: []string{"search_all"}}, map[string]Policy{
+		"sandbox": {},
+		"hr":      {ReadOnly: true, Allow: []string{"get_page"}},
+	})
+
+	tests := map[string]struct {
+		tool    string
+		call    Call
+		wantErr bool
+	}{
+		"ok: read tool":                             {tool: "get_page", call: Call{Primary: "main"}},
+		"ok: write tool in writable project":        {tool: "create_page_url", call: Call{Project: "sandbox", Primary: "main"}},
+		"ok: write tool in writable primary":        {tool: "create_page_url", call: Call{Primary: "sandbox"}},
+		"ok: denied tool in project without denial": {tool: "search_all", call: Call{Project: "sandbox", Primary: "main"}},
+		"ok: project without override":              {tool: "list_pages", call: Call{Project: "notes", Primary: "main"}},
+		"err: write tool in read-only mode":         {tool: "create_page_url", call: Call{Primary: "main"}, wantErr: true},
+		"err: denied tool":                          {tool: "search_all", call: Call{Primary: "main"}, wantErr: true},
+		"err: tool outside project allow list":      {tool: "list_pages", call: Call{Project: "hr", Primary: "main"}, wantErr: true},
+		"err: write tool in read-only project":      {tool: "create_page_url", call: Call{Project: "hr", Primary: "sandbox"}, wantErr: true},
+		"err: tool outside primary allow list":      {tool: "list_pages", call: Call{Primary: "hr"}, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := r.Check(tt.tool, tt.call)
+			if tt.wantErr != errors.Is(err, ErrDisabled) {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	r := NewRegistry(Policy{ReadOnly: true}, map[string]Policy{"sandbox": {}})
+	called := false
+	handler := Wrap(r, "create_page_url", func(_ context.Context, project string) (Call, error) {
+		return Call{Project: project, Primary: "main"}, nil
+	}, func(_ context.Context, project string) (string, error) {
+		called = true
+		return "created in " + project, nil
+	})
+
+	ctx := context.Background()
+	if _, err := handler(ctx, ""); !errors.Is(err, ErrDisabled) || called {
+		t.Errorf("handler() in read-only primary error = %v, called = %v, want ErrDisabled without calling", err, called)
+	}
+	if got, err := handler(ctx, "sandbox"); err != nil || got != "created in sandbox" {
+		t.Errorf("handler() in writable project = %q, %v", got, err)
+	}
+}
+
+func TestProjectArgument(t *testing.T) {
+	tests := map[string]struct {
+		args string
+		want string
+	}{
+		"ok: project":         {args: `{"page_title":"a","project":"wiki"}`, want: "wiki"},
+		"ok: no project":      {args: `{"page_title":"a"}`, want: ""},
+		"ok: no arguments":    {args: ``, want: ""},
+		"ok: invalid project": {args: `{"project":1}`, want: ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ProjectArgument([]byte(tt.args)); got != tt.want {
+				t.Errorf("ProjectArgument() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
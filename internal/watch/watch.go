@@ -0,0 +1,288 @@
+// Package watch polls Scrapbox for changes to page resources and reports them
+// to MCP clients.
+//
+// A Watcher belongs to a single client session. Every subscribed resource is
+// polled by its own goroutine, which compares the page's updated timestamp
+// with the previous poll. A further goroutine watches a fingerprint of the
+// page list of the project so that clients learn about created and deleted
+// pages.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+const (
+	// DefaultInterval is the time between polls when Options.Interval is not set.
+	DefaultInterval = 30 * time.Second
+	// DefaultMaxBackoff caps the poll delay after failures when
+	// Options.MaxBackoff is not set.
+	DefaultMaxBackoff = 5 * time.Minute
+)
+
+// ErrClosed is returned by Subscribe after the Watcher has been closed.
+var ErrClosed = errors.New("watcher is closed")
+
+// errGone is returned by a probe whose resource no longer exists.
+var errGone = errors.New("resource is gone")
+
+// fingerprintPages is the number of most recently created pages whose IDs
+// make up the fingerprint of the page list.
+const fingerprintPages = 100
+
+// Options configures polling.
+type Options struct {
+	// Interval is the time between two successful polls.
+	Interval time.Duration
+	// MaxBackoff is the longest delay between polls. The delay doubles after
+	// every failed poll until it reaches MaxBackoff, and is reset to Interval
+	// by the next successful one.
+	MaxBackoff time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Interval <= 0 {
+		o.Interval = DefaultInterval
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+	if o.MaxBackoff < o.Interval {
+		o.MaxBackoff = o.Interval
+	}
+	return o
+}
+
+// Notifier delivers change notifications to a client session.
+type Notifier interface {
+	// ResourceUpdated sends notifications/resources/updated for uri.
+	ResourceUpdated(ctx context.Context, uri string) error
+	// ResourceListChanged sends notifications/resources/list_changed.
+	ResourceListChanged(ctx context.Context) error
+}
+
+// Watcher polls the subscribed resources of one session.
+type Watcher struct {
+	src      resources.Source
+	notifier Notifier
+	opts     Options
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	subs map[string]*subscription
+}
+
+// subscription is the poller of a subscribed resource.
+type subscription struct {
+	cancel context.CancelFunc
+}
+
+// New creates a Watcher and starts watching the project for created and
+// deleted pages. Close must be called to stop it.
+func New(src resources.Source, notifier Notifier, opts Options) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watcher{
+		src:      src,
+		notifier: notifier,
+		opts:     opts.withDefaults(),
+		ctx:      ctx,
+		cancel:   cancel,
+		subs:     make(map[string]*subscription),
+	}
+	w.start(ctx, w.listFingerprint, w.notifier.ResourceListChanged)
+	return w
+}
+
+// Subscribe starts polling the page named by uri. Subscribing to a URI that
+// is already watched is a no-op. It returns resources.ErrNotFound if uri does
+// not name an existing page of the project. Once the page is deleted, the
+// client is notified a last time and the subscription is dropped.
+func (w *Watcher) Subscribe(ctx context.Context, uri string) error {
+	project, title, _, err := resources.ParseURI(uri)
+	if err != nil {
+		return err
+	}
+	if project != w.src.ProjectName() {
+		return fmt.Errorf("%w: %s", resources.ErrNotFound, uri)
+	}
+	if w.ctx.Err() != nil {
+		return ErrClosed
+	}
+	if _, err := w.pageUpdated(title)(ctx); errors.Is(err, errGone) {
+		return fmt.Errorf("%w: %s", resources.ErrNotFound, uri)
+	} else if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx.Err() != nil {
+		return ErrClosed
+	}
+	if _, ok := w.subs[uri]; ok {
+		return nil
+	}
+	subCtx, cancel := context.WithCancel(w.ctx)
+	sub := &subscription{cancel: cancel}
+	w.subs[uri] = sub
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		err := w.poll(subCtx, w.pageUpdated(title), func(ctx context.Context) error {
+			return w.notifier.ResourceUpdated(ctx, uri)
+		})
+		if errors.Is(err, errGone) {
+			w.drop(uri, sub)
+		}
+	}()
+	return nil
+}
+
+// drop removes sub, the subscription to uri, unless it was replaced.
+func (w *Watcher) drop(uri string, sub *subscription) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sub.cancel()
+	if w.subs[uri] == sub {
+		delete(w.subs, uri)
+	}
+}
+
+// Unsubscribe stops polling uri.
+func (w *Watcher) Unsubscribe(uri string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if sub, ok := w.subs[uri]; ok {
+		sub.cancel()
+		delete(w.subs, uri)
+	}
+}
+
+// Subscriptions returns the number of subscribed resources.
+func (w *Watcher) Subscriptions() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.subs)
+}
+
+// Close stops all polling and waits for the goroutines to exit. It is safe to
+// call Close more than once.
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	w.cancel()
+	w.subs = make(map[string]*subscription)
+	w.mu.Unlock()
+	w.wg.Wait()
+}
+
+// probe returns an opaque value describing the watched state; a change in the
+// value between two polls is reported as a change. It returns errGone once
+// the watched resource no longer exists.
+type probe func(ctx context.Context) (int64, error)
+
+// start runs poll in a new goroutine tracked by the Watcher.
+func (w *Watcher) start(ctx context.Context, p probe, notify func(context.Context) error) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		_ = w.poll(ctx, p, notify)
+	}()
+}
+
+// poll calls p once immediately to record a baseline and then every
+// Interval, backing off exponentially while p fails. notify is called
+// whenever the probed value differs from the last successful poll, and a
+// last time when p returns errGone, which poll returns.
+func (w *Watcher) poll(ctx context.Context, p probe, notify func(context.Context) error) error {
+	var (
+		last  int64
+		known bool
+		delay time.Duration
+	)
+	for {
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+
+		v, err := p(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, errGone) {
+			_ = notify(ctx)
+			return err
+		}
+		if err != nil {
+			delay = w.backoff(delay)
+			continue
+		}
+		delay = w.opts.Interval
+
+		if known && v != last {
+			// A failed notification means the session is going away;
+			// Close will stop the poller.
+			_ = notify(ctx)
+		}
+		last, known = v, true
+	}
+}
+
+// backoff returns the delay to wait after a failed poll.
+func (w *Watcher) backoff(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return w.opts.Interval
+	}
+	delay *= 2
+	if delay > w.opts.MaxBackoff {
+		delay = w.opts.MaxBackoff
+	}
+	return delay
+}
+
+// pageUpdated probes the updated timestamp of a page.
+func (w *Watcher) pageUpdated(title string) probe {
+	return func(ctx context.Context) (int64, error) {
+		page, err := w.src.GetPage(ctx, title)
+		if scrapbox.IsNotFound(err) || errors.Is(err, scrapbox.ErrPageDenied) {
+			return 0, errGone
+		}
+		if err != nil {
+			return 0, err
+		}
+		return page.Updated, nil
+	}
+}
+
+// listFingerprint probes the page list of the project: the page count and
+// the IDs of the most recently created pages, so that a page created and
+// another deleted between two polls still change it. Editing a page does
+// not.
+func (w *Watcher) listFingerprint(ctx context.Context) (int64, error) {
+	list, err := w.src.ListPagesWithOptions(ctx, scrapbox.ListPagesOptions{Limit: fingerprintPages, Sort: "created"})
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d", list.Count)
+	for _, p := range list.Pages {
+		fmt.Fprintf(h, "\x00%s", p.ID)
+	}
+	return int64(h.Sum64()), nil
+}
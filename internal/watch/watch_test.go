@@ -0,0 +1,294 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	scrapboxerrors "github.com/takak2166/scrapbox-mcp/internal/errors"
+	"github.com/takak2166/scrapbox-mcp/internal/resources"
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox"
+)
+
+type fakeSource struct {
+	mu      sync.Mutex
+	updated map[string]int64
+	ids     []string
+	fail    bool
+	polls   int
+}
+
+func (f *fakeSource) ProjectName() string { return "proj" }
+
+func (f *fakeSource) GetPage(ctx context.Context, title string) (*scrapbox.Page, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls++
+	if f.fail {
+		return nil, errors.New("unavailable")
+	}
+	updated, ok := f.updated[title]
+	if !ok {
+		return nil, &scrapboxerrors.ScrapboxError{Code: scrapboxerrors.ErrNotFound, Message: "page not found"}
+	}
+	return &scrapbox.Page{Title: title, Updated: updated}, nil
+}
+
+func (f *fakeSource) ListPagesWithOptions(ctx context.Context, opts scrapbox.ListPagesOptions) (*scrapbox.PageList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return nil, errors.New("unavailable")
+	}
+	list := &scrapbox.PageList{Count: len(f.ids)}
+	for _, id := range f.ids {
+		list.Pages = append(list.Pages, scrapbox.Page{ID: id})
+	}
+	return list, nil
+}
+
+func (f *fakeSource) set(fn func(f *fakeSource)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+type fakeNotifier struct {
+	updated     chan string
+	listChanged chan struct{}
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{updated: make(chan string, 16), listChanged: make(chan struct{}, 16)}
+}
+
+func (n *fakeNotifier) ResourceUpdated(ctx context.Context, uri string) error {
+	n.updated <- uri
+	return nil
+}
+
+func (n *fakeNotifier) ResourceListChanged(ctx context.Context) error {
+	n.listChanged <- struct{}{}
+	return nil
+}
+
+const testInterval = 5 * time.Millisecond
+
+func TestOptions_withDefaults(t *testing.T) {
+	tests := map[string]struct {
+		opts Options
+		want Options
+	}{
+		"zero": {
+			opts: Options{},
+			want: Options{Interval: DefaultInterval, MaxBackoff: DefaultMaxBackoff},
+		},
+		"custom": {
+			opts: Options{Interval: time.Second, MaxBackoff: time.Minute},
+			want: Options{Interval: time.Second, MaxBackoff: time.Minute},
+		},
+		"back-off shorter than interval": {
+			opts: Options{Interval: time.Minute, MaxBackoff: time.Second},
+			want: Options{Interval: time.Minute, MaxBackoff: time.Minute},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.opts.withDefaults(); got != tt.want {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatcher_backoff(t *testing.T) {
+	w := &Watcher{opts: Options{Interval: time.Second, MaxBackoff: 5 * time.Second}}
+	tests := map[string]struct {
+		delay time.Duration
+		want  time.Duration
+	}{
+		"first poll":   {delay: 0, want: time.Second},
+		"doubles":      {delay: time.Second, want: 2 * time.Second},
+		"capped":       {delay: 4 * time.Second, want: 5 * time.Second},
+		"stays at cap": {delay: 5 * time.Second, want: 5 * time.Second},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := w.backoff(tt.delay); got != tt.want {
+				t.Errorf("backoff(%v) = %v, want %v", tt.delay, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatcher_Subscribe(t *testing.T) {
+	src := &fakeSource{updated: map[string]int64{"Page": 100}, ids: []string{"p1"}}
+	n := newFakeNotifier()
+	w := New(src, n, Options{Interval: testInterval})
+	defer w.Close()
+
+	uri := resources.URI("proj", "Page")
+	if err := w.Subscribe(context.Background(), uri); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := w.Subscribe(context.Background(), uri); err != nil {
+		t.Fatalf("Subscribe() again error = %v", err)
+	}
+	if got := w.Subscriptions(); got != 1 {
+		t.Errorf("Subscriptions() = %d, want 1", got)
+	}
+
+	// Let the baseline be recorded before changing the page.
+	time.Sleep(4 * testInterval)
+	select {
+	case got := <-n.updated:
+		t.Fatalf("unexpected update for %s before the page changed", got)
+	default:
+	}
+
+	src.set(func(f *fakeSource) { f.updated["Page"] = 200 })
+	select {
+	case got := <-n.updated:
+		if got != uri {
+			t.Errorf("ResourceUpdated() uri = %q, want %q", got, uri)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update notification")
+	}
+
+	w.Unsubscribe(uri)
+	if got := w.Subscriptions(); got != 0 {
+		t.Errorf("Subscriptions() after Unsubscribe = %d, want 0", got)
+	}
+}
+
+func TestWatcher_SubscribeErrors(t *testing.T) {
+	src := &fakeSource{updated: map[string]int64{}}
+	w := New(src, newFakeNotifier(), Options{Interval: testInterval})
+
+	if err := w.Subscribe(context.Background(), "https://example.com/proj/Page"); err == nil {
+		t.Error("Subscribe() with a foreign scheme error = nil")
+	}
+	if err := w.Subscribe(context.Background(), resources.URI("other", "Page")); !errors.Is(err, resources.ErrNotFound) {
+		t.Errorf("Subscribe() for another project error = %v, want ErrNotFound", err)
+	}
+	if err := w.Subscribe(context.Background(), resources.URI("proj", "Missing")); !errors.Is(err, resources.ErrNotFound) {
+		t.Errorf("Subscribe() for a missing page error = %v, want ErrNotFound", err)
+	}
+	if got := w.Subscriptions(); got != 0 {
+		t.Errorf("Subscriptions() = %d, want 0", got)
+	}
+
+	w.Close()
+	w.Close()
+	if err := w.Subscribe(context.Background(), resources.URI("proj", "Page")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe() after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestWatcher_PageDeleted(t *testing.T) {
+	src := &fakeSource{updated: map[string]int64{"Page": 100}}
+	n := newFakeNotifier()
+	w := New(src, n, Options{Interval: testInterval})
+	defer w.Close()
+
+	uri := resources.URI("proj", "Page")
+	if err := w.Subscribe(context.Background(), uri); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	time.Sleep(4 * testInterval)
+	src.set(func(f *fakeSource) { delete(f.updated, "Page") })
+	select {
+	case got := <-n.updated:
+		if got != uri {
+			t.Errorf("ResourceUpdated() uri = %q, want %q", got, uri)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update notification for the deleted page")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for w.Subscriptions() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription to the deleted page not dropped")
+		}
+		time.Sleep(testInterval)
+	}
+	select {
+	case got := <-n.updated:
+		t.Errorf("unexpected update for %s after the subscription was dropped", got)
+	case <-time.After(4 * testInterval):
+	}
+}
+
+func TestWatcher_ListChanged(t *testing.T) {
+	tests := map[string]func(f *fakeSource){
+		"ok: page created":             func(f *fakeSource) { f.ids = append(f.ids, "p4") },
+		"ok: page deleted":             func(f *fakeSource) { f.ids = f.ids[1:] },
+		"ok: page created and deleted": func(f *fakeSource) { f.ids = append(f.ids[1:], "p4") },
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			src := &fakeSource{ids: []string{"p1", "p2", "p3"}}
+			n := newFakeNotifier()
+			w := New(src, n, Options{Interval: testInterval})
+			defer w.Close()
+
+			time.Sleep(4 * testInterval)
+			select {
+			case <-n.listChanged:
+				t.Fatal("unexpected list_changed notification before the list changed")
+			default:
+			}
+			src.set(change)
+			select {
+			case <-n.listChanged:
+			case <-time.After(time.Second):
+				t.Fatal("no list_changed notification")
+			}
+		})
+	}
+}
+
+func TestWatcher_Close(t *testing.T) {
+	src := &fakeSource{updated: map[string]int64{"Page": 1}}
+	w := New(src, newFakeNotifier(), Options{Interval: testInterval})
+	if err := w.Subscribe(context.Background(), resources.URI("proj", "Page")); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	time.Sleep(2 * testInterval)
+	w.Close()
+
+	src.mu.Lock()
+	polls := src.polls
+	src.mu.Unlock()
+	time.Sleep(4 * testInterval)
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if src.polls != polls {
+		t.Errorf("page polled %d times after Close", src.polls-polls)
+	}
+}
+
+func TestWatcher_BacksOffOnFailure(t *testing.T) {
+	src := &fakeSource{updated: map[string]int64{"Page": 1}}
+	w := New(src, newFakeNotifier(), Options{Interval: testInterval, MaxBackoff: 1000 * testInterval})
+	defer w.Close()
+	if err := w.Subscribe(context.Background(), resources.URI("proj", "Page")); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	src.set(func(f *fakeSource) { f.fail, f.polls = true, 0 })
+
+	// Delays of 1, 2, 4, 8 and 16 intervals add up to 31 intervals, while a
+	// poller without back-off would have polled about 40 times.
+	time.Sleep(40 * testInterval)
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if src.polls > 10 {
+		t.Errorf("page polled %d times while failing, want back-off", src.polls)
+	}
+}
@@ -1,43 +1,118 @@
 package scrapbox
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/takak2166/scrapbox-mcp/internal/errors"
 )
 
+// ErrCookieExpired is wrapped by the errors of requests made after Scrapbox
+// rejected the session cookie of a client.
+var ErrCookieExpired = stderrors.New("session cookie expired")
+
+// IsNotFound reports whether err is the error of a request for a page or
+// other resource that does not exist.
+func IsNotFound(err error) bool {
+	var serr *errors.ScrapboxError
+	return stderrors.As(err, &serr) && serr.Code == errors.ErrNotFound
+}
+
 // Client is a Scrapbox API client.
 type Client struct {
 	httpClient  *http.Client
 	baseURL     string
 	projectName string
-	cookie      string
+
+	// mu guards the cookie, which reloadCookie replaces with the cookie read
+	// by reload and SetCookie with another one, where it is configured and
+	// when reload was last called.
+	mu           sync.Mutex
+	cookie       string
+	cookieSource string
+	reload       func(context.Context) (string, error)
+	reloaded     time.Time
+
+	// expired is set once Scrapbox rejects the cookie; onExpired is called
+	// at that moment.
+	expired   atomic.Bool
+	onExpired func(error)
+
+	// filter hides pages, as set by WithPageFilter. linksMu guards links,
+	// the links of every page by normalized title for a filter needing them,
+	// and when and under which version of the filter they were loaded.
+	filter       PageFilter
+	linksMu      sync.Mutex
+	links        map[string][]string
+	linksLoaded  time.Time
+	linksVersion uint64
+
+	logger *slog.Logger
 }
 
 // Page represents a Scrapbox page.
 type Page struct {
-	Title string `json:"title"`
-	Lines []Line `json:"lines"`
+	ID             string   `json:"id,omitempty"`
+	Title          string   `json:"title"`
+	Created        int64    `json:"created,omitempty"`
+	Updated        int64    `json:"updated,omitempty"`
+	Pin            int64    `json:"pin,omitempty"`
+	Views          int      `json:"views,omitempty"`
+	Linked         int      `json:"linked,omitempty"`
+	User           *User    `json:"user,omitempty"`
+	LastUpdateUser *User    `json:"lastUpdateUser,omitempty"`
+	Lines          []Line   `json:"lines"`
+	Links          []string `json:"links,omitempty"`
+}
+
+// User represents a Scrapbox user as embedded in page responses.
+type User struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
 }
 
 // Line represents a line of text in a Scrapbox page.
 type Line struct {
+	ID      string `json:"id,omitempty"`
 	Text    string `json:"text"`
+	UserID  string `json:"userId,omitempty"`
 	Created int64  `json:"created"`
 	Updated int64  `json:"updated"`
 }
 
 // PageList represents a list of Scrapbox pages.
 type PageList struct {
+	Count int    `json:"count,omitempty"`
+	Skip  int    `json:"skip,omitempty"`
+	Limit int    `json:"limit,omitempty"`
 	Pages []Page `json:"pages"`
 }
 
+// ListPagesOptions controls paging and ordering of ListPagesWithOptions.
+type ListPagesOptions struct {
+	// Skip is the number of pages to skip from the beginning of the list.
+	Skip int
+	// Limit is the maximum number of pages to return (1-1000).
+	Limit int
+	// Sort is the sort key, e.g. "updated", "created" or "title".
+	Sort string
+}
+
+// maxListLimit is the largest page size accepted by the list endpoint.
+const maxListLimit = 1000
+
 // SearchPage represents a page in search results.
 type SearchPage struct {
 	Title string   `json:"title"`
@@ -49,103 +124,554 @@ type SearchPageList struct {
 	Pages []SearchPage `json:"pages"`
 }
 
-// NewClient creates a new Scrapbox API client.
-func NewClient(projectName, cookie string) *Client {
-	return &Client{
+// PageTitle is an entry of the title list of a project.
+type PageTitle struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Links   []string `json:"links,omitempty"`
+	Updated int64    `json:"updated,omitempty"`
+}
+
+// PageSnapshot is a saved version of a page.
+type PageSnapshot struct {
+	Title   string `json:"title"`
+	Lines   []Line `json:"lines"`
+	Created int64  `json:"created"`
+}
+
+// PageSnapshotList represents the snapshots of a page.
+type PageSnapshotList struct {
+	PageID    string         `json:"pageId"`
+	Snapshots []PageSnapshot `json:"snapshots"`
+}
+
+// Commit represents a single edit of a page.
+type Commit struct {
+	ID       string   `json:"id"`
+	ParentID string   `json:"parentId,omitempty"`
+	PageID   string   `json:"pageId"`
+	UserID   string   `json:"userId"`
+	Kind     string   `json:"kind,omitempty"`
+	Created  int64    `json:"created"`
+	Changes  []Change `json:"changes"`
+}
+
+// Change is one operation of a commit. Exactly one of Insert, Update and
+// Delete is set to the ID of the affected line; Lines holds the new line
+// content for inserts and updates.
+type Change struct {
+	Insert string          `json:"_insert,omitempty"`
+	Update string          `json:"_update,omitempty"`
+	Delete string          `json:"_delete,omitempty"`
+	Lines  json.RawMessage `json:"lines,omitempty"`
+	Title  string          `json:"title,omitempty"`
+}
+
+// CommitList represents the commits of a page.
+type CommitList struct {
+	Commits []Commit `json:"commits"`
+}
+
+// Project represents a Scrapbox project. Users lists the project members
+// and is only returned to members.
+type Project struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	DisplayName   string `json:"displayName,omitempty"`
+	PublicVisible bool   `json:"publicVisible"`
+	// IsMember reports whether the user of the session cookie is a member.
+	IsMember bool   `json:"isMember,omitempty"`
+	Users    []User `json:"users,omitempty"`
+}
+
+// Me is the user a session cookie belongs to, as returned by /api/users/me.
+type Me struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	// IsGuest is true if the cookie does not belong to a logged-in user.
+	IsGuest bool `json:"isGuest"`
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sets the API base URL, "https://scrapbox.io/api" by default.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithCookieSource names where the session cookie is configured, such as an
+// environment variable, in the error returned once the cookie has expired.
+func WithCookieSource(source string) Option {
+	return func(c *Client) {
+		c.cookieSource = source
+	}
+}
+
+// WithCookieReload sets a function re-reading the session cookie from where
+// it is configured, such as a file or a password manager. It is called when
+// Scrapbox rejects the cookie, so that a rotated cookie is picked up without
+// creating a new client.
+func WithCookieReload(reload func(context.Context) (string, error)) Option {
+	return func(c *Client) {
+		c.reload = reload
+	}
+}
+
+// minReloadInterval is how often an expired client re-reads its cookie at
+// most, so that a command behind WithCookieReload is not run on every
+// request.
+const minReloadInterval = 10 * time.Second
+
+// NewClient creates a new Scrapbox API client. An empty cookie accesses the
+// project anonymously, which only works for public projects.
+func NewClient(projectName, cookie string, opts ...Option) *Client {
+	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		baseURL:     "https://scrapbox.io/api",
-		projectName: projectName,
-		cookie:      cookie,
+		baseURL:      "https://scrapbox.io/api",
+		projectName:  projectName,
+		cookie:       cookie,
+		cookieSource: "the connect.sid cookie",
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-// GetPage retrieves a page by title.
-func (c *Client) GetPage(ctx context.Context, title string) (*Page, error) {
-	endpoint := fmt.Sprintf("%s/pages/%s/%s", c.baseURL, c.projectName, url.PathEscape(title))
-	log.Printf("GET request to %s", endpoint)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+// ProjectName returns the name of the project this client talks to.
+func (c *Client) ProjectName() string {
+	return c.projectName
+}
+
+// Anonymous reports whether the client sends no session cookie.
+func (c *Client) Anonymous() bool {
+	return c.sessionCookie() == ""
+}
+
+// CookieSource names where the session cookie is configured, as set by
+// WithCookieSource.
+func (c *Client) CookieSource() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cookieSource
+}
+
+// SetCookie replaces the session cookie, where it is configured and the
+// function re-reading it, as set by NewClient, WithCookieSource and
+// WithCookieReload, for example when the configuration is reloaded. It
+// clears Expired, so that the next request tries the new cookie.
+func (c *Client) SetCookie(cookie, source string, reload func(context.Context) (string, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cookie, c.cookieSource, c.reload = cookie, source, reload
+	c.reloaded = time.Time{}
+	c.expired.Store(false)
+}
+
+// Expired reports whether Scrapbox has rejected the session cookie. An
+// expired client fails every request without sending it, until the function
+// set by WithCookieReload returns a new cookie.
+func (c *Client) Expired() bool {
+	return c.expired.Load()
+}
+
+// SetExpiredHandler sets a function called with the error returned to the
+// caller when Scrapbox first rejects the session cookie. It must be called
+// before the client is used.
+func (c *Client) SetExpiredHandler(f func(error)) {
+	c.onExpired = f
+}
+
+// expiredError returns the error of requests made with an expired cookie.
+func (c *Client) expiredError() error {
+	return &errors.ScrapboxError{
+		Code:    errors.ErrInvalidCredentials,
+		Message: fmt.Sprintf("cookie expired for project %s, refresh %s", c.projectName, c.CookieSource()),
+		Err:     ErrCookieExpired,
+	}
+}
+
+// sessionCookie returns the current session cookie.
+func (c *Client) sessionCookie() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cookie
+}
+
+// reloadCookie re-reads the session cookie after Scrapbox rejected stale and
+// returns it, or "" if there is no newer cookie. If throttle is set, the
+// cookie is not re-read within minReloadInterval of the last time.
+func (c *Client) reloadCookie(ctx context.Context, stale string, throttle bool) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cookie != stale {
+		// Another request has reloaded it already.
+		return c.cookie
+	}
+	if c.reload == nil {
+		return ""
+	}
+	if throttle && time.Since(c.reloaded) < minReloadInterval {
+		return ""
+	}
+	c.reloaded = time.Now()
+	cookie, err := c.reload(ctx)
 	if err != nil {
-		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to create request", Err: err}
+		c.log().WarnContext(ctx, "failed to reload the session cookie", "project", c.projectName, "error", err)
+		return ""
+	}
+	if cookie == "" || cookie == stale {
+		return ""
 	}
-	req.Header.Set("Cookie", fmt.Sprintf("connect.sid=%s", c.cookie))
+	c.cookie = cookie
+	c.expired.Store(false)
+	return cookie
+}
 
-	resp, err := c.httpClient.Do(req)
+// getJSON sends a GET request to endpoint and decodes the JSON response into v.
+func (c *Client) getJSON(ctx context.Context, endpoint string, v any) error {
+	cookie := c.sessionCookie()
+	if cookie != "" && c.expired.Load() {
+		// The cookie may have been rotated where it is configured since.
+		if cookie = c.reloadCookie(ctx, cookie, true); cookie == "" {
+			return c.expiredError()
+		}
+	}
+	resp, err := c.get(ctx, endpoint, cookie)
 	if err != nil {
-		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to send request", Err: err}
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && cookie != "" {
+		if fresh := c.reloadCookie(ctx, cookie, false); fresh != "" {
+			resp.Body.Close()
+			if resp, err = c.get(ctx, endpoint, fresh); err != nil {
+				return err
+			}
+		}
 	}
 	defer resp.Body.Close()
 
-	log.Printf("Response status code: %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusUnauthorized && cookie != "" {
+		err := c.expiredError()
+		if !c.expired.Swap(true) {
+			c.log().WarnContext(ctx, "session cookie rejected", "project", c.projectName)
+			if c.onExpired != nil {
+				c.onExpired(err)
+			}
+		}
+		return err
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, &errors.ScrapboxError{Code: resp.StatusCode, Message: "unexpected status code", Err: nil}
+		return &errors.ScrapboxError{Code: resp.StatusCode, Message: "unexpected status code", Err: nil}
 	}
 
-	var page Page
-	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
-		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to decode response", Err: err}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to decode response", Err: err}
 	}
-
-	return &page, nil
+	return nil
 }
 
-// ListPages retrieves a list of pages.
-func (c *Client) ListPages(ctx context.Context) (*PageList, error) {
-	endpoint := fmt.Sprintf("%s/pages/%s", c.baseURL, c.projectName)
-	log.Printf("GET request to %s", endpoint)
+// get sends a GET request to endpoint with the session cookie, if any.
+func (c *Client) get(ctx context.Context, endpoint, cookie string) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to create request", Err: err}
+		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to create request", Err: redactURL(err)}
 	}
-	req.Header.Set("Cookie", fmt.Sprintf("connect.sid=%s", c.cookie))
+	if cookie != "" {
+		req.Header.Set("Cookie", fmt.Sprintf("connect.sid=%s", cookie))
+	}
+	// Only the path is logged: the query holds search terms.
+	logger := c.log().With("project", c.projectName, "request_id", requestID(ctx))
+	logger.DebugContext(ctx, "scrapbox request", "method", req.Method, "path", req.URL.Path)
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		err = redactURL(err)
+		logger.WarnContext(ctx, "scrapbox request failed", "path", req.URL.Path, "error", err)
 		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to send request", Err: err}
 	}
-	defer resp.Body.Close()
+	level := slog.LevelDebug
+	if resp.StatusCode >= http.StatusInternalServerError {
+		level = slog.LevelWarn
+	}
+	logger.Log(ctx, level, "scrapbox response", "path", req.URL.Path, "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
 
-	log.Printf("Response status code: %d", resp.StatusCode)
-	if resp.StatusCode != http.StatusOK {
-		return nil, &errors.ScrapboxError{Code: resp.StatusCode, Message: "unexpected status code", Err: nil}
+// GetPage retrieves a page by title.
+func (c *Client) GetPage(ctx context.Context, title string) (*Page, error) {
+	if c.filter != nil && c.filter.Denies(title, nil) {
+		return nil, deniedError(title)
+	}
+	endpoint := fmt.Sprintf("%s/pages/%s/%s", c.baseURL, c.projectName, url.PathEscape(title))
+	var page Page
+	if err := c.getJSON(ctx, endpoint, &page); err != nil {
+		return nil, err
 	}
+	if c.filter != nil {
+		if c.filter.Denies(page.Title, page.Links) {
+			return nil, deniedError(title)
+		}
+		if len(page.Links) > 0 {
+			deny, err := c.denier(ctx)
+			if err != nil {
+				return nil, err
+			}
+			page.Links = filterLinks(page.Links, deny)
+		}
+	}
+	return &page, nil
+}
 
+// ListPages retrieves a list of pages.
+func (c *Client) ListPages(ctx context.Context) (*PageList, error) {
+	endpoint := fmt.Sprintf("%s/pages/%s", c.baseURL, c.projectName)
 	var pageList PageList
-	if err := json.NewDecoder(resp.Body).Decode(&pageList); err != nil {
-		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to decode response", Err: err}
+	if err := c.getJSON(ctx, endpoint, &pageList); err != nil {
+		return nil, err
 	}
+	pages, err := c.filterPages(ctx, pageList.Pages)
+	if err != nil {
+		return nil, err
+	}
+	pageList.Pages = pages
+	return &pageList, nil
+}
 
+// ListPagesWithOptions retrieves one page of the page list using the given
+// paging and sort options. Count is the number of pages in the project,
+// including those hidden by the PageFilter of the client.
+func (c *Client) ListPagesWithOptions(ctx context.Context, opts ListPagesOptions) (*PageList, error) {
+	list, err := c.listPages(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if list.Pages, err = c.filterPages(ctx, list.Pages); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// listPages is ListPagesWithOptions without the PageFilter of the client.
+func (c *Client) listPages(ctx context.Context, opts ListPagesOptions) (*PageList, error) {
+	query := url.Values{}
+	if opts.Skip > 0 {
+		query.Set("skip", strconv.Itoa(opts.Skip))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(min(opts.Limit, maxListLimit)))
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	endpoint := fmt.Sprintf("%s/pages/%s", c.baseURL, c.projectName)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var pageList PageList
+	if err := c.getJSON(ctx, endpoint, &pageList); err != nil {
+		return nil, err
+	}
 	return &pageList, nil
 }
 
+// ListAllPages retrieves the metadata of every page in the project by walking
+// the page list until it is exhausted. The returned pages carry no lines.
+//
+// The list is walked in creation order, which editing a page does not
+// change, so that no page is skipped when pages are edited during the walk.
+// A page created meanwhile shifts the following ones down instead, so pages
+// listed twice are dropped.
+func (c *Client) ListAllPages(ctx context.Context) ([]Page, error) {
+	var pages []Page
+	seen := map[string]bool{}
+	for skip := 0; ; {
+		list, err := c.listPages(ctx, ListPagesOptions{Skip: skip, Limit: maxListLimit, Sort: "created"})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range list.Pages {
+			if p.ID != "" && seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+			pages = append(pages, p)
+		}
+		skip += len(list.Pages)
+		if len(list.Pages) == 0 || skip >= list.Count {
+			return c.filterPages(ctx, pages)
+		}
+	}
+}
+
+// ListPagesUpdatedSince retrieves the metadata of the pages updated after
+// since, most recently updated first. It walks the page list sorted by
+// update time and stops at the first older page that is not pinned: pinned
+// pages are listed first whatever the sort. The returned pages carry no
+// lines.
+func (c *Client) ListPagesUpdatedSince(ctx context.Context, since time.Time) ([]Page, error) {
+	var pages []Page
+	done := func() ([]Page, error) {
+		slices.SortStableFunc(pages, func(a, b Page) int { return cmp.Compare(b.Updated, a.Updated) })
+		return c.filterPages(ctx, pages)
+	}
+	for skip := 0; ; {
+		list, err := c.listPages(ctx, ListPagesOptions{Skip: skip, Limit: maxListLimit, Sort: "updated"})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range list.Pages {
+			if p.Updated > since.Unix() {
+				pages = append(pages, p)
+			} else if p.Pin == 0 {
+				return done()
+			}
+		}
+		skip += len(list.Pages)
+		if len(list.Pages) == 0 || skip >= list.Count {
+			return done()
+		}
+	}
+}
+
 // SearchPages searches pages by query.
 func (c *Client) SearchPages(ctx context.Context, query string) (*SearchPageList, error) {
 	endpoint := fmt.Sprintf("%s/pages/%s/search/query?q=%s", c.baseURL, c.projectName, url.QueryEscape(query))
-	log.Printf("GET request to %s", endpoint)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	var pageList SearchPageList
+	if err := c.getJSON(ctx, endpoint, &pageList); err != nil {
+		return nil, err
+	}
+	if err := c.filterSearch(ctx, &pageList); err != nil {
+		return nil, err
+	}
+	return &pageList, nil
+}
+
+// SearchTitles retrieves one chunk of the title list of the project. The
+// list starts after the page whose ID is followingID, or at the beginning
+// when followingID is empty.
+func (c *Client) SearchTitles(ctx context.Context, followingID string) ([]PageTitle, error) {
+	titles, err := c.searchTitles(ctx, followingID)
 	if err != nil {
-		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to create request", Err: err}
+		return nil, err
 	}
-	req.Header.Set("Cookie", fmt.Sprintf("connect.sid=%s", c.cookie))
+	return c.filterTitles(ctx, titles)
+}
 
-	resp, err := c.httpClient.Do(req)
+// searchTitles is SearchTitles without the PageFilter of the client.
+func (c *Client) searchTitles(ctx context.Context, followingID string) ([]PageTitle, error) {
+	endpoint := fmt.Sprintf("%s/pages/%s/search/titles", c.baseURL, c.projectName)
+	if followingID != "" {
+		endpoint += "?followingId=" + url.QueryEscape(followingID)
+	}
+	var titles []PageTitle
+	if err := c.getJSON(ctx, endpoint, &titles); err != nil {
+		return nil, err
+	}
+	return titles, nil
+}
+
+// ListAllTitles retrieves the title list of the whole project by following
+// SearchTitles until it is exhausted.
+func (c *Client) ListAllTitles(ctx context.Context) ([]PageTitle, error) {
+	titles, err := c.listAllTitles(ctx)
 	if err != nil {
-		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to send request", Err: err}
+		return nil, err
 	}
-	defer resp.Body.Close()
+	if c.filter != nil && c.filter.NeedsLinks() {
+		// Save the filter from listing the titles again.
+		c.linksMu.Lock()
+		c.setLinks(titles)
+		c.linksMu.Unlock()
+	}
+	return c.filterTitles(ctx, titles)
+}
 
-	log.Printf("Response status code: %d", resp.StatusCode)
-	if resp.StatusCode != http.StatusOK {
-		return nil, &errors.ScrapboxError{Code: resp.StatusCode, Message: "unexpected status code", Err: nil}
+// listAllTitles is ListAllTitles without the PageFilter of the client.
+func (c *Client) listAllTitles(ctx context.Context) ([]PageTitle, error) {
+	var titles []PageTitle
+	followingID := ""
+	for {
+		chunk, err := c.searchTitles(ctx, followingID)
+		if err != nil {
+			return nil, err
+		}
+		titles = append(titles, chunk...)
+		if len(chunk) < maxListLimit || chunk[len(chunk)-1].ID == followingID {
+			return titles, nil
+		}
+		followingID = chunk[len(chunk)-1].ID
 	}
+}
 
-	var pageList SearchPageList
-	if err := json.NewDecoder(resp.Body).Decode(&pageList); err != nil {
-		return nil, &errors.ScrapboxError{Code: errors.ErrServerError, Message: "Failed to decode response", Err: err}
+// GetPageSnapshots retrieves the saved snapshots of a page by page ID.
+func (c *Client) GetPageSnapshots(ctx context.Context, pageID string) (*PageSnapshotList, error) {
+	endpoint := fmt.Sprintf("%s/page-snapshots/%s/%s", c.baseURL, c.projectName, url.PathEscape(pageID))
+	var list PageSnapshotList
+	if err := c.getJSON(ctx, endpoint, &list); err != nil {
+		return nil, err
+	}
+	if c.filter != nil {
+		for _, s := range list.Snapshots {
+			if c.filter.Denies(s.Title, nil) {
+				return nil, deniedError(s.Title)
+			}
+		}
+	}
+	return &list, nil
+}
+
+// GetPageCommits retrieves the edit history of a page by page ID.
+func (c *Client) GetPageCommits(ctx context.Context, pageID string) (*CommitList, error) {
+	endpoint := fmt.Sprintf("%s/commits/%s/%s", c.baseURL, c.projectName, url.PathEscape(pageID))
+	var list CommitList
+	if err := c.getJSON(ctx, endpoint, &list); err != nil {
+		return nil, err
+	}
+	if c.filter != nil {
+		for _, commit := range list.Commits {
+			for _, ch := range commit.Changes {
+				if ch.Title != "" && c.filter.Denies(ch.Title, nil) {
+					return nil, deniedError(ch.Title)
+				}
+			}
+		}
 	}
+	return &list, nil
+}
 
-	return &pageList, nil
+// GetMe retrieves the user the session cookie belongs to.
+func (c *Client) GetMe(ctx context.Context) (*Me, error) {
+	endpoint := fmt.Sprintf("%s/users/me", c.baseURL)
+	var me Me
+	if err := c.getJSON(ctx, endpoint, &me); err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
+
+// GetProject retrieves the project information, including its members.
+func (c *Client) GetProject(ctx context.Context) (*Project, error) {
+	endpoint := fmt.Sprintf("%s/projects/%s", c.baseURL, c.projectName)
+	var project Project
+	if err := c.getJSON(ctx, endpoint, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
 }
 
 // CreatePageURL generates a URL for creating a new page.
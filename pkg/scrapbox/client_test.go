@@ -3,10 +3,16 @@ package scrapbox
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/takak2166/scrapbox-mcp/internal/errors"
@@ -246,3 +252,474 @@ func TestClient_CreatePageURL(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_ListAllPages(t *testing.T) {
+	tests := map[string]struct {
+		// created is a page created after the first request, listed first.
+		created     *Page
+		wantPages   []Page
+		wantQueries []string
+	}{
+		"ok: every page": {
+			wantPages:   []Page{{ID: "a", Title: "A"}, {ID: "b", Title: "B"}, {ID: "c", Title: "C"}},
+			wantQueries: []string{"limit=1000&sort=created", "limit=1000&skip=2&sort=created"},
+		},
+		"ok: page created during the walk": {
+			created:     &Page{ID: "n", Title: "N"},
+			wantPages:   []Page{{ID: "a", Title: "A"}, {ID: "b", Title: "B"}, {ID: "c", Title: "C"}},
+			wantQueries: []string{"limit=1000&sort=created", "limit=1000&skip=2&sort=created"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			all := []Page{{ID: "a", Title: "A"}, {ID: "b", Title: "B"}, {ID: "c", Title: "C"}}
+			var queries []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				queries = append(queries, r.URL.RawQuery)
+				skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+				// Serve two pages per request regardless of the requested limit.
+				end := min(skip+2, len(all))
+				_ = json.NewEncoder(w).Encode(PageList{Count: len(all), Skip: skip, Pages: all[skip:end]})
+				if tt.created != nil && len(queries) == 1 {
+					all = append([]Page{*tt.created}, all...)
+				}
+			}))
+			t.Cleanup(ts.Close)
+			client := &Client{
+				httpClient:  ts.Client(),
+				baseURL:     ts.URL,
+				projectName: "testproject",
+				cookie:      "dummy",
+			}
+
+			pages, err := client.ListAllPages(context.Background())
+			if err != nil {
+				t.Fatalf("ListAllPages() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantPages, pages); diff != "" {
+				t.Errorf("ListAllPages() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantQueries, queries); diff != "" {
+				t.Errorf("ListAllPages() queries mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClient_GetPageSnapshots(t *testing.T) {
+	tests := map[string]struct {
+		statusCode int
+		response   any
+		expectList *PageSnapshotList
+		expectErr  bool
+	}{
+		"ok: success": {
+			statusCode: http.StatusOK,
+			response: PageSnapshotList{
+				PageID:    "p1",
+				Snapshots: []PageSnapshot{{Title: "A", Lines: []Line{{Text: "A"}}, Created: 100}},
+			},
+			expectList: &PageSnapshotList{
+				PageID:    "p1",
+				Snapshots: []PageSnapshot{{Title: "A", Lines: []Line{{Text: "A"}}, Created: 100}},
+			},
+		},
+		"ng: unexpected status": {
+			statusCode: http.StatusNotFound,
+			response:   map[string]string{"error": "not found"},
+			expectErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/page-snapshots/testproject/p1" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				w.WriteHeader(tc.statusCode)
+				_ = json.NewEncoder(w).Encode(tc.response)
+			}))
+			t.Cleanup(ts.Close)
+			client := &Client{
+				httpClient:  ts.Client(),
+				baseURL:     ts.URL,
+				projectName: "testproject",
+				cookie:      "dummy",
+			}
+			list, err := client.GetPageSnapshots(context.Background(), "p1")
+			if diff := cmp.Diff(tc.expectList, list); diff != "" {
+				t.Errorf("GetPageSnapshots() mismatch (-want +got):\n%s", diff)
+			}
+			if (err != nil) != tc.expectErr {
+				t.Errorf("GetPageSnapshots() error = %v, expectErr %v", err, tc.expectErr)
+			}
+		})
+	}
+}
+
+func TestClient_GetPageCommits(t *testing.T) {
+	body := `{"commits":[{"id":"c1","pageId":"p1","userId":"u1","created":100,"changes":[{"_insert":"l1","lines":{"id":"l1","text":"new"}},{"_delete":"l2","lines":-1}]}]}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/commits/testproject/p1" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	client := &Client{
+		httpClient:  ts.Client(),
+		baseURL:     ts.URL,
+		projectName: "testproject",
+		cookie:      "dummy",
+	}
+	list, err := client.GetPageCommits(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPageCommits() unexpected error: %v", err)
+	}
+	want := &CommitList{Commits: []Commit{{
+		ID: "c1", PageID: "p1", UserID: "u1", Created: 100,
+		Changes: []Change{
+			{Insert: "l1", Lines: json.RawMessage(`{"id":"l1","text":"new"}`)},
+			{Delete: "l2", Lines: json.RawMessage(`-1`)},
+		},
+	}}}
+	if diff := cmp.Diff(want, list); diff != "" {
+		t.Errorf("GetPageCommits() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestClient_GetProject(t *testing.T) {
+	tests := map[string]struct {
+		statusCode    int
+		response      any
+		expectProject *Project
+		expectErr     bool
+	}{
+		"ok: success": {
+			statusCode: http.StatusOK,
+			response: map[string]any{
+				"id":            "proj1",
+				"name":          "testproject",
+				"displayName":   "Test Project",
+				"publicVisible": true,
+				"users":         []map[string]any{{"id": "u1", "name": "alice", "displayName": "Alice"}},
+			},
+			expectProject: &Project{
+				ID:            "proj1",
+				Name:          "testproject",
+				DisplayName:   "Test Project",
+				PublicVisible: true,
+				Users:         []User{{ID: "u1", Name: "alice", DisplayName: "Alice"}},
+			},
+		},
+		"ng: unauthorized": {
+			statusCode: http.StatusUnauthorized,
+			response:   map[string]string{"error": "unauthorized"},
+			expectErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/projects/testproject" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				w.WriteHeader(tc.statusCode)
+				_ = json.NewEncoder(w).Encode(tc.response)
+			}))
+			t.Cleanup(ts.Close)
+			client := &Client{
+				httpClient:  ts.Client(),
+				baseURL:     ts.URL,
+				projectName: "testproject",
+				cookie:      "dummy",
+			}
+			project, err := client.GetProject(context.Background())
+			if diff := cmp.Diff(tc.expectProject, project); diff != "" {
+				t.Errorf("GetProject() mismatch (-want +got):\n%s", diff)
+			}
+			if (err != nil) != tc.expectErr {
+				t.Errorf("GetProject() error = %v, expectErr %v", err, tc.expectErr)
+			}
+		})
+	}
+}
+
+func TestClient_Cookie(t *testing.T) {
+	tests := map[string]struct {
+		cookie        string
+		wantHeader    string
+		wantAnonymous bool
+	}{
+		"ok: session cookie": {
+			cookie:     "s%3Aabc",
+			wantHeader: "connect.sid=s%3Aabc",
+		},
+		"ok: anonymous": {
+			cookie:        "",
+			wantHeader:    "",
+			wantAnonymous: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var header string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Get("Cookie")
+				_ = json.NewEncoder(w).Encode(Project{Name: "testproject", PublicVisible: true})
+			}))
+			t.Cleanup(ts.Close)
+			client := NewClient("testproject", tc.cookie, WithBaseURL(ts.URL))
+			if _, err := client.GetProject(context.Background()); err != nil {
+				t.Fatalf("GetProject() error = %v", err)
+			}
+			if header != tc.wantHeader {
+				t.Errorf("Cookie header = %q, want %q", header, tc.wantHeader)
+			}
+			if got := client.Anonymous(); got != tc.wantAnonymous {
+				t.Errorf("Anonymous() = %v, want %v", got, tc.wantAnonymous)
+			}
+		})
+	}
+}
+
+func TestClient_GetMe(t *testing.T) {
+	tests := map[string]struct {
+		response  any
+		expectMe  *Me
+		expectErr bool
+	}{
+		"ok: logged in": {
+			response: map[string]any{"id": "u1", "name": "alice", "displayName": "Alice", "isGuest": false},
+			expectMe: &Me{ID: "u1", Name: "alice", DisplayName: "Alice"},
+		},
+		"ok: guest": {
+			response: map[string]any{"isGuest": true},
+			expectMe: &Me{IsGuest: true},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/users/me" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				_ = json.NewEncoder(w).Encode(tc.response)
+			}))
+			t.Cleanup(ts.Close)
+			client := NewClient("testproject", "dummy", WithBaseURL(ts.URL))
+			me, err := client.GetMe(context.Background())
+			if diff := cmp.Diff(tc.expectMe, me); diff != "" {
+				t.Errorf("GetMe() mismatch (-want +got):\n%s", diff)
+			}
+			if (err != nil) != tc.expectErr {
+				t.Errorf("GetMe() error = %v, expectErr %v", err, tc.expectErr)
+			}
+		})
+	}
+}
+
+func TestClient_Expired(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(ts.Close)
+
+	var notified []error
+	client := NewClient("testproject", "dummy", WithBaseURL(ts.URL), WithCookieSource("SCRAPBOX_SID"))
+	client.SetExpiredHandler(func(err error) { notified = append(notified, err) })
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetPage(context.Background(), "Page")
+		if !stderrors.Is(err, ErrCookieExpired) {
+			t.Fatalf("GetPage() error = %v, want %v", err, ErrCookieExpired)
+		}
+		if want := "cookie expired for project testproject, refresh SCRAPBOX_SID"; !strings.Contains(err.Error(), want) {
+			t.Errorf("GetPage() error = %v, want it to contain %q", err, want)
+		}
+	}
+	if !client.Expired() {
+		t.Error("Expired() = false, want true")
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1: an expired client must not send requests", requests)
+	}
+	if len(notified) != 1 {
+		t.Errorf("expired handler called %d times, want 1", len(notified))
+	}
+
+	// Anonymous clients only get the status code, since there is no cookie
+	// to refresh.
+	anonymous := NewClient("testproject", "", WithBaseURL(ts.URL))
+	if _, err := anonymous.GetPage(context.Background(), "Page"); err == nil || stderrors.Is(err, ErrCookieExpired) {
+		t.Errorf("GetPage() anonymous error = %v, want a plain status error", err)
+	}
+	if anonymous.Expired() {
+		t.Error("Expired() anonymous = true, want false")
+	}
+}
+
+func TestClient_CookieReload(t *testing.T) {
+	valid := "new"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "connect.sid="+valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Page{Title: "Page"})
+	}))
+	t.Cleanup(ts.Close)
+
+	source := "new"
+	reloads := 0
+	var notified []error
+	client := NewClient("testproject", "old", WithBaseURL(ts.URL), WithCookieReload(func(context.Context) (string, error) {
+		reloads++
+		return source, nil
+	}))
+	client.SetExpiredHandler(func(err error) { notified = append(notified, err) })
+
+	// A rejected cookie is re-read and the request retried.
+	if _, err := client.GetPage(context.Background(), "Page"); err != nil {
+		t.Fatalf("GetPage() error = %v", err)
+	}
+	if reloads != 1 || client.Expired() || len(notified) != 0 {
+		t.Errorf("reloads = %d, Expired() = %v, notified = %d, want 1, false, 0", reloads, client.Expired(), len(notified))
+	}
+
+	// Without a newer cookie the client expires, and does not re-read the
+	// cookie again right away.
+	valid = "newer"
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetPage(context.Background(), "Page"); !stderrors.Is(err, ErrCookieExpired) {
+			t.Fatalf("GetPage() error = %v, want %v", err, ErrCookieExpired)
+		}
+	}
+	if reloads != 2 || !client.Expired() || len(notified) != 1 {
+		t.Errorf("reloads = %d, Expired() = %v, notified = %d, want 2, true, 1", reloads, client.Expired(), len(notified))
+	}
+
+	// Once the cookie is rotated, an expired client recovers.
+	source = "newer"
+	client.reloaded = time.Time{}
+	if _, err := client.GetPage(context.Background(), "Page"); err != nil {
+		t.Fatalf("GetPage() after rotation error = %v", err)
+	}
+	if client.Expired() {
+		t.Error("Expired() after rotation = true, want false")
+	}
+}
+
+func TestClient_ListPagesUpdatedSince(t *testing.T) {
+	all := []Page{{Title: "A", Updated: 500}, {Title: "B", Updated: 400}, {Title: "C", Updated: 300}, {Title: "D", Updated: 200}, {Title: "E", Updated: 100}}
+	// Scrapbox lists pinned pages first whatever the sort.
+	pinned := []Page{{Title: "Old pin", Updated: 50, Pin: 1}, {Title: "New pin", Updated: 450, Pin: 2}}
+	tests := map[string]struct {
+		pages       []Page
+		since       int64
+		expectPages []Page
+		expectReqs  int
+	}{
+		"ok: stops at first older page": {
+			pages:       all,
+			since:       250,
+			expectPages: all[:3],
+			expectReqs:  2,
+		},
+		"ok: stops within first request": {
+			pages:       all,
+			since:       400,
+			expectPages: all[:1],
+			expectReqs:  1,
+		},
+		"ok: walks whole list": {
+			pages:       all,
+			since:       0,
+			expectPages: all,
+			expectReqs:  3,
+		},
+		"ok: pinned pages listed first": {
+			pages:       append(slices.Clone(pinned), all...),
+			since:       250,
+			expectPages: []Page{all[0], pinned[1], all[1], all[2]},
+			expectReqs:  3,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			reqs := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reqs++
+				if got := r.URL.Query().Get("sort"); got != "updated" {
+					t.Errorf("unexpected sort %q", got)
+				}
+				skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+				end := min(skip+2, len(tc.pages))
+				_ = json.NewEncoder(w).Encode(PageList{Count: len(tc.pages), Skip: skip, Pages: tc.pages[skip:end]})
+			}))
+			t.Cleanup(ts.Close)
+			client := &Client{
+				httpClient:  ts.Client(),
+				baseURL:     ts.URL,
+				projectName: "testproject",
+				cookie:      "dummy",
+			}
+
+			pages, err := client.ListPagesUpdatedSince(context.Background(), time.Unix(tc.since, 0))
+			if err != nil {
+				t.Fatalf("ListPagesUpdatedSince() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectPages, pages); diff != "" {
+				t.Errorf("ListPagesUpdatedSince() mismatch (-want +got):\n%s", diff)
+			}
+			if reqs != tc.expectReqs {
+				t.Errorf("ListPagesUpdatedSince() made %d requests, want %d", reqs, tc.expectReqs)
+			}
+		})
+	}
+}
+
+func TestClient_ListAllTitles(t *testing.T) {
+	all := make([]PageTitle, 1500)
+	for i := range all {
+		all[i] = PageTitle{ID: fmt.Sprintf("id%04d", i), Title: fmt.Sprintf("Page %d", i)}
+	}
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pages/testproject/search/titles" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		queries = append(queries, r.URL.RawQuery)
+		start := 0
+		if id := r.URL.Query().Get("followingId"); id != "" {
+			for i, p := range all {
+				if p.ID == id {
+					start = i + 1
+				}
+			}
+		}
+		end := min(start+maxListLimit, len(all))
+		_ = json.NewEncoder(w).Encode(all[start:end])
+	}))
+	t.Cleanup(ts.Close)
+	client := &Client{
+		httpClient:  ts.Client(),
+		baseURL:     ts.URL,
+		projectName: "testproject",
+		cookie:      "dummy",
+	}
+
+	titles, err := client.ListAllTitles(context.Background())
+	if err != nil {
+		t.Fatalf("ListAllTitles() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(all, titles); diff != "" {
+		t.Errorf("ListAllTitles() mismatch (-want +got):\n%s", diff)
+	}
+	wantQueries := []string{"", "followingId=id0999"}
+	if diff := cmp.Diff(wantQueries, queries); diff != "" {
+		t.Errorf("ListAllTitles() queries mismatch (-want +got):\n%s", diff)
+	}
+}
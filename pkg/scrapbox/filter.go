@@ -0,0 +1,182 @@
+package scrapbox
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/takak2166/scrapbox-mcp/pkg/scrapbox/notation"
+)
+
+// ErrPageDenied is wrapped by the errors of requests for pages hidden by the
+// PageFilter of a client.
+var ErrPageDenied = stderrors.New("page denied by the access policy")
+
+// RedactedLine replaces the lines of search results that link to pages
+// hidden by the PageFilter of a client.
+const RedactedLine = "[redacted]"
+
+// linksTTL is how long a client caches the links of every page for a
+// PageFilter that needs them.
+const linksTTL = time.Minute
+
+// PageFilter hides pages from the results of a client, set by
+// WithPageFilter. Its rules may change while the client is in use.
+type PageFilter interface {
+	// Denies reports whether the page titled title, linking to links, is
+	// hidden.
+	Denies(title string, links []string) bool
+	// NeedsLinks reports whether Denies depends on links, so that the
+	// client has to look up the links of the pages it lists.
+	NeedsLinks() bool
+}
+
+// VersionedFilter is a PageFilter numbering the versions of its rules, so
+// that the links a client caches for it and the results others derive from
+// the client can be dropped when the rules change.
+type VersionedFilter interface {
+	PageFilter
+	// Version returns the version of the rules, which changes whenever
+	// they do.
+	Version() uint64
+}
+
+// WithPageFilter hides the pages denied by f: they are left out of page
+// lists, search results and title lists, lines linking to them are
+// redacted from search results, their titles are removed from the links of
+// other pages, and requests for them fail with ErrPageDenied.
+func WithPageFilter(f PageFilter) Option {
+	return func(c *Client) {
+		c.filter = f
+	}
+}
+
+// FilterVersion returns the version of the rules of the PageFilter of the
+// client if it is a VersionedFilter, or 0. Results of the client cached
+// under one version are stale under another.
+func (c *Client) FilterVersion() uint64 {
+	if f, ok := c.filter.(VersionedFilter); ok {
+		return f.Version()
+	}
+	return 0
+}
+
+// denier returns the function reporting whether the page titled title,
+// linking to links, is hidden by the filter of the client. Pages listed
+// without their links are looked up in the link index of the project.
+func (c *Client) denier(ctx context.Context) (func(title string, links []string) bool, error) {
+	f := c.filter
+	if f == nil {
+		return func(string, []string) bool { return false }, nil
+	}
+	if !f.NeedsLinks() {
+		return f.Denies, nil
+	}
+	index, err := c.linkIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the links of the pages to apply the access policy: %w", err)
+	}
+	return func(title string, links []string) bool {
+		if links == nil {
+			links = index[notation.NormalizeTitle(title)]
+		}
+		return f.Denies(title, links)
+	}, nil
+}
+
+// linkIndex returns the links of every page by normalized title, cached for
+// linksTTL or until the rules of the filter change.
+func (c *Client) linkIndex(ctx context.Context) (map[string][]string, error) {
+	c.linksMu.Lock()
+	defer c.linksMu.Unlock()
+	if c.links != nil && time.Since(c.linksLoaded) < linksTTL && c.linksVersion == c.FilterVersion() {
+		return c.links, nil
+	}
+	titles, err := c.listAllTitles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.setLinks(titles)
+	return c.links, nil
+}
+
+// setLinks caches the links of titles, the whole title list of the project.
+// The caller must hold linksMu.
+func (c *Client) setLinks(titles []PageTitle) {
+	index := make(map[string][]string, len(titles))
+	for _, t := range titles {
+		index[notation.NormalizeTitle(t.Title)] = t.Links
+	}
+	c.links, c.linksLoaded, c.linksVersion = index, time.Now(), c.FilterVersion()
+}
+
+// deniedError returns the error of a request for the hidden page titled
+// title.
+func deniedError(title string) error {
+	return fmt.Errorf("%w: %s", ErrPageDenied, title)
+}
+
+// filterLinks returns links without the titles of hidden pages.
+func filterLinks(links []string, deny func(string, []string) bool) []string {
+	if links == nil {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(links), func(l string) bool { return deny(l, nil) })
+}
+
+// filterPages removes the hidden pages from pages and the hidden titles from
+// the links of the others.
+func (c *Client) filterPages(ctx context.Context, pages []Page) ([]Page, error) {
+	if c.filter == nil {
+		return pages, nil
+	}
+	deny, err := c.denier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pages = slices.DeleteFunc(pages, func(p Page) bool { return deny(p.Title, p.Links) })
+	for i := range pages {
+		pages[i].Links = filterLinks(pages[i].Links, deny)
+	}
+	return pages, nil
+}
+
+// filterTitles removes the hidden pages from titles and the hidden titles
+// from the links of the others, which are the backlinks of those titles.
+func (c *Client) filterTitles(ctx context.Context, titles []PageTitle) ([]PageTitle, error) {
+	if c.filter == nil {
+		return titles, nil
+	}
+	deny, err := c.denier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	titles = slices.DeleteFunc(titles, func(t PageTitle) bool { return deny(t.Title, t.Links) })
+	for i := range titles {
+		titles[i].Links = filterLinks(titles[i].Links, deny)
+	}
+	return titles, nil
+}
+
+// filterSearch removes the hidden pages from the results of a search and
+// redacts the lines of the others linking to hidden pages.
+func (c *Client) filterSearch(ctx context.Context, list *SearchPageList) error {
+	if c.filter == nil {
+		return nil
+	}
+	deny, err := c.denier(ctx)
+	if err != nil {
+		return err
+	}
+	list.Pages = slices.DeleteFunc(list.Pages, func(p SearchPage) bool { return deny(p.Title, nil) })
+	for _, p := range list.Pages {
+		for i, line := range p.Lines {
+			if slices.ContainsFunc(notation.Titles(notation.Parse([]string{line})), func(t string) bool { return deny(t, nil) }) {
+				p.Lines[i] = RedactedLine
+			}
+		}
+	}
+	return nil
+}
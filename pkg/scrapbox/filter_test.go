@@ -0,0 +1,134 @@
+package scrapbox
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// denyFilter hides the pages titled titles and those linking to tags.
+type denyFilter struct {
+	titles []string
+	tags   []string
+}
+
+func (f denyFilter) Denies(title string, links []string) bool {
+	return slices.Contains(f.titles, title) || slices.ContainsFunc(links, func(l string) bool { return slices.Contains(f.tags, l) })
+}
+
+func (f denyFilter) NeedsLinks() bool {
+	return len(f.tags) > 0
+}
+
+func TestClient_PageFilter(t *testing.T) {
+	titles := []PageTitle{
+		{ID: "1", Title: "Public", Links: []string{"Salaries", "Secret", "Other"}},
+		{ID: "2", Title: "Salaries", Links: []string{"hr"}},
+		{ID: "3", Title: "Secret"},
+	}
+	responses := map[string]any{
+		"/pages/testproject/search/titles": titles,
+		"/pages/testproject": PageList{Count: 3, Pages: []Page{
+			{ID: "1", Title: "Public"},
+			{ID: "2", Title: "Salaries"},
+			{ID: "3", Title: "Secret"},
+		}},
+		"/pages/testproject/search/query": SearchPageList{Pages: []SearchPage{
+			{Title: "Public", Lines: []string{"see [Secret]", "nothing to hide", "paid #Salaries"}},
+			{Title: "Salaries", Lines: []string{"#hr"}},
+		}},
+		"/pages/testproject/Public":   Page{ID: "1", Title: "Public", Links: titles[0].Links},
+		"/pages/testproject/Salaries": Page{ID: "2", Title: "Salaries", Links: titles[1].Links},
+		"/pages/testproject/Secret":   Page{ID: "3", Title: "Secret"},
+	}
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(ts.Close)
+
+	tests := map[string]struct {
+		call         func(c *Client) (any, error)
+		want         any
+		wantErr      error
+		wantRequests []string
+	}{
+		"ok: get page without denied links": {
+			call: func(c *Client) (any, error) { return c.GetPage(context.Background(), "Public") },
+			want: &Page{ID: "1", Title: "Public", Links: []string{"Other"}},
+			wantRequests: []string{
+				"/pages/testproject/Public",
+				"/pages/testproject/search/titles",
+			},
+		},
+		"err: get page denied by title": {
+			call:    func(c *Client) (any, error) { return c.GetPage(context.Background(), "Secret") },
+			want:    (*Page)(nil),
+			wantErr: ErrPageDenied,
+		},
+		"err: get page denied by tag": {
+			call:         func(c *Client) (any, error) { return c.GetPage(context.Background(), "Salaries") },
+			want:         (*Page)(nil),
+			wantErr:      ErrPageDenied,
+			wantRequests: []string{"/pages/testproject/Salaries"},
+		},
+		"ok: list pages": {
+			call: func(c *Client) (any, error) {
+				return c.ListPagesWithOptions(context.Background(), ListPagesOptions{Limit: 10})
+			},
+			want: &PageList{Count: 3, Pages: []Page{{ID: "1", Title: "Public"}}},
+			wantRequests: []string{
+				"/pages/testproject",
+				"/pages/testproject/search/titles",
+			},
+		},
+		"ok: search redacts lines linking to denied pages": {
+			call: func(c *Client) (any, error) { return c.SearchPages(context.Background(), "hr") },
+			want: &SearchPageList{Pages: []SearchPage{
+				{Title: "Public", Lines: []string{RedactedLine, "nothing to hide", RedactedLine}},
+			}},
+			wantRequests: []string{
+				"/pages/testproject/search/query",
+				"/pages/testproject/search/titles",
+			},
+		},
+		"ok: titles without denied backlinks": {
+			call:         func(c *Client) (any, error) { return c.ListAllTitles(context.Background()) },
+			want:         []PageTitle{{ID: "1", Title: "Public", Links: []string{"Other"}}},
+			wantRequests: []string{"/pages/testproject/search/titles"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			requests = nil
+			client := &Client{
+				httpClient:  ts.Client(),
+				baseURL:     ts.URL,
+				projectName: "testproject",
+				filter:      denyFilter{titles: []string{"Secret"}, tags: []string{"hr"}},
+			}
+			got, err := tc.call(client)
+			if !stderrors.Is(err, tc.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRequests, requests); diff != "" {
+				t.Errorf("requests mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package scrapbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderrors "errors"
+	"log/slog"
+	"net/url"
+)
+
+// WithLogger sets the logger of the client, slog.Default() by default. Each
+// request is logged at debug level with its method, path and request ID;
+// failed requests are logged at warning level. Session cookies and query
+// strings, which hold search terms, are never logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// log returns the logger of the client.
+func (c *Client) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return slog.Default()
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx whose Scrapbox requests are
+// logged with the request ID id, so that the requests made for the same
+// caller request can be told apart from the others. Without one, each
+// request is logged with a new ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of ctx, or "" if it has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a new random request ID.
+func NewRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// requestID returns the request ID of ctx, or a new one.
+func requestID(ctx context.Context) string {
+	if id := RequestID(ctx); id != "" {
+		return id
+	}
+	return NewRequestID()
+}
+
+// redactURL returns err with the query string removed from the URL of the
+// *url.Error it wraps, if any, so that it can be logged.
+func redactURL(err error) error {
+	var ue *url.Error
+	if !stderrors.As(err, &ue) {
+		return err
+	}
+	if u, perr := url.Parse(ue.URL); perr == nil && (u.RawQuery != "" || u.User != nil) {
+		u.RawQuery, u.ForceQuery, u.User = "", false, nil
+		ue.URL = u.String()
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package scrapbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient_Logger(t *testing.T) {
+	tests := map[string]struct {
+		statusCode  int
+		closed      bool
+		ctx         context.Context
+		expectMsgs  []string
+		expectLevel string
+		expectID    string
+	}{
+		"ok: request and response": {
+			statusCode:  http.StatusOK,
+			ctx:         ContextWithRequestID(context.Background(), "42"),
+			expectMsgs:  []string{"scrapbox request", "scrapbox response"},
+			expectLevel: "DEBUG",
+			expectID:    "42",
+		},
+		"ok: server error": {
+			statusCode:  http.StatusBadGateway,
+			ctx:         ContextWithRequestID(context.Background(), "43"),
+			expectMsgs:  []string{"scrapbox request", "scrapbox response"},
+			expectLevel: "WARN",
+			expectID:    "43",
+		},
+		"ng: unreachable": {
+			closed:      true,
+			ctx:         ContextWithRequestID(context.Background(), "44"),
+			expectMsgs:  []string{"scrapbox request", "scrapbox request failed"},
+			expectLevel: "WARN",
+			expectID:    "44",
+		},
+		"ok: generated request id": {
+			statusCode:  http.StatusOK,
+			ctx:         context.Background(),
+			expectMsgs:  []string{"scrapbox request", "scrapbox response"},
+			expectLevel: "DEBUG",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(`{"pages":[]}`))
+			}))
+			t.Cleanup(ts.Close)
+			if tc.closed {
+				ts.Close()
+			}
+
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			client := NewClient("testproject", "secret-cookie", WithBaseURL(ts.URL), WithHTTPClient(ts.Client()), WithLogger(logger))
+			_, err := client.SearchPages(tc.ctx, "private words")
+			if tc.statusCode != http.StatusOK && err == nil {
+				t.Fatal("SearchPages() expected error but got nil")
+			}
+			if err != nil && strings.Contains(err.Error(), "private") {
+				t.Errorf("SearchPages() error contains the query: %v", err)
+			}
+
+			out := buf.String()
+			for _, secret := range []string{"secret-cookie", "private", "q="} {
+				if strings.Contains(out, secret) {
+					t.Errorf("log contains %q:\n%s", secret, out)
+				}
+			}
+			var msgs []string
+			ids := map[string]bool{}
+			var lastLevel string
+			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+				var record struct {
+					Level     string `json:"level"`
+					Msg       string `json:"msg"`
+					Path      string `json:"path"`
+					RequestID string `json:"request_id"`
+				}
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("log line %q: %v", line, err)
+				}
+				if record.Path != "/pages/testproject/search/query" {
+					t.Errorf("path = %q", record.Path)
+				}
+				msgs = append(msgs, record.Msg)
+				ids[record.RequestID] = true
+				lastLevel = record.Level
+			}
+			if diff := cmp.Diff(tc.expectMsgs, msgs); diff != "" {
+				t.Errorf("log messages mismatch (-want +got):\n%s", diff)
+			}
+			if lastLevel != tc.expectLevel {
+				t.Errorf("level = %s, want %s", lastLevel, tc.expectLevel)
+			}
+			if len(ids) != 1 || ids[""] || (tc.expectID != "" && !ids[tc.expectID]) {
+				t.Errorf("request ids = %v, want one %q", ids, tc.expectID)
+			}
+		})
+	}
+}
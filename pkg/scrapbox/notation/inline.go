@@ -0,0 +1,148 @@
+package notation
+
+import (
+	"strings"
+)
+
+// decorationMarks are the characters that may start a [* decorated] bracket.
+const decorationMarks = "*/-_!#%{}~<>"
+
+// imageExtensions are URL suffixes that Scrapbox renders as images.
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp"}
+
+// ParseInline parses the inline notation of a single line.
+func ParseInline(s string) []Node {
+	var nodes []Node
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, &Text{Text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		atWordStart := i == 0 || s[i-1] == ' ' || s[i-1] == '\t'
+		switch {
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				flush()
+				nodes = append(nodes, &Code{Text: s[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "[["):
+			if end := strings.Index(s[i+2:], "]]"); end >= 0 {
+				flush()
+				nodes = append(nodes, &Strong{Nodes: ParseInline(s[i+2 : i+2+end])})
+				i += end + 4
+				continue
+			}
+		case s[i] == '[':
+			if end := closingBracket(s, i); end > i+1 {
+				flush()
+				nodes = append(nodes, parseBracket(s[i+1:end]))
+				i = end + 1
+				continue
+			}
+		case s[i] == '#' && atWordStart:
+			if end := wordEnd(s, i+1); end > i+1 {
+				flush()
+				nodes = append(nodes, &Hashtag{Tag: s[i+1 : end]})
+				i = end
+				continue
+			}
+		case atWordStart && isURL(s[i:]):
+			flush()
+			end := wordEnd(s, i)
+			nodes = append(nodes, &ExternalLink{URL: s[i:end]})
+			i = end
+			continue
+		}
+		text.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return nodes
+}
+
+// closingBracket returns the index of the "]" matching the "[" at start,
+// or -1 if the bracket is not closed.
+func closingBracket(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// wordEnd returns the index of the first whitespace at or after start.
+func wordEnd(s string, start int) int {
+	for i := start; i < len(s); i++ {
+		if s[i] == ' ' || s[i] == '\t' {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// parseBracket interprets the content of a single [bracket].
+func parseBracket(content string) Node {
+	if strings.HasPrefix(content, "$ ") {
+		return &Formula{Formula: strings.TrimPrefix(content, "$ ")}
+	}
+	if marks := leadingMarks(content); marks != "" && len(content) > len(marks) && content[len(marks)] == ' ' {
+		return &Decoration{Marks: marks, Nodes: ParseInline(content[len(marks)+1:])}
+	}
+	if title, ok := strings.CutSuffix(content, ".icon"); ok {
+		return &Icon{Title: title}
+	}
+	fields := strings.Fields(content)
+	if len(fields) > 0 {
+		first, last := fields[0], fields[len(fields)-1]
+		switch {
+		case isURL(first):
+			label := strings.TrimSpace(strings.TrimPrefix(content, first))
+			if label == "" && isImageURL(first) {
+				return &Image{URL: first}
+			}
+			return &ExternalLink{URL: first, Label: label}
+		case isURL(last):
+			return &ExternalLink{URL: last, Label: strings.TrimSpace(strings.TrimSuffix(content, last))}
+		}
+	}
+	return &Link{Title: content}
+}
+
+// leadingMarks returns the run of decoration characters at the start of s.
+func leadingMarks(s string) string {
+	n := 0
+	for n < len(s) && strings.IndexByte(decorationMarks, s[n]) >= 0 {
+		n++
+	}
+	return s[:n]
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+func isImageURL(s string) bool {
+	lower := strings.ToLower(s)
+	if strings.HasPrefix(lower, "https://gyazo.com/") || strings.HasPrefix(lower, "https://i.gyazo.com/") {
+		return true
+	}
+	for _, ext := range imageExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
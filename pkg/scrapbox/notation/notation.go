@@ -0,0 +1,238 @@
+// Package notation parses Scrapbox page text into a small syntax tree.
+//
+// The tree covers the notation that matters when converting pages to other
+// formats: indentation, quotes, code blocks, tables, links, hashtags, icons,
+// images, decorations, inline code and formulas. Anything that is not
+// recognised is kept as plain text.
+package notation
+
+import (
+	"strings"
+)
+
+// Block is a top-level element of a page: a Line, a CodeBlock or a Table.
+type Block interface {
+	block()
+}
+
+// Line is a single line of ordinary text.
+type Line struct {
+	// Indent is the number of leading spaces or tabs.
+	Indent int
+	// Quote reports whether the line starts with ">".
+	Quote bool
+	// Nodes holds the inline content of the line.
+	Nodes []Node
+}
+
+// CodeBlock is a "code:filename" block followed by its indented body.
+type CodeBlock struct {
+	Indent   int
+	FileName string
+	Lines    []string
+}
+
+// Table is a "table:name" block whose rows are tab-separated cells.
+type Table struct {
+	Indent int
+	Name   string
+	Rows   [][]string
+}
+
+func (*Line) block()      {}
+func (*CodeBlock) block() {}
+func (*Table) block()     {}
+
+// Node is an inline element of a Line.
+type Node interface {
+	node()
+}
+
+// Text is plain text.
+type Text struct {
+	Text string
+}
+
+// Link is an internal link to another page, written as [title].
+type Link struct {
+	Title string
+}
+
+// Hashtag is a tag written as #tag or #[tag with spaces].
+type Hashtag struct {
+	Tag string
+}
+
+// ExternalLink is a link to a URL, optionally with a label.
+type ExternalLink struct {
+	URL   string
+	Label string
+}
+
+// Image is an embedded image URL.
+type Image struct {
+	URL string
+}
+
+// Icon is a page icon written as [title.icon].
+type Icon struct {
+	Title string
+}
+
+// Decoration is styled text written as [* text], [/ text], [- text] and so on.
+// Marks holds the decoration characters, e.g. "**" or "/".
+type Decoration struct {
+	Marks string
+	Nodes []Node
+}
+
+// Strong is bold text written as [[text]].
+type Strong struct {
+	Nodes []Node
+}
+
+// Code is inline code written between backquotes.
+type Code struct {
+	Text string
+}
+
+// Formula is a TeX formula written as [$ formula].
+type Formula struct {
+	Formula string
+}
+
+func (*Text) node()         {}
+func (*Link) node()         {}
+func (*Hashtag) node()      {}
+func (*ExternalLink) node() {}
+func (*Image) node()        {}
+func (*Icon) node()         {}
+func (*Decoration) node()   {}
+func (*Strong) node()       {}
+func (*Code) node()         {}
+func (*Formula) node()      {}
+
+// Parse parses the body lines of a page. The title line must not be included.
+func Parse(lines []string) []Block {
+	var blocks []Block
+	for i := 0; i < len(lines); i++ {
+		indent, rest := splitIndent(lines[i])
+		switch {
+		case strings.HasPrefix(rest, "code:"):
+			body, next := blockBody(lines, i+1, indent)
+			blocks = append(blocks, &CodeBlock{Indent: indent, FileName: strings.TrimPrefix(rest, "code:"), Lines: body})
+			i = next - 1
+		case strings.HasPrefix(rest, "table:"):
+			body, next := blockBody(lines, i+1, indent)
+			rows := make([][]string, 0, len(body))
+			for _, l := range body {
+				rows = append(rows, strings.Split(l, "\t"))
+			}
+			blocks = append(blocks, &Table{Indent: indent, Name: strings.TrimPrefix(rest, "table:"), Rows: rows})
+			i = next - 1
+		default:
+			line := &Line{Indent: indent}
+			if strings.HasPrefix(rest, ">") {
+				line.Quote = true
+				rest = strings.TrimPrefix(strings.TrimPrefix(rest, ">"), " ")
+			}
+			line.Nodes = ParseInline(rest)
+			blocks = append(blocks, line)
+		}
+	}
+	return blocks
+}
+
+// splitIndent returns the indentation width of s and the remaining text.
+func splitIndent(s string) (int, string) {
+	n := 0
+	for n < len(s) && (s[n] == ' ' || s[n] == '\t') {
+		n++
+	}
+	return n, s[n:]
+}
+
+// blockBody collects the lines following a code or table header that are
+// indented deeper than the header. The body lines are returned with the
+// header's indentation plus one character removed.
+func blockBody(lines []string, start, indent int) ([]string, int) {
+	var body []string
+	i := start
+	for ; i < len(lines); i++ {
+		n, _ := splitIndent(lines[i])
+		if n <= indent {
+			break
+		}
+		body = append(body, lines[i][indent+1:])
+	}
+	return body, i
+}
+
+// Titles returns the titles of all pages linked from blocks, including
+// hashtags, in order of first appearance.
+func Titles(blocks []Block) []string {
+	seen := map[string]bool{}
+	var titles []string
+	add := func(t string) {
+		if t == "" || seen[NormalizeTitle(t)] {
+			return
+		}
+		seen[NormalizeTitle(t)] = true
+		titles = append(titles, t)
+	}
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *Link:
+				add(n.Title)
+			case *Hashtag:
+				add(n.Tag)
+			case *Decoration:
+				walk(n.Nodes)
+			case *Strong:
+				walk(n.Nodes)
+			}
+		}
+	}
+	for _, b := range blocks {
+		if l, ok := b.(*Line); ok {
+			walk(l.Nodes)
+		}
+	}
+	return titles
+}
+
+// Tags returns the hashtags used in blocks in order of first appearance.
+func Tags(blocks []Block) []string {
+	seen := map[string]bool{}
+	var tags []string
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *Hashtag:
+				if !seen[n.Tag] {
+					seen[n.Tag] = true
+					tags = append(tags, n.Tag)
+				}
+			case *Decoration:
+				walk(n.Nodes)
+			case *Strong:
+				walk(n.Nodes)
+			}
+		}
+	}
+	for _, b := range blocks {
+		if l, ok := b.(*Line); ok {
+			walk(l.Nodes)
+		}
+	}
+	return tags
+}
+
+// NormalizeTitle folds a page title the way Scrapbox compares titles:
+// case-insensitively, with spaces and underscores treated as equal.
+func NormalizeTitle(title string) string {
+	return strings.ToLower(strings.ReplaceAll(title, " ", "_"))
+}
@@ -0,0 +1,103 @@
+package notation
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		lines []string
+		want  []Block
+	}{
+		"ok: indented line with quote": {
+			lines: []string{" > quoted"},
+			want:  []Block{&Line{Indent: 1, Quote: true, Nodes: []Node{&Text{Text: "quoted"}}}},
+		},
+		"ok: code block": {
+			lines: []string{"code:main.go", " package main", "  func main() {}", "after"},
+			want: []Block{
+				&CodeBlock{FileName: "main.go", Lines: []string{"package main", " func main() {}"}},
+				&Line{Nodes: []Node{&Text{Text: "after"}}},
+			},
+		},
+		"ok: indented table": {
+			lines: []string{" table:members", "  name\trole", "  alice\tdev"},
+			want: []Block{
+				&Table{Indent: 1, Name: "members", Rows: [][]string{{"name", "role"}, {"alice", "dev"}}},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Parse(tc.lines)); diff != "" {
+				t.Errorf("Parse() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseInline(t *testing.T) {
+	tests := map[string]struct {
+		text string
+		want []Node
+	}{
+		"ok: internal link and hashtag": {
+			text: "see [Design Doc] #spec",
+			want: []Node{&Text{Text: "see "}, &Link{Title: "Design Doc"}, &Text{Text: " "}, &Hashtag{Tag: "spec"}},
+		},
+		"ok: labelled external links": {
+			text: "[https://example.com Example][Label https://example.org]",
+			want: []Node{
+				&ExternalLink{URL: "https://example.com", Label: "Example"},
+				&ExternalLink{URL: "https://example.org", Label: "Label"},
+			},
+		},
+		"ok: bare url and image": {
+			text: "https://example.com [https://example.com/a.png]",
+			want: []Node{&ExternalLink{URL: "https://example.com"}, &Text{Text: " "}, &Image{URL: "https://example.com/a.png"}},
+		},
+		"ok: decoration with nested link": {
+			text: "[** [Page] here]",
+			want: []Node{&Decoration{Marks: "**", Nodes: []Node{&Link{Title: "Page"}, &Text{Text: " here"}}}},
+		},
+		"ok: strong, code, icon and formula": {
+			text: "[[bold]] `x[y]` [alice.icon] [$ e=mc^2]",
+			want: []Node{
+				&Strong{Nodes: []Node{&Text{Text: "bold"}}},
+				&Text{Text: " "},
+				&Code{Text: "x[y]"},
+				&Text{Text: " "},
+				&Icon{Title: "alice"},
+				&Text{Text: " "},
+				&Formula{Formula: "e=mc^2"},
+			},
+		},
+		"ok: unmatched bracket is text": {
+			text: "a [b",
+			want: []Node{&Text{Text: "a [b"}},
+		},
+		"ok: hash inside word is text": {
+			text: "C#",
+			want: []Node{&Text{Text: "C#"}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, ParseInline(tc.text)); diff != "" {
+				t.Errorf("ParseInline() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTitlesAndTags(t *testing.T) {
+	blocks := Parse([]string{"[A] #b [* [c]]", "[a] #b"})
+	if diff := cmp.Diff([]string{"A", "b", "c"}, Titles(blocks)); diff != "" {
+		t.Errorf("Titles() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"b"}, Tags(blocks)); diff != "" {
+		t.Errorf("Tags() mismatch (-want +got):\n%s", diff)
+	}
+}